@@ -0,0 +1,188 @@
+// Package attrdef validates product attributes against the attribute
+// definitions of the product's category.
+//
+// A definition set on a category applies to its whole subtree. A
+// subcategory may redefine an attribute of the same name, which then
+// overrides the inherited definition. Attributes without a definition are
+// free-form.
+package attrdef
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type is the kind of value an attribute holds, stored in
+// attribute_definitions.type.
+type Type string
+
+const (
+	Text    Type = "TEXT"
+	Number  Type = "NUMBER"
+	Boolean Type = "BOOLEAN"
+	// Enum attributes take one of the definition's AllowedValues.
+	Enum Type = "ENUM"
+)
+
+// Valid reports whether t is a known type.
+func (t Type) Valid() bool {
+	switch t {
+	case Text, Number, Boolean, Enum:
+		return true
+	}
+	return false
+}
+
+// Definition describes an attribute.
+type Definition struct {
+	Name          string
+	Type          Type
+	AllowedValues []string
+	Required      bool
+}
+
+// Check reports whether d itself is well-formed.
+func (d Definition) Check() error {
+	if strings.TrimSpace(d.Name) == "" {
+		return errors.New("attribute name must not be empty")
+	}
+	if !d.Type.Valid() {
+		return fmt.Errorf("unknown attribute type %q", d.Type)
+	}
+	if d.Type != Enum {
+		if len(d.AllowedValues) > 0 {
+			return fmt.Errorf("only %s attributes have allowed values", Enum)
+		}
+		return nil
+	}
+	if len(d.AllowedValues) == 0 {
+		return fmt.Errorf("%s attributes need at least one allowed value", Enum)
+	}
+	seen := make(map[string]bool, len(d.AllowedValues))
+	for _, v := range d.AllowedValues {
+		key := strings.ToLower(strings.TrimSpace(v))
+		if key == "" {
+			return errors.New("allowed values must not be empty")
+		}
+		if seen[key] {
+			return fmt.Errorf("allowed value %q is listed twice", v)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// CheckValue reports whether value is valid for d.
+func (d Definition) CheckValue(value string) error {
+	switch d.Type {
+	case Number:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return errors.New("must be a number")
+		}
+	case Boolean:
+		if value != "true" && value != "false" {
+			return errors.New(`must be "true" or "false"`)
+		}
+	case Enum:
+		for _, allowed := range d.AllowedValues {
+			if strings.EqualFold(value, allowed) {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(d.AllowedValues, ", "))
+	}
+	return nil
+}
+
+// Value is an attribute value to validate.
+type Value struct {
+	Name  string
+	Value string
+}
+
+// Problem is an attribute that failed validation.
+type Problem struct {
+	Name    string
+	Message string
+}
+
+// ValidationError lists every attribute that failed validation.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Name + ": " + p.Message
+	}
+	return "invalid attributes: " + strings.Join(msgs, "; ")
+}
+
+// Set is the definitions applying to a category, keyed by lower-cased name.
+type Set map[string]Definition
+
+// Inherit builds the definitions applying to a category from those of each
+// category on the path down to it, root first. Nearer definitions override
+// inherited ones of the same name.
+func Inherit(path ...[]Definition) Set {
+	s := make(Set)
+	for _, defs := range path {
+		for _, d := range defs {
+			s[strings.ToLower(d.Name)] = d
+		}
+	}
+	return s
+}
+
+// Lookup returns the definition of the named attribute, if any.
+func (s Set) Lookup(name string) (Definition, bool) {
+	d, ok := s[strings.ToLower(name)]
+	return d, ok
+}
+
+// Validate checks values against the definitions, returning a
+// *ValidationError naming every invalid one. With complete, values are all
+// of a product's attributes and missing required attributes are reported
+// too.
+func (s Set) Validate(values []Value, complete bool) error {
+	var e ValidationError
+	present := make(map[string]bool, len(values))
+	for _, v := range values {
+		if strings.TrimSpace(v.Name) == "" {
+			e.Problems = append(e.Problems, Problem{Name: v.Name, Message: "name must not be empty"})
+			continue
+		}
+		present[strings.ToLower(v.Name)] = true
+		if strings.TrimSpace(v.Value) == "" {
+			e.Problems = append(e.Problems, Problem{Name: v.Name, Message: "must not be empty"})
+			continue
+		}
+		if d, ok := s.Lookup(v.Name); ok {
+			if err := d.CheckValue(v.Value); err != nil {
+				e.Problems = append(e.Problems, Problem{Name: v.Name, Message: err.Error()})
+			}
+		}
+	}
+	if complete {
+		keys := make([]string, 0, len(s))
+		for key := range s {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if d := s[key]; d.Required && !present[key] {
+				e.Problems = append(e.Problems, Problem{Name: d.Name, Message: "is required"})
+			}
+		}
+	}
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return &e
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"sync"
+
+	"go-backend/models"
+	"go-backend/store"
+)
+
+var (
+	// ErrInvalidCredentials is returned by Login for an unknown email address
+	// or a wrong password; the two are deliberately indistinguishable.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrInvalidEmail is returned by Register for a malformed email address.
+	ErrInvalidEmail = errors.New("invalid email address")
+)
+
+// Registration is the data supplied by a new user.
+type Registration struct {
+	Email     string
+	Password  string
+	FirstName string
+	LastName  string
+}
+
+// Accounts registers and authenticates users against a UserStore.
+type Accounts struct {
+	users store.UserStore
+
+	// dummyHash is compared against when logging in with an unknown email
+	// address, so the response time doesn't reveal which addresses exist.
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+// NewAccounts returns an Accounts backed by users.
+func NewAccounts(users store.UserStore) *Accounts {
+	return &Accounts{users: users}
+}
+
+// NormalizeEmail trims and lower-cases an email address, rejecting anything
+// that isn't a bare address.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// Register validates and creates a new account. It returns ErrInvalidEmail,
+// a *PasswordError or store.ErrEmailTaken for unacceptable registrations.
+func (a *Accounts) Register(ctx context.Context, reg Registration) (*models.User, error) {
+	email, err := NormalizeEmail(reg.Email)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidatePassword(reg.Password, email); err != nil {
+		return nil, err
+	}
+	hash, err := HashPassword(reg.Password)
+	if err != nil {
+		return nil, err
+	}
+	return a.users.Create(ctx, store.NewUser{
+		Email:        email,
+		PasswordHash: hash,
+		FirstName:    strings.TrimSpace(reg.FirstName),
+		LastName:     strings.TrimSpace(reg.LastName),
+	})
+}
+
+// Login returns the user with the given email address and password, or
+// ErrInvalidCredentials.
+func (a *Accounts) Login(ctx context.Context, email, password string) (*models.User, error) {
+	u, hash, err := a.users.Credentials(ctx, strings.TrimSpace(email))
+	if errors.Is(err, store.ErrNotFound) {
+		a.dummyOnce.Do(func() { a.dummyHash, _ = HashPassword("not a real password") })
+		CheckPassword(a.dummyHash, password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := CheckPassword(hash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return u, nil
+}
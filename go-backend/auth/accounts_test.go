@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-backend/store"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{password: "Short1!"},
+		{password: "alllowercaseletters"},
+		{password: "lowerUPPER12", ok: true},
+		{password: "lower-digits-123", ok: true},
+		{password: "Annabel-2024"}, // contains the email's local part
+		{password: string(make([]byte, MaxPasswordLength+1))},
+	}
+	for _, tt := range tests {
+		err := ValidatePassword(tt.password, "annabel@example.com")
+		var pe *PasswordError
+		if tt.ok && err != nil {
+			t.Errorf("ValidatePassword(%q) = %v, want nil", tt.password, err)
+		}
+		if !tt.ok && !errors.As(err, &pe) {
+			t.Errorf("ValidatePassword(%q) = %v, want a *PasswordError", tt.password, err)
+		}
+	}
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	accounts := NewAccounts(store.NewMemory().Users)
+
+	u, err := accounts.Register(ctx, Registration{Email: " Ann@Example.com ", Password: "correct-Horse-1", FirstName: " Ann "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Email != "ann@example.com" || u.FirstName != "Ann" || u.Role != string(RoleCustomer) {
+		t.Errorf("registered %+v, want a normalized customer", u)
+	}
+	if _, err := accounts.Register(ctx, Registration{Email: "ann@example.com", Password: "correct-Horse-2"}); !errors.Is(err, store.ErrEmailTaken) {
+		t.Errorf("registering twice: error = %v, want store.ErrEmailTaken", err)
+	}
+	if _, err := accounts.Register(ctx, Registration{Email: "Ann <ann@example.com>", Password: "correct-Horse-1"}); !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("registering a display name: error = %v, want ErrInvalidEmail", err)
+	}
+
+	logins := []struct {
+		email, password string
+		err             error
+	}{
+		{email: "ANN@example.com", password: "correct-Horse-1"},
+		{email: "ann@example.com", password: "correct-Horse-2", err: ErrInvalidCredentials},
+		{email: "bob@example.com", password: "correct-Horse-1", err: ErrInvalidCredentials},
+	}
+	for _, tt := range logins {
+		got, err := accounts.Login(ctx, tt.email, tt.password)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Login(%s, %s) error = %v, want %v", tt.email, tt.password, err, tt.err)
+		}
+		if tt.err == nil && err == nil && got.ID != u.ID {
+			t.Errorf("Login(%s) = user %d, want %d", tt.email, got.ID, u.ID)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package auth implements user accounts: password hashing and policy,
+// registration and login, the cookie sessions that carry a logged-in user
+// between requests, and the roles and request principal used for
+// authorization.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength is the shortest password accepted at registration.
+	MinPasswordLength = 10
+	// MaxPasswordLength is bcrypt's input limit; longer passwords would be
+	// silently truncated, so they are rejected instead.
+	MaxPasswordLength = 72
+
+	bcryptCost = 12
+)
+
+// PasswordError explains why a password was rejected by ValidatePassword.
+type PasswordError struct {
+	Reason string
+}
+
+func (e *PasswordError) Error() string {
+	return "password " + e.Reason
+}
+
+// ValidatePassword enforces the password strength rules: a length between
+// MinPasswordLength and MaxPasswordLength bytes, at least three of the four
+// character classes (lower case, upper case, digits, symbols), and not
+// containing the local part of the account's email address.
+func ValidatePassword(password, email string) error {
+	if len(password) < MinPasswordLength {
+		return &PasswordError{fmt.Sprintf("must be at least %d characters long", MinPasswordLength)}
+	}
+	if len(password) > MaxPasswordLength {
+		return &PasswordError{fmt.Sprintf("must be at most %d bytes long", MaxPasswordLength)}
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < 3 {
+		return &PasswordError{"must mix at least three of lower case letters, upper case letters, digits and symbols"}
+	}
+
+	if local, _, _ := strings.Cut(email, "@"); len(local) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(local)) {
+		return &PasswordError{"must not contain your email address"}
+	}
+	return nil
+}
+
+// HashPassword returns the bcrypt hash stored in users.password_hash.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash.
+func CheckPassword(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go-backend/store"
+)
+
+// Role is a user's level of access, stored in users.role.
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleSeller   Role = "seller"
+	RoleAdmin    Role = "admin"
+)
+
+// Valid reports whether r is one of the known roles.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleCustomer, RoleSeller, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID int32
+	Role   Role
+}
+
+// HasRole reports whether p holds any of roles. Admins hold every role.
+func (p *Principal) HasRole(roles ...Role) bool {
+	if p == nil {
+		return false
+	}
+	if p.Role == RoleAdmin {
+		return true
+	}
+	for _, r := range roles {
+		if p.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the caller attached to ctx, or nil for anonymous
+// requests.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// ErrInvalidToken is returned by a TokenVerifier for a bearer token that is
+// malformed, expired or revoked.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// TokenVerifier turns a bearer token into the principal it was issued to.
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (*Principal, error)
+}
+
+// Authenticator resolves the caller of each request from its bearer token or
+// session cookie.
+type Authenticator struct {
+	sessions *Sessions
+	users    store.UserStore
+	tokens   TokenVerifier
+}
+
+// NewAuthenticator returns an Authenticator. tokens may be nil, in which case
+// requests carrying a bearer token are rejected.
+func NewAuthenticator(sessions *Sessions, users store.UserStore, tokens TokenVerifier) *Authenticator {
+	return &Authenticator{sessions: sessions, users: users, tokens: tokens}
+}
+
+// Middleware attaches the caller's Principal to the request context and
+// makes the session available to StartSession and EndSession. A bearer token
+// takes precedence over the session cookie; an invalid one is answered with
+// 401 rather than silently treated as anonymous.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return a.sessions.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.authenticate(r)
+		if errors.Is(err, ErrInvalidToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to authenticate request", http.StatusInternalServerError)
+			return
+		}
+		if p != nil {
+			r = r.WithContext(WithPrincipal(r.Context(), p))
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*Principal, error) {
+	if token, ok := bearerToken(r); ok {
+		if a.tokens == nil {
+			return nil, ErrInvalidToken
+		}
+		return a.tokens.VerifyToken(r.Context(), token)
+	}
+
+	id, ok := a.sessions.UserID(r)
+	if !ok {
+		return nil, nil
+	}
+	// Look the user up on every request so role changes and deleted
+	// accounts take effect immediately.
+	u, err := a.users.Get(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{UserID: u.ID, Role: Role(u.Role)}, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
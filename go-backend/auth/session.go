@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+const (
+	sessionName   = "session"
+	sessionUserID = "userID"
+)
+
+// ErrNoHTTPContext is returned by StartSession and EndSession when the
+// context was not prepared by Sessions.Middleware.
+var ErrNoHTTPContext = errors.New("session cookies are not available in this context")
+
+// Sessions keeps the logged-in user ID in a signed cookie.
+type Sessions struct {
+	store sessions.Store
+}
+
+// NewSessions returns cookie sessions signed with secret.
+func NewSessions(secret []byte) *Sessions {
+	cs := sessions.NewCookieStore(secret)
+	cs.Options.HttpOnly = true
+	cs.Options.SameSite = http.SameSiteNoneMode
+	cs.Options.Secure = true
+	return &Sessions{store: cs}
+}
+
+// Start logs userID in for the client making r.
+func (s *Sessions) Start(w http.ResponseWriter, r *http.Request, userID int32) error {
+	session, _ := s.store.Get(r, sessionName)
+	session.Values[sessionUserID] = userID
+	return session.Save(r, w)
+}
+
+// End logs the client making r out.
+func (s *Sessions) End(w http.ResponseWriter, r *http.Request) error {
+	session, _ := s.store.Get(r, sessionName)
+	delete(session.Values, sessionUserID)
+	session.Options.MaxAge = -1
+	return session.Save(r, w)
+}
+
+// UserID returns the logged-in user ID of the client making r.
+func (s *Sessions) UserID(r *http.Request) (int32, bool) {
+	session, err := s.store.Get(r, sessionName)
+	if err != nil {
+		return 0, false
+	}
+	id, ok := session.Values[sessionUserID].(int32)
+	return id, ok
+}
+
+// httpContext lets GraphQL resolvers, which only see a context, read and set
+// the session cookie of the request being served.
+type httpContext struct {
+	sessions *Sessions
+	w        http.ResponseWriter
+	r        *http.Request
+}
+
+type contextKey struct{}
+
+// Middleware makes the session of each request available to StartSession
+// and EndSession. Authenticator.Middleware includes it.
+func (s *Sessions) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hc := &httpContext{sessions: s, w: w, r: r}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, hc)))
+	})
+}
+
+// StartSession logs userID in for the request carried by ctx.
+func StartSession(ctx context.Context, userID int32) error {
+	hc, ok := ctx.Value(contextKey{}).(*httpContext)
+	if !ok {
+		return ErrNoHTTPContext
+	}
+	return hc.sessions.Start(hc.w, hc.r, userID)
+}
+
+// EndSession logs out the client of the request carried by ctx.
+func EndSession(ctx context.Context) error {
+	hc, ok := ctx.Value(contextKey{}).(*httpContext)
+	if !ok {
+		return ErrNoHTTPContext
+	}
+	return hc.sessions.End(hc.w, hc.r)
+}
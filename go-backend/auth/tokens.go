@@ -0,0 +1,335 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-backend/models"
+	"go-backend/store"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenConfig controls the lifetime of API tokens and signing keys.
+type TokenConfig struct {
+	// Issuer is the iss claim of access tokens.
+	Issuer string
+	// AccessTTL is how long an access token is accepted.
+	AccessTTL time.Duration
+	// RefreshTTL is how long a refresh token may be exchanged. Every refresh
+	// issues a new token, so an active client never has to log in again.
+	RefreshTTL time.Duration
+	// KeyRotation is the age after which a new signing key is generated.
+	// Old keys keep verifying until the tokens they signed have expired.
+	KeyRotation time.Duration
+}
+
+// DefaultTokenConfig returns the token lifetimes used in production.
+func DefaultTokenConfig() TokenConfig {
+	return TokenConfig{
+		Issuer:      "sellcustom",
+		AccessTTL:   15 * time.Minute,
+		RefreshTTL:  30 * 24 * time.Hour,
+		KeyRotation: 7 * 24 * time.Hour,
+	}
+}
+
+// keyReloadInterval bounds how stale the cached key set may get, so keys
+// rotated by another instance are picked up.
+const keyReloadInterval = time.Minute
+
+// TokenPair is the response of the token endpoints, shaped after RFC 6749.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// accessClaims are the claims of an access token. The subject is the user
+// ID; the role is copied in at issue time, so a role change takes effect
+// when the client next refreshes.
+type accessClaims struct {
+	Role Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// Tokens issues and verifies access tokens signed with Ed25519 keys, and
+// rotating refresh tokens. It implements TokenVerifier.
+type Tokens struct {
+	store store.TokenStore
+	users store.UserStore
+	cfg   TokenConfig
+
+	mu       sync.Mutex
+	keys     []*store.SigningKey
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+// NewTokens returns a token service keeping its state in tokens.
+func NewTokens(tokens store.TokenStore, users store.UserStore, cfg TokenConfig) *Tokens {
+	return &Tokens{store: tokens, users: users, cfg: cfg, now: time.Now}
+}
+
+// Issue starts a new refresh token family for u and returns its first pair.
+func (t *Tokens) Issue(ctx context.Context, u *models.User) (*TokenPair, error) {
+	return t.issue(ctx, u, uuid.New().String())
+}
+
+// Refresh exchanges a refresh token for a new pair. Refresh tokens are
+// single-use: presenting one a second time is treated as theft and revokes
+// every token of its family.
+func (t *Tokens) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	rt, err := t.store.ConsumeRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, store.ErrTokenReused) {
+		if err := t.store.RevokeRefreshFamily(ctx, rt.Family); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.now().After(rt.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	u, err := t.users.Get(ctx, rt.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t.issue(ctx, u, rt.Family)
+}
+
+// Revoke invalidates a refresh token and the rest of its family. Unknown
+// tokens are ignored, as RFC 7009 asks.
+func (t *Tokens) Revoke(ctx context.Context, refreshToken string) error {
+	rt, err := t.store.ConsumeRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, store.ErrTokenReused) {
+		return err
+	}
+	return t.store.RevokeRefreshFamily(ctx, rt.Family)
+}
+
+// VerifyToken checks an access token's signature, issuer and expiry.
+func (t *Tokens) VerifyToken(ctx context.Context, token string) (*Principal, error) {
+	var claims accessClaims
+	var keyErr error
+	_, err := jwt.ParseWithClaims(token, &claims, func(tok *jwt.Token) (any, error) {
+		kid, _ := tok.Header["kid"].(string)
+		key, err := t.publicKey(ctx, kid)
+		if err != nil {
+			if !errors.Is(err, errUnknownKey) {
+				keyErr = err
+			}
+			return nil, err
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(t.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(t.now),
+	)
+	if keyErr != nil {
+		// The key set couldn't be read; that's our failure, not the client's.
+		return nil, keyErr
+	}
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 32)
+	if err != nil || !claims.Role.Valid() {
+		return nil, ErrInvalidToken
+	}
+	return &Principal{UserID: int32(id), Role: claims.Role}, nil
+}
+
+// JWK is a public key in JSON Web Key form (RFC 8037 for Ed25519).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// KeySet is the document served at /.well-known/jwks.json.
+type KeySet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet returns the public halves of every key that may have signed a
+// still-valid access token.
+func (t *Tokens) KeySet(ctx context.Context) (*KeySet, error) {
+	keys, err := t.loadKeys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	set := &KeySet{Keys: []JWK{}}
+	for _, k := range keys {
+		pub := ed25519.NewKeyFromSeed(k.PrivateKey).Public().(ed25519.PublicKey)
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+			KeyID:     k.ID,
+			Algorithm: jwt.SigningMethodEdDSA.Alg(),
+			Use:       "sig",
+		})
+	}
+	return set, nil
+}
+
+func (t *Tokens) issue(ctx context.Context, u *models.User, family string) (*TokenPair, error) {
+	key, err := t.signingKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := t.now()
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, accessClaims{
+		Role: Role(u.Role),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.cfg.Issuer,
+			Subject:   strconv.Itoa(int(u.ID)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.cfg.AccessTTL)),
+		},
+	})
+	tok.Header["kid"] = key.ID
+	access, err := tok.SignedString(ed25519.NewKeyFromSeed(key.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = t.store.CreateRefreshToken(ctx, &store.RefreshToken{
+		Hash:      hashToken(refresh),
+		UserID:    u.ID,
+		Family:    family,
+		ExpiresAt: now.Add(t.cfg.RefreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(t.cfg.AccessTTL.Seconds()),
+		RefreshToken: refresh,
+	}, nil
+}
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// signingKey returns the newest key, generating one if there is none or it
+// is due for rotation. Two instances rotating at once both add a key, which
+// is harmless: both stay in the key set.
+func (t *Tokens) signingKey(ctx context.Context) (*store.SigningKey, error) {
+	keys, err := t.loadKeys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	now := t.now()
+	if len(keys) > 0 && now.Sub(keys[0].CreatedAt) < t.cfg.KeyRotation {
+		return keys[0], nil
+	}
+	return t.rotate(ctx, now)
+}
+
+func (t *Tokens) rotate(ctx context.Context, now time.Time) (*store.SigningKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	key := &store.SigningKey{ID: uuid.New().String(), PrivateKey: priv.Seed(), CreatedAt: now}
+	if err := t.store.AddSigningKey(ctx, key); err != nil {
+		return nil, err
+	}
+
+	// A key that was superseded more than AccessTTL ago can't have signed a
+	// live token; keys are superseded at most KeyRotation after creation.
+	if err := t.store.DeleteSigningKeysBefore(ctx, now.Add(-t.cfg.KeyRotation-t.cfg.AccessTTL)); err != nil {
+		return nil, err
+	}
+	if err := t.store.DeleteRefreshTokensBefore(ctx, now); err != nil {
+		return nil, err
+	}
+	if _, err := t.loadKeys(ctx, true); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (t *Tokens) publicKey(ctx context.Context, kid string) (ed25519.PublicKey, error) {
+	for _, reload := range []bool{false, true} {
+		keys, err := t.loadKeys(ctx, reload)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if k.ID == kid {
+				return ed25519.NewKeyFromSeed(k.PrivateKey).Public().(ed25519.PublicKey), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+}
+
+// loadKeys returns the cached key set, reading it from the store when
+// forced or stale.
+func (t *Tokens) loadKeys(ctx context.Context, force bool) ([]*store.SigningKey, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !force && t.keys != nil && t.now().Sub(t.loadedAt) < keyReloadInterval {
+		return t.keys, nil
+	}
+	keys, err := t.store.SigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.keys, t.loadedAt = keys, t.now()
+	return keys, nil
+}
+
+// randomToken returns an unguessable opaque token.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
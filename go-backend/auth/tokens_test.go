@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-backend/store"
+)
+
+func newTestTokens(t *testing.T) (*Tokens, *store.Store) {
+	t.Helper()
+	st := store.NewMemory()
+	return NewTokens(st.Tokens, st.Users, DefaultTokenConfig()), st
+}
+
+func TestAccessToken(t *testing.T) {
+	ctx := context.Background()
+	tokens, st := newTestTokens(t)
+	u, err := st.Users.Create(ctx, store.NewUser{Email: "ann@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tokens.Issue(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := tokens.VerifyToken(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.UserID != u.ID || p.Role != RoleCustomer {
+		t.Errorf("VerifyToken() = %+v, want user %d as a customer", p, u.ID)
+	}
+
+	tampered := pair.AccessToken[:len(pair.AccessToken)-2] + "AA"
+	if _, err := tokens.VerifyToken(ctx, tampered); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken(tampered) error = %v, want ErrInvalidToken", err)
+	}
+	other, _ := newTestTokens(t)
+	if _, err := other.VerifyToken(ctx, pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken() with another key set error = %v, want ErrInvalidToken", err)
+	}
+	tokens.now = func() time.Time { return time.Now().Add(DefaultTokenConfig().AccessTTL + time.Minute) }
+	if _, err := tokens.VerifyToken(ctx, pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken(expired) error = %v, want ErrInvalidToken", err)
+	}
+}
+
+// Refresh tokens are single-use; presenting one twice revokes the tokens
+// issued in its place too.
+func TestRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	tokens, st := newTestTokens(t)
+	u, err := st.Users.Create(ctx, store.NewUser{Email: "ann@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := tokens.Issue(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tokens.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Refresh() returned the same refresh token")
+	}
+
+	if _, err := tokens.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("reusing a refresh token: error = %v, want ErrInvalidToken", err)
+	}
+	if _, err := tokens.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refreshing after reuse: error = %v, want ErrInvalidToken", err)
+	}
+
+	// Another login's family is untouched, until it is revoked.
+	third, err := tokens.Issue(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.Revoke(ctx, third.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refreshing a revoked token: error = %v, want ErrInvalidToken", err)
+	}
+	if err := tokens.Revoke(ctx, "unknown"); err != nil {
+		t.Errorf("Revoke(unknown) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,527 @@
+// Package config gathers the server's settings. Each setting has a default
+// and can be given in a config file, an environment variable or a flag,
+// which take precedence in that order: flags override the environment,
+// which overrides the file.
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxQRSessionTTL and MaxQRUploads cap what a QR upload session may be
+// opened with, by default or by a client asking for its own.
+const (
+	MaxQRSessionTTL = 24 * time.Hour
+	MaxQRUploads    = 100
+)
+
+// minSecretLength is the fewest bytes a signing secret may have in
+// production.
+const minSecretLength = 32
+
+// Environments the server may run in. Development fills in secrets that
+// aren't set, so the server starts without any configuration; production
+// insists on them.
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+// Config is the server's configuration.
+type Config struct {
+	Env      string
+	Server   Server
+	Database Database
+	Auth     Auth
+	Payments Payments
+	Images   Images
+	Uploads  Uploads
+
+	// File is the config file the settings were read from, if any.
+	File string
+	// PrintConfig asks for the settings to be printed rather than the
+	// server started.
+	PrintConfig bool
+	// Args are the command line arguments left after the flags.
+	Args []string
+	// Warnings are about settings filled in for development that must be
+	// set in production.
+	Warnings []string
+
+	settings []*setting
+	sources  map[string]string
+}
+
+type Server struct {
+	Port int
+	// CORSOrigins are the browser origins allowed to call the API.
+	CORSOrigins []string
+
+	// Timeouts of the HTTP server; zero means none. WebSocket connections
+	// aren't subject to them once upgraded.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout bounds how long the server takes to stop once
+	// signalled, DrainDelay included, before cutting the requests still in
+	// flight. DrainDelay is how long it keeps serving while reporting
+	// itself not ready, so load balancers stop sending it requests first.
+	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration
+}
+
+type Database struct {
+	URL             Secret
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+type Auth struct {
+	// SessionSecret signs session cookies.
+	SessionSecret      Secret
+	TokenIssuer        string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	SigningKeyRotation time.Duration
+}
+
+type Payments struct {
+	WebhookSecret Secret
+	// PendingOrderTTL is how long an order may wait for its payment before
+	// it is cancelled and its stock released.
+	PendingOrderTTL time.Duration
+}
+
+type Images struct {
+	// Storage is "local" or "s3".
+	Storage string
+	// Dir and BaseURL are where local storage keeps images and the URL it
+	// serves them from.
+	Dir     string
+	BaseURL string
+	S3      S3
+}
+
+type S3 struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey Secret
+	PathStyle       bool
+	PublicURL       string
+}
+
+type Uploads struct {
+	// MaxBytes is the largest request body an upload may have.
+	MaxBytes int64
+	// QRSessionTTL and QRMaxUploads are what QR upload sessions are opened
+	// with when the client doesn't ask for its own.
+	QRSessionTTL time.Duration
+	QRMaxUploads int
+}
+
+// setting is one configurable value: its key in a config file, with
+// sections separated by dots, the environment variable that sets it and
+// where it is kept. Its flag is named after the key.
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	value  flag.Value
+	secret bool
+	// show, if set, is how a secret is printed instead of being hidden.
+	show func(string) string
+}
+
+func (s *setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// defaults returns the configuration used for anything not set.
+func defaults() *Config {
+	c := &Config{
+		Env: Development,
+		Server: Server{
+			Port:              8080,
+			CORSOrigins:       []string{"http://localhost:5173"},
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       time.Minute,
+			WriteTimeout:      90 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   25 * time.Second,
+			DrainDelay:        5 * time.Second,
+		},
+		Database: Database{
+			MaxIdleConns: 2,
+		},
+		Auth: Auth{
+			TokenIssuer:        "sellcustom",
+			AccessTokenTTL:     15 * time.Minute,
+			RefreshTokenTTL:    30 * 24 * time.Hour,
+			SigningKeyRotation: 7 * 24 * time.Hour,
+		},
+		Payments: Payments{
+			PendingOrderTTL: time.Hour,
+		},
+		Images: Images{
+			Storage: "local",
+			Dir:     "uploads",
+			S3:      S3{Endpoint: "https://s3.amazonaws.com"},
+		},
+		Uploads: Uploads{
+			MaxBytes:     10 << 20,
+			QRSessionTTL: 5 * time.Minute,
+			QRMaxUploads: 10,
+		},
+		sources: make(map[string]string),
+	}
+	c.settings = []*setting{
+		{key: "env", env: "APP_ENV", usage: "environment, development or production", value: stringField(&c.Env)},
+
+		{key: "server.port", env: "PORT", usage: "port to listen on", value: intField(&c.Server.Port)},
+		{key: "server.cors_origins", env: "CORS_ORIGINS", usage: "comma-separated browser origins allowed to call the API", value: listField(&c.Server.CORSOrigins)},
+		{key: "server.read_header_timeout", env: "HTTP_READ_HEADER_TIMEOUT", usage: "longest a client may take to send request headers", value: durationField(&c.Server.ReadHeaderTimeout)},
+		{key: "server.read_timeout", env: "HTTP_READ_TIMEOUT", usage: "longest a client may take to send a whole request", value: durationField(&c.Server.ReadTimeout)},
+		{key: "server.write_timeout", env: "HTTP_WRITE_TIMEOUT", usage: "longest a request may take to answer once its headers are read", value: durationField(&c.Server.WriteTimeout)},
+		{key: "server.idle_timeout", env: "HTTP_IDLE_TIMEOUT", usage: "longest a keep-alive connection is kept idle", value: durationField(&c.Server.IdleTimeout)},
+		{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT", usage: "longest a graceful shutdown may take before requests are cut", value: durationField(&c.Server.ShutdownTimeout)},
+		{key: "server.drain_delay", env: "SHUTDOWN_DRAIN_DELAY", usage: "how long the server reports itself not ready before it stops taking requests", value: durationField(&c.Server.DrainDelay)},
+
+		{key: "database.url", env: "DATABASE_URL", usage: "PostgreSQL connection string", value: secretField(&c.Database.URL), secret: true, show: redactConnString},
+		{key: "database.max_open_conns", env: "DB_MAX_OPEN_CONNS", usage: "most open database connections, 0 for no limit", value: intField(&c.Database.MaxOpenConns)},
+		{key: "database.max_idle_conns", env: "DB_MAX_IDLE_CONNS", usage: "most idle database connections kept", value: intField(&c.Database.MaxIdleConns)},
+		{key: "database.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "longest a database connection is reused, 0 for no limit", value: durationField(&c.Database.ConnMaxLifetime)},
+
+		{key: "auth.session_secret", env: "SESSION_SECRET", usage: "secret signing session cookies", value: secretField(&c.Auth.SessionSecret), secret: true},
+		{key: "auth.token_issuer", env: "TOKEN_ISSUER", usage: "issuer of access tokens", value: stringField(&c.Auth.TokenIssuer)},
+		{key: "auth.access_token_ttl", env: "ACCESS_TOKEN_TTL", usage: "lifetime of access tokens", value: durationField(&c.Auth.AccessTokenTTL)},
+		{key: "auth.refresh_token_ttl", env: "REFRESH_TOKEN_TTL", usage: "lifetime of refresh tokens", value: durationField(&c.Auth.RefreshTokenTTL)},
+		{key: "auth.signing_key_rotation", env: "SIGNING_KEY_ROTATION", usage: "how often the token signing key is replaced", value: durationField(&c.Auth.SigningKeyRotation)},
+
+		{key: "payments.webhook_secret", env: "PAYMENT_WEBHOOK_SECRET", usage: "secret the payment provider signs webhooks with", value: secretField(&c.Payments.WebhookSecret), secret: true},
+		{key: "payments.pending_order_ttl", env: "PENDING_ORDER_TTL", usage: "how long an unpaid order holds its stock before it is cancelled", value: durationField(&c.Payments.PendingOrderTTL)},
+
+		{key: "images.storage", env: "IMAGE_STORAGE", usage: "where images are kept, local or s3", value: stringField(&c.Images.Storage)},
+		{key: "images.dir", env: "IMAGE_DIR", usage: "directory local storage keeps images in", value: stringField(&c.Images.Dir)},
+		{key: "images.base_url", env: "IMAGE_BASE_URL", usage: "URL local storage serves images from (default http://localhost:<port>/media)", value: stringField(&c.Images.BaseURL)},
+		{key: "images.s3.endpoint", env: "S3_ENDPOINT", usage: "S3-compatible service endpoint", value: stringField(&c.Images.S3.Endpoint)},
+		{key: "images.s3.region", env: "S3_REGION", usage: "S3 region", value: stringField(&c.Images.S3.Region)},
+		{key: "images.s3.bucket", env: "S3_BUCKET", usage: "S3 bucket", value: stringField(&c.Images.S3.Bucket)},
+		{key: "images.s3.access_key_id", env: "S3_ACCESS_KEY_ID", usage: "S3 access key ID", value: stringField(&c.Images.S3.AccessKeyID)},
+		{key: "images.s3.secret_access_key", env: "S3_SECRET_ACCESS_KEY", usage: "S3 secret access key", value: secretField(&c.Images.S3.SecretAccessKey), secret: true},
+		{key: "images.s3.path_style", env: "S3_PATH_STYLE", usage: "address the bucket in the path rather than the host name", value: boolField(&c.Images.S3.PathStyle)},
+		{key: "images.s3.public_url", env: "S3_PUBLIC_URL", usage: "URL images in the bucket are served from, such as a CDN", value: stringField(&c.Images.S3.PublicURL)},
+
+		{key: "uploads.max_bytes", env: "UPLOAD_MAX_BYTES", usage: "largest upload request, such as 10MB", value: bytesField(&c.Uploads.MaxBytes)},
+		{key: "uploads.qr_session_ttl", env: "QR_SESSION_TTL", usage: "default lifetime of QR upload sessions", value: durationField(&c.Uploads.QRSessionTTL)},
+		{key: "uploads.qr_max_uploads", env: "QR_MAX_UPLOADS", usage: "default number of uploads a QR upload session takes", value: intField(&c.Uploads.QRMaxUploads)},
+	}
+	return c
+}
+
+// Error lists everything wrong with a configuration.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Load reads the configuration from the config file, the environment, as
+// read by getenv, and the command line flags in args. The file is named by
+// the --config flag or CONFIG_FILE and may be YAML (.yaml, .yml) or TOML
+// (.toml). Any flags have to come before other arguments, which are left
+// in Args.
+//
+// Load returns an *Error listing every setting that can't be read or
+// isn't valid, along with the configuration as far as it got, so that it
+// can still be printed. It returns flag.ErrHelp if the flags ask for help,
+// having written it to output.
+func Load(args []string, getenv func(string) string, output io.Writer) (*Config, error) {
+	c := defaults()
+	var problems []string
+
+	fs := flag.NewFlagSet("main", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&c.File, "config", "", "config file, YAML or TOML (env CONFIG_FILE)")
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "print the configuration, with secrets redacted, and exit")
+	// Flags are only recorded here, to be applied once the file and the
+	// environment have been.
+	flags := make(map[*setting]string)
+	for _, s := range c.settings {
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		if def := s.value.String(); def != "" {
+			usage += " (default " + def + ")"
+		}
+		fs.Var(&flagValue{s: s, given: flags}, s.flagName(), usage)
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: main [flags] [migrate up|down [steps]|status|redo]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	c.Args = fs.Args()
+
+	if c.File == "" {
+		c.File = getenv("CONFIG_FILE")
+	}
+	if c.File != "" {
+		values, err := readFile(c.File)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := c.lookup(key)
+			if s == nil {
+				problems = append(problems, fmt.Sprintf("%s: unknown setting %q", c.File, key))
+				continue
+			}
+			problems = c.set(problems, s, values[key], "file "+c.File)
+		}
+	}
+	for _, s := range c.settings {
+		if v := getenv(s.env); v != "" {
+			problems = c.set(problems, s, v, "env "+s.env)
+		}
+	}
+	for _, s := range c.settings {
+		if v, ok := flags[s]; ok {
+			problems = c.set(problems, s, v, "flag --"+s.flagName())
+		}
+	}
+
+	problems = append(problems, c.validate()...)
+	if len(problems) > 0 {
+		return c, &Error{Problems: problems}
+	}
+	return c, nil
+}
+
+// flagValue records the value given to a setting's flag.
+type flagValue struct {
+	s     *setting
+	given map[*setting]string
+}
+
+func (f *flagValue) Set(v string) error {
+	f.given[f.s] = v
+	return nil
+}
+
+func (f *flagValue) String() string {
+	return ""
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	b, ok := f.s.value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (c *Config) lookup(key string) *setting {
+	for _, s := range c.settings {
+		if s.key == key {
+			return s
+		}
+	}
+	return nil
+}
+
+// set gives s the value v from source, adding a problem if v can't be
+// read.
+func (c *Config) set(problems []string, s *setting, v, source string) []string {
+	if err := s.value.Set(v); err != nil {
+		return append(problems, fmt.Sprintf("%s (%s): %v", s.key, source, err))
+	}
+	c.sources[s.key] = source
+	return problems
+}
+
+// Source says where a setting's value came from: "default", "file
+// <name>", "env <NAME>", "flag --<name>", or how it was filled in.
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return "default"
+}
+
+// validate fills in what is derived from other settings or, in
+// development, generated, and returns what is wrong with the rest.
+func (c *Config) validate() []string {
+	var problems []string
+	fail := func(key, format string, args ...any) {
+		s := c.lookup(key)
+		problems = append(problems, fmt.Sprintf("%s (%s): %s", key, c.describe(s), fmt.Sprintf(format, args...)))
+	}
+
+	if c.Env != Development && c.Env != Production {
+		fail("env", "must be %s or %s, got %q", Development, Production, c.Env)
+	}
+	production := c.Env == Production
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		fail("server.port", "must be from 1 to 65535, got %d", c.Server.Port)
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			fail("server.cors_origins", "%q is not an origin such as https://shop.example.com", origin)
+		}
+	}
+	for _, timeout := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+	} {
+		if timeout.d < 0 {
+			fail(timeout.key, "must not be negative")
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdown_timeout", "must be positive")
+	}
+	if c.Server.DrainDelay < 0 || c.Server.DrainDelay >= c.Server.ShutdownTimeout {
+		fail("server.drain_delay", "must be at least 0 and less than server.shutdown_timeout (%s), got %s", c.Server.ShutdownTimeout, c.Server.DrainDelay)
+	}
+
+	if c.Database.URL == "" {
+		fail("database.url", "is required")
+	}
+	if c.Database.MaxOpenConns < 0 {
+		fail("database.max_open_conns", "must not be negative")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		fail("database.max_idle_conns", "must not be negative")
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		fail("database.conn_max_lifetime", "must not be negative")
+	}
+
+	switch {
+	case c.Auth.SessionSecret == "" && production:
+		fail("auth.session_secret", "is required in production")
+	case c.Auth.SessionSecret == "":
+		c.Auth.SessionSecret = randomSecret()
+		c.sources["auth.session_secret"] = "generated"
+		c.Warnings = append(c.Warnings, "Generated a session secret; sessions won't survive a restart. Set SESSION_SECRET")
+	case len(c.Auth.SessionSecret) < minSecretLength && production:
+		fail("auth.session_secret", "must be at least %d bytes in production, got %d", minSecretLength, len(c.Auth.SessionSecret))
+	}
+	if c.Auth.TokenIssuer == "" {
+		fail("auth.token_issuer", "is required")
+	}
+	if c.Auth.AccessTokenTTL <= 0 {
+		fail("auth.access_token_ttl", "must be positive")
+	}
+	if c.Auth.RefreshTokenTTL <= 0 {
+		fail("auth.refresh_token_ttl", "must be positive")
+	}
+	if c.Auth.SigningKeyRotation <= 0 {
+		fail("auth.signing_key_rotation", "must be positive")
+	}
+
+	switch {
+	case c.Payments.WebhookSecret == "" && production:
+		fail("payments.webhook_secret", "is required in production")
+	case c.Payments.WebhookSecret == "":
+		// Not a fixed default, which anyone could sign forged webhooks with
+		// on a deployment that forgot APP_ENV
+		c.Payments.WebhookSecret = randomSecret()
+		c.sources["payments.webhook_secret"] = "generated"
+		c.Warnings = append(c.Warnings, "Generated a payment webhook secret; only the in-process fake provider can sign webhooks. Set PAYMENT_WEBHOOK_SECRET")
+	}
+	if c.Payments.PendingOrderTTL <= 0 {
+		fail("payments.pending_order_ttl", "must be positive")
+	}
+
+	switch c.Images.Storage {
+	case "local":
+		if c.Images.Dir == "" {
+			fail("images.dir", "is required for local storage")
+		}
+		if c.Images.BaseURL == "" {
+			c.Images.BaseURL = fmt.Sprintf("http://localhost:%d/media", c.Server.Port)
+			c.sources["images.base_url"] = "from server.port"
+		}
+		if u, err := url.Parse(c.Images.BaseURL); err != nil || u.Scheme == "" || u.Host == "" || u.Path == "" {
+			fail("images.base_url", "%q must be an absolute URL with a path, such as https://shop.example.com/media", c.Images.BaseURL)
+		}
+	case "s3":
+		if c.Images.S3.Bucket == "" {
+			fail("images.s3.bucket", "is required for s3 storage")
+		}
+		if c.Images.S3.AccessKeyID == "" {
+			fail("images.s3.access_key_id", "is required for s3 storage")
+		}
+		if c.Images.S3.SecretAccessKey == "" {
+			fail("images.s3.secret_access_key", "is required for s3 storage")
+		}
+	default:
+		fail("images.storage", "must be local or s3, got %q", c.Images.Storage)
+	}
+
+	if c.Uploads.MaxBytes <= 0 {
+		fail("uploads.max_bytes", "must be positive")
+	}
+	if c.Uploads.QRSessionTTL <= 0 || c.Uploads.QRSessionTTL > MaxQRSessionTTL {
+		fail("uploads.qr_session_ttl", "must be a duration between 0 and %s, got %s", MaxQRSessionTTL, c.Uploads.QRSessionTTL)
+	}
+	if c.Uploads.QRMaxUploads < 1 || c.Uploads.QRMaxUploads > MaxQRUploads {
+		fail("uploads.qr_max_uploads", "must be from 1 to %d, got %d", MaxQRUploads, c.Uploads.QRMaxUploads)
+	}
+	return problems
+}
+
+// describe names where s can be set, or where it was, for error messages.
+func (c *Config) describe(s *setting) string {
+	if source, ok := c.sources[s.key]; ok {
+		return source
+	}
+	return fmt.Sprintf("set with %s or --%s", s.env, s.flagName())
+}
+
+func randomSecret() Secret {
+	b := make([]byte, minSecretLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return Secret(hex.EncodeToString(b))
+}
+
+// redactConnString hides the password in a PostgreSQL connection string,
+// whether a URL or key=value pairs.
+func redactConnString(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if key, _, ok := strings.Cut(f, "="); ok && key == "password" {
+			fields[i] = "password=xxxxx"
+		}
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile reads a YAML or TOML config file into its settings, keyed as
+// they are in Config's setting keys, with each value as text. Sections
+// become the leading parts of keys and lists become comma-separated.
+func readFile(name string) (map[string]string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("%s: config file must end in .yaml, .yml or .toml", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	values := make(map[string]string)
+	if err := flatten(name, "", doc, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flatten(name, prefix string, section map[string]any, values map[string]string) error {
+	for k, v := range section {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flatten(name, key, v, values); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				text, err := scalar(item)
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", name, key, err)
+				}
+				items[i] = text
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			text, err := scalar(v)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", name, key, err)
+			}
+			values[key] = text
+		}
+	}
+	return nil
+}
+
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("unexpected %T value", v)
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Print writes every setting with its value and where the value came from,
+// hiding secrets, so it is safe to log.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	if c.File != "" {
+		fmt.Fprintf(tw, "config\t%s\t\n", c.File)
+	}
+	for _, s := range c.settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.key, s.display(), c.Source(s.key))
+	}
+	return tw.Flush()
+}
+
+// display is how s's value may be shown.
+func (s *setting) display() string {
+	v := s.value.String()
+	switch {
+	case !s.secret || v == "":
+		return v
+	case s.show != nil:
+		return s.show(v)
+	}
+	return redacted
+}
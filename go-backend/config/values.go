@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Secret is a setting that must not end up in logs. It prints as
+// [REDACTED]; Reveal returns the value itself.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+// Reveal returns the secret.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// field is a setting's destination. It implements flag.Value, so every
+// source sets it by parsing text: flags and environment variables are
+// text, and config file values are turned into text.
+type field[T any] struct {
+	p      *T
+	parse  func(string) (T, error)
+	format func(T) string
+	isBool bool
+}
+
+func (f *field[T]) Set(s string) error {
+	v, err := f.parse(s)
+	if err != nil {
+		return err
+	}
+	*f.p = v
+	return nil
+}
+
+func (f *field[T]) String() string {
+	if f == nil || f.p == nil {
+		return ""
+	}
+	return f.format(*f.p)
+}
+
+// IsBoolFlag lets boolean flags be given without a value.
+func (f *field[T]) IsBoolFlag() bool {
+	return f.isBool
+}
+
+func stringField(p *string) *field[string] {
+	return &field[string]{p: p, parse: func(s string) (string, error) { return strings.TrimSpace(s), nil }, format: identity}
+}
+
+func secretField(p *Secret) *field[Secret] {
+	return &field[Secret]{
+		p:      p,
+		parse:  func(s string) (Secret, error) { return Secret(strings.TrimSpace(s)), nil },
+		format: func(s Secret) string { return string(s) },
+	}
+}
+
+func intField(p *int) *field[int] {
+	return &field[int]{p: p, parse: func(s string) (int, error) {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a whole number", s)
+		}
+		return n, nil
+	}, format: strconv.Itoa}
+}
+
+func boolField(p *bool) *field[bool] {
+	return &field[bool]{p: p, parse: func(s string) (bool, error) {
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return false, fmt.Errorf("%q is not true or false", s)
+		}
+		return b, nil
+	}, format: strconv.FormatBool, isBool: true}
+}
+
+func durationField(p *time.Duration) *field[time.Duration] {
+	return &field[time.Duration]{p: p, parse: func(s string) (time.Duration, error) {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a duration such as 90s, 5m or 1h30m", s)
+		}
+		return d, nil
+	}, format: time.Duration.String}
+}
+
+// byteUnits are the suffixes a size may have, largest first so "MB" isn't
+// read as "B".
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// bytesField takes a size in bytes, optionally with a KB, MB or GB suffix,
+// which are powers of 1024.
+func bytesField(p *int64) *field[int64] {
+	return &field[int64]{p: p, parse: func(s string) (int64, error) {
+		text := strings.ToUpper(strings.TrimSpace(s))
+		unit := int64(1)
+		for _, u := range byteUnits {
+			if strings.HasSuffix(text, u.suffix) {
+				text, unit = strings.TrimSpace(strings.TrimSuffix(text, u.suffix)), u.size
+				break
+			}
+		}
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a size such as 10MB or 512KB", s)
+		}
+		return n * unit, nil
+	}, format: formatBytes}
+}
+
+func formatBytes(n int64) string {
+	for _, u := range byteUnits {
+		if n != 0 && n%u.size == 0 {
+			return strconv.FormatInt(n/u.size, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
+
+// listField takes a comma-separated list.
+func listField(p *[]string) *field[[]string] {
+	return &field[[]string]{p: p, parse: func(s string) ([]string, error) {
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	}, format: func(l []string) string { return strings.Join(l, ",") }}
+}
+
+func identity(s string) string {
+	return s
+}
@@ -0,0 +1,279 @@
+// Package customization validates and prices the customisations a buyer
+// chooses for a product, such as an engraving or a colour, against the
+// options the seller offers on it.
+//
+// Every chosen option adds its Price to the product's unit price. TEXT and
+// DIMENSIONS options can also charge a UnitPrice per character and per
+// square unit of the given dimensions.
+package customization
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-backend/pricing"
+)
+
+// Kind is the kind of input an option asks for, stored in
+// customization_options.kind.
+type Kind string
+
+const (
+	// Text options take free text, such as an engraving.
+	Text Kind = "TEXT"
+	// Colour options take a #rrggbb colour.
+	Colour Kind = "COLOUR"
+	// Artwork options take the URL of an image the buyer uploaded.
+	Artwork Kind = "ARTWORK"
+	// Dimensions options take a width and a height.
+	Dimensions Kind = "DIMENSIONS"
+)
+
+// Valid reports whether k is a known kind.
+func (k Kind) Valid() bool {
+	switch k {
+	case Text, Colour, Artwork, Dimensions:
+		return true
+	}
+	return false
+}
+
+var colourRe = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// NormalizeColour lower-cases a #rrggbb colour, reporting whether s is one.
+func NormalizeColour(s string) (string, bool) {
+	c := strings.ToLower(strings.TrimSpace(s))
+	return c, colourRe.MatchString(c)
+}
+
+// Option is a customisation offered on a product.
+type Option struct {
+	ID       int32
+	Name     string
+	Kind     Kind
+	Required bool
+	// MaxLength caps TEXT values, in characters.
+	MaxLength int32
+	// AllowedValues lists the colours a COLOUR option offers; empty offers
+	// any colour.
+	AllowedValues []string
+	// The bounds of DIMENSIONS values. A zero maximum is unbounded.
+	MinWidth, MaxWidth, MinHeight, MaxHeight float64
+	// Price is added to the unit price when the option is chosen.
+	Price pricing.Cents
+	// UnitPrice is added per character of TEXT values and per square unit
+	// of DIMENSIONS values.
+	UnitPrice pricing.Cents
+}
+
+// Check reports whether o itself is well-formed.
+func (o Option) Check() error {
+	if strings.TrimSpace(o.Name) == "" {
+		return errors.New("option name must not be empty")
+	}
+	if !o.Kind.Valid() {
+		return fmt.Errorf("unknown option kind %q", o.Kind)
+	}
+	if o.Price < 0 || o.UnitPrice < 0 {
+		return errors.New("price modifiers must not be negative")
+	}
+	if o.UnitPrice != 0 && o.Kind != Text && o.Kind != Dimensions {
+		return fmt.Errorf("only %s and %s options have a unit price", Text, Dimensions)
+	}
+	if o.Kind == Text && o.MaxLength <= 0 {
+		return fmt.Errorf("%s options need a positive maximum length", Text)
+	}
+	if o.Kind != Text && o.MaxLength != 0 {
+		return fmt.Errorf("only %s options have a maximum length", Text)
+	}
+	if o.Kind != Colour && len(o.AllowedValues) > 0 {
+		return fmt.Errorf("only %s options have allowed values", Colour)
+	}
+	for _, v := range o.AllowedValues {
+		if _, ok := NormalizeColour(v); !ok {
+			return fmt.Errorf("allowed value %q is not a #rrggbb colour", v)
+		}
+	}
+	bounded := o.MinWidth != 0 || o.MaxWidth != 0 || o.MinHeight != 0 || o.MaxHeight != 0
+	if o.Kind != Dimensions && bounded {
+		return fmt.Errorf("only %s options have bounds", Dimensions)
+	}
+	if o.MinWidth < 0 || o.MinHeight < 0 ||
+		(o.MaxWidth != 0 && o.MaxWidth < o.MinWidth) || (o.MaxHeight != 0 && o.MaxHeight < o.MinHeight) {
+		return errors.New("bounds must not be negative and maxima must not be below minima")
+	}
+	return nil
+}
+
+// Choice is the value a buyer chose for an option. Width and Height are
+// used by DIMENSIONS options, Value by the others.
+type Choice struct {
+	OptionID      int32
+	Value         string
+	Width, Height float64
+}
+
+// Normalize returns c in the form it is stored in: trimmed, with colours
+// lower-cased and the fields the option's kind doesn't use cleared.
+func (o Option) Normalize(c Choice) Choice {
+	c.OptionID = o.ID
+	if o.Kind == Dimensions {
+		c.Value = ""
+		return c
+	}
+	c.Width, c.Height = 0, 0
+	c.Value = strings.TrimSpace(c.Value)
+	if o.Kind == Colour {
+		c.Value, _ = NormalizeColour(c.Value)
+	}
+	return c
+}
+
+// CheckChoice reports whether c, normalized, is a valid value for o.
+func (o Option) CheckChoice(c Choice) error {
+	switch o.Kind {
+	case Text:
+		if c.Value == "" {
+			return errors.New("must not be empty")
+		}
+		if n := utf8.RuneCountInString(c.Value); n > int(o.MaxLength) {
+			return fmt.Errorf("must be at most %d characters", o.MaxLength)
+		}
+		if strings.IndexFunc(c.Value, unicode.IsControl) >= 0 {
+			return errors.New("must not contain control characters")
+		}
+	case Colour:
+		if !colourRe.MatchString(c.Value) {
+			return errors.New("must be a #rrggbb colour")
+		}
+		if len(o.AllowedValues) == 0 {
+			return nil
+		}
+		for _, allowed := range o.AllowedValues {
+			if a, _ := NormalizeColour(allowed); a == c.Value {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(o.AllowedValues, ", "))
+	case Artwork:
+		u, err := url.Parse(c.Value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("must be the http(s) URL of an uploaded image")
+		}
+	case Dimensions:
+		if !(c.Width > 0) || !(c.Height > 0) || math.IsInf(c.Width, 0) || math.IsInf(c.Height, 0) {
+			return errors.New("width and height must be positive")
+		}
+		if c.Width < o.MinWidth || (o.MaxWidth != 0 && c.Width > o.MaxWidth) {
+			return fmt.Errorf("width must be between %s", bounds(o.MinWidth, o.MaxWidth))
+		}
+		if c.Height < o.MinHeight || (o.MaxHeight != 0 && c.Height > o.MaxHeight) {
+			return fmt.Errorf("height must be between %s", bounds(o.MinHeight, o.MaxHeight))
+		}
+	}
+	return nil
+}
+
+func bounds(min, max float64) string {
+	if max == 0 {
+		return fmt.Sprintf("%g and any size", min)
+	}
+	return fmt.Sprintf("%g and %g", min, max)
+}
+
+// PriceOf is what choosing c adds to the unit price.
+func (o Option) PriceOf(c Choice) pricing.Cents {
+	price := o.Price
+	switch o.Kind {
+	case Text:
+		price += o.UnitPrice * pricing.Cents(utf8.RuneCountInString(c.Value))
+	case Dimensions:
+		price += pricing.Cents(math.Round(float64(o.UnitPrice) * c.Width * c.Height))
+	}
+	return price
+}
+
+// Priced is a valid, normalized choice and what it adds to the unit price.
+type Priced struct {
+	Option Option
+	Choice Choice
+	Price  pricing.Cents
+}
+
+// Problem is an option whose choice failed validation.
+type Problem struct {
+	Option  string
+	Message string
+}
+
+// ValidationError lists every choice that failed validation.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Option + ": " + p.Message
+	}
+	return "invalid customizations: " + strings.Join(msgs, "; ")
+}
+
+// Apply validates choices against the options of a product and prices
+// them. The result follows the order of options, so equal configurations
+// come out equal. Unknown or repeated options and missing required ones
+// are reported in a *ValidationError along with invalid values.
+func Apply(options []Option, choices []Choice) ([]Priced, error) {
+	var e ValidationError
+	chosen := make(map[int32]Choice, len(choices))
+	for _, c := range choices {
+		if _, dup := chosen[c.OptionID]; dup {
+			e.Problems = append(e.Problems, Problem{Option: fmt.Sprintf("option %d", c.OptionID), Message: "is chosen twice"})
+		}
+		chosen[c.OptionID] = c
+	}
+
+	var priced []Priced
+	for _, o := range options {
+		c, ok := chosen[o.ID]
+		delete(chosen, o.ID)
+		if !ok {
+			if o.Required {
+				e.Problems = append(e.Problems, Problem{Option: o.Name, Message: "is required"})
+			}
+			continue
+		}
+		c = o.Normalize(c)
+		if err := o.CheckChoice(c); err != nil {
+			e.Problems = append(e.Problems, Problem{Option: o.Name, Message: err.Error()})
+			continue
+		}
+		priced = append(priced, Priced{Option: o, Choice: c, Price: o.PriceOf(c)})
+	}
+	for _, c := range choices {
+		if _, unknown := chosen[c.OptionID]; unknown {
+			e.Problems = append(e.Problems, Problem{Option: fmt.Sprintf("option %d", c.OptionID), Message: "is not offered on this product"})
+			delete(chosen, c.OptionID)
+		}
+	}
+
+	if len(e.Problems) > 0 {
+		return nil, &e
+	}
+	return priced, nil
+}
+
+// Total is what priced adds to the unit price altogether.
+func Total(priced []Priced) pricing.Cents {
+	var total pricing.Cents
+	for _, p := range priced {
+		total += p.Price
+	}
+	return total
+}
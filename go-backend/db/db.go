@@ -3,7 +3,8 @@ package db
 import (
 	"database/sql"
 	"log"
-	"os"
+
+	"go-backend/config"
 
 	_ "github.com/lib/pq"
 )
@@ -27,100 +28,20 @@ func CheckTables() {
 	}
 }
 
-func InitDB() {
-	// Use the External Database URL from Render
+// InitDB connects to the database cfg describes, with its pool limits.
+func InitDB(cfg config.Database) {
 	var err error
-
-	// err = godotenv.Load()
-	// if err != nil {
-	// 	log.Fatal("Error loading .env file")
-	// }
-
-	connStr := os.Getenv("DATABASE_URL")
-	if connStr == "" {
-		log.Fatal("DATABASE_URL environment variable is not set")
-	}
-
-	DB, err = sql.Open("postgres", connStr)
+	DB, err = sql.Open("postgres", cfg.URL.Reveal())
 	if err != nil {
 		log.Fatal(err)
 	}
+	DB.SetMaxOpenConns(cfg.MaxOpenConns)
+	DB.SetMaxIdleConns(cfg.MaxIdleConns)
+	DB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
 	if err = DB.Ping(); err != nil {
 		log.Fatal(err)
 	}
 
 	log.Println("Connected to database")
-
-	// Create tables
-	createTables()
-}
-
-func createTables() {
-	tables := []string{
-		`CREATE TABLE IF NOT EXISTS categories (
-            id SERIAL PRIMARY KEY,
-            name VARCHAR(100) NOT NULL,
-            parent_id INTEGER REFERENCES categories(id)
-        )`,
-		`CREATE TABLE IF NOT EXISTS products (
-            id SERIAL PRIMARY KEY,
-            name VARCHAR(255) NOT NULL,
-            description TEXT,
-            price DECIMAL(10, 2) NOT NULL,
-            stock_quantity INTEGER NOT NULL,
-            category_id INTEGER REFERENCES categories(id),
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        )`,
-		`CREATE TABLE IF NOT EXISTS product_images (
-            id SERIAL PRIMARY KEY,
-            product_id INTEGER REFERENCES products(id),
-            image_url VARCHAR(255) NOT NULL,
-            is_primary BOOLEAN DEFAULT false
-        )`,
-		`CREATE TABLE IF NOT EXISTS product_attributes (
-            id SERIAL PRIMARY KEY,
-            product_id INTEGER REFERENCES products(id),
-            attribute_name VARCHAR(100) NOT NULL,
-            attribute_value VARCHAR(255) NOT NULL
-        )`,
-		`CREATE TABLE IF NOT EXISTS users (
-            id SERIAL PRIMARY KEY,
-            email VARCHAR(255) UNIQUE NOT NULL,
-            password_hash VARCHAR(255) NOT NULL,
-            first_name VARCHAR(100),
-            last_name VARCHAR(100),
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        )`,
-		`CREATE TABLE IF NOT EXISTS orders (
-            id SERIAL PRIMARY KEY,
-            user_id INTEGER REFERENCES users(id),
-            total_amount DECIMAL(10, 2) NOT NULL,
-            status VARCHAR(50) NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        )`,
-		`CREATE TABLE IF NOT EXISTS order_items (
-            id SERIAL PRIMARY KEY,
-            order_id INTEGER REFERENCES orders(id),
-            product_id INTEGER REFERENCES products(id),
-            quantity INTEGER NOT NULL,
-            price_at_time DECIMAL(10, 2) NOT NULL
-        )`,
-		`CREATE TABLE IF NOT EXISTS reviews (
-            id SERIAL PRIMARY KEY,
-            product_id INTEGER REFERENCES products(id),
-            user_id INTEGER REFERENCES users(id),
-            rating INTEGER NOT NULL CHECK (rating >= 1 AND rating <= 5),
-            comment TEXT,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        )`,
-	}
-
-	for _, table := range tables {
-		_, err := DB.Exec(table)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
 }
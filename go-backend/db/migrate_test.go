@@ -0,0 +1,100 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// The migrations shipped in the binary number from 1 without gaps, and each
+// can be rolled back.
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s is in place %d; versions must number from 1 without gaps", m.Version, m.Name, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has an empty script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoadMigrationsFiles(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []int64
+		wantErr string
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"migrations/0010_later.up.sql":    file("CREATE TABLE b ();"),
+				"migrations/0010_later.down.sql":  file("DROP TABLE b;"),
+				"migrations/0002_first.up.sql":    file("CREATE TABLE a ();"),
+				"migrations/0002_first.down.sql":  file("DROP TABLE a;"),
+				"migrations/0003_second.up.sql":   file("ALTER TABLE a ADD x INT;"),
+				"migrations/0003_second.down.sql": file("ALTER TABLE a DROP x;"),
+			},
+			want: []int64{2, 3, 10},
+		},
+		{
+			name:    "bad name",
+			files:   fstest.MapFS{"migrations/0001-init.up.sql": file("")},
+			wantErr: "invalid migration file name",
+		},
+		{
+			name:    "no down script",
+			files:   fstest.MapFS{"migrations/0001_init.up.sql": file("CREATE TABLE a ();")},
+			wantErr: "has no down script",
+		},
+		{
+			name:    "no up script",
+			files:   fstest.MapFS{"migrations/0001_init.down.sql": file("DROP TABLE a;")},
+			wantErr: "has no up script",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"migrations/0001_init.up.sql":    file("CREATE TABLE a ();"),
+				"migrations/0001_other.down.sql": file("DROP TABLE a;"),
+			},
+			wantErr: "conflicting names",
+		},
+	}
+	for _, tt := range tests {
+		migrations, err := loadMigrations(tt.files)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want one saying %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []int64
+		for _, m := range migrations {
+			got = append(got, m.Version)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: versions %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: versions %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
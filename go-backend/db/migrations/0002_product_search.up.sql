@@ -0,0 +1,47 @@
+-- Full-text search over products. search_vector weights the name (A) above
+-- the description (B) and attribute values (C); it is maintained by triggers
+-- on products and product_attributes. pg_trgm backs the typo-tolerant
+-- fallback on product names.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products ADD COLUMN search_vector tsvector;
+
+CREATE FUNCTION product_search_vector(p_id INTEGER, p_name TEXT, p_description TEXT) RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('english', coalesce(p_name, '')), 'A')
+        || setweight(to_tsvector('english', coalesce(p_description, '')), 'B')
+        || setweight(to_tsvector('english', coalesce(
+            (SELECT string_agg(attribute_value, ' ') FROM product_attributes WHERE product_id = p_id), '')), 'C')
+$$ LANGUAGE SQL STABLE;
+
+CREATE FUNCTION products_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := product_search_vector(NEW.id, NEW.name, NEW.description);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_search_vector_update
+    BEFORE INSERT OR UPDATE OF name, description ON products
+    FOR EACH ROW EXECUTE FUNCTION products_search_vector_trigger();
+
+CREATE FUNCTION product_attributes_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE products SET search_vector = product_search_vector(id, name, description) WHERE id = OLD.product_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE products SET search_vector = product_search_vector(id, name, description) WHERE id = NEW.product_id;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_attributes_search_vector_update
+    AFTER INSERT OR UPDATE OR DELETE ON product_attributes
+    FOR EACH ROW EXECUTE FUNCTION product_attributes_search_vector_trigger();
+
+UPDATE products SET search_vector = product_search_vector(id, name, description);
+
+CREATE INDEX products_search_vector_idx ON products USING GIN (search_vector);
+CREATE INDEX products_name_trgm_idx ON products USING GIN (name gin_trgm_ops);
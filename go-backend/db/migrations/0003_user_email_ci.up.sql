@@ -0,0 +1,3 @@
+-- Email addresses are unique regardless of case. The application stores them
+-- lower-cased; this index keeps rows written by other clients honest too.
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(email));
@@ -0,0 +1,5 @@
+-- Every account is a customer unless promoted. The first admin has to be
+-- promoted by hand: UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'customer'
+        CHECK (role IN ('customer', 'seller', 'admin'));
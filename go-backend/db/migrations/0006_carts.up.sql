@@ -0,0 +1,24 @@
+-- Shopping carts. Anonymous carts are found by their token; a user has at
+-- most one cart. Carts are deleted once expires_at has passed, which every
+-- write pushes back.
+CREATE TABLE IF NOT EXISTS carts (
+    id SERIAL PRIMARY KEY,
+    token TEXT UNIQUE,
+    user_id INTEGER UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    CHECK ((token IS NULL) <> (user_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS carts_expires_at_idx ON carts (expires_at);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id SERIAL PRIMARY KEY,
+    cart_id INTEGER NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    price_at_add DECIMAL(10, 2) NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (cart_id, product_id)
+);
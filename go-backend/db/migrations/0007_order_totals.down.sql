@@ -0,0 +1,10 @@
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS line_total,
+    DROP COLUMN IF EXISTS tax,
+    DROP COLUMN IF EXISTS discount;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shipping_total,
+    DROP COLUMN IF EXISTS tax_total,
+    DROP COLUMN IF EXISTS discount_total,
+    DROP COLUMN IF EXISTS subtotal;
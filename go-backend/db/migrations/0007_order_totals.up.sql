@@ -0,0 +1,17 @@
+-- Orders keep the breakdown of their total as computed at checkout, and
+-- each item its share of it.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS discount_total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_total DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS shipping_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS discount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS line_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+-- Existing orders were never split up; treat their items as undiscounted and
+-- untaxed and the stored total as the subtotal.
+UPDATE order_items SET line_total = price_at_time * quantity;
+UPDATE orders SET subtotal = total_amount;
@@ -0,0 +1,13 @@
+-- Stock held for carts in checkout. A product's available quantity is its
+-- stock_quantity less the unexpired reservations of other carts; orders take
+-- their quantities out of stock_quantity directly.
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    cart_id INTEGER NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (cart_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS stock_reservations_product_id_idx ON stock_reservations (product_id, expires_at);
+CREATE INDEX IF NOT EXISTS stock_reservations_expires_at_idx ON stock_reservations (expires_at);
@@ -0,0 +1,23 @@
+-- Orders move through a fixed lifecycle (see package orderstatus). Every
+-- change is recorded with who made it; actor_id is NULL for changes made by
+-- the system.
+UPDATE orders SET status = upper(status);
+
+ALTER TABLE orders ADD CONSTRAINT orders_status_check
+    CHECK (status IN ('PENDING', 'PAID', 'FULFILLING', 'SHIPPED', 'DELIVERED', 'CANCELLED', 'REFUNDED'));
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_id_idx ON order_status_history (order_id, created_at);
+
+-- Existing orders get a creation entry for their current status.
+INSERT INTO order_status_history (order_id, to_status, actor_id, created_at)
+SELECT id, status, user_id, COALESCE(created_at, now()) FROM orders;
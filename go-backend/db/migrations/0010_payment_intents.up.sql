@@ -0,0 +1,20 @@
+-- Attempts to collect an order's total through a payment provider.
+-- reference is the provider's ID for the payment, set once the provider has
+-- authorized it.
+CREATE TABLE IF NOT EXISTS payment_intents (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    reference TEXT,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    amount_refunded DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL
+        CHECK (status IN ('PENDING', 'AUTHORIZED', 'CAPTURED', 'FAILED', 'VOIDED', 'REFUNDED')),
+    failure_reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (provider, reference),
+    CHECK (amount_refunded BETWEEN 0 AND amount)
+);
+
+CREATE INDEX IF NOT EXISTS payment_intents_order_id_idx ON payment_intents (order_id);
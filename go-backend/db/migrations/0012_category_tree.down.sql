@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS products_category_id_idx;
+DROP INDEX IF EXISTS categories_parent_id_idx;
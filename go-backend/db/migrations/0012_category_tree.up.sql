@@ -0,0 +1,4 @@
+-- Walking the category tree and counting the products in a subtree look up
+-- categories by parent and products by category.
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+CREATE INDEX IF NOT EXISTS products_category_id_idx ON products (category_id);
@@ -0,0 +1,22 @@
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS sku,
+    DROP COLUMN IF EXISTS variant_id;
+
+DELETE FROM stock_reservations WHERE variant_id IS NOT NULL;
+DROP INDEX IF EXISTS stock_reservations_line_idx;
+ALTER TABLE stock_reservations
+    DROP COLUMN IF EXISTS variant_id,
+    ADD PRIMARY KEY (cart_id, product_id);
+
+DELETE FROM cart_items WHERE variant_id IS NOT NULL;
+DROP INDEX IF EXISTS cart_items_line_idx;
+ALTER TABLE cart_items
+    DROP COLUMN IF EXISTS variant_id,
+    ADD UNIQUE (cart_id, product_id);
+
+DELETE FROM product_images WHERE variant_id IS NOT NULL;
+ALTER TABLE product_images DROP COLUMN IF EXISTS variant_id;
+
+DROP TABLE IF EXISTS product_variants;
+DROP INDEX IF EXISTS product_attributes_product_id_idx;
+DROP TABLE IF EXISTS attribute_definitions;
@@ -0,0 +1,54 @@
+-- Attribute definitions apply to products in a category and its
+-- subcategories; a definition in a subcategory overrides one of the same
+-- name further up. allowed_values is only set for ENUM attributes.
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id SERIAL PRIMARY KEY,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('TEXT', 'NUMBER', 'BOOLEAN', 'ENUM')),
+    allowed_values TEXT[] NOT NULL DEFAULT '{}',
+    required BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS attribute_definitions_category_name_idx
+    ON attribute_definitions (category_id, lower(name));
+
+CREATE INDEX IF NOT EXISTS product_attributes_product_id_idx ON product_attributes (product_id);
+
+-- Variants are purchasable versions of a product with their own SKU, price
+-- and stock. options holds the attribute values that set a variant apart,
+-- as a JSON array of {"name", "value"} objects.
+CREATE TABLE IF NOT EXISTS product_variants (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(100) NOT NULL UNIQUE,
+    price DECIMAL(10, 2) NOT NULL,
+    stock_quantity INTEGER NOT NULL DEFAULT 0,
+    options JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS product_variants_product_id_idx ON product_variants (product_id);
+
+-- Images with a variant_id belong to that variant rather than the product.
+ALTER TABLE product_images
+    ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE;
+
+-- Cart lines, reservations and order items name the variant bought, if any.
+-- A cart holds one line per product and variant.
+ALTER TABLE cart_items
+    ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS cart_items_cart_id_product_id_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS cart_items_line_idx ON cart_items (cart_id, product_id, COALESCE(variant_id, 0));
+
+ALTER TABLE stock_reservations
+    ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE CASCADE,
+    DROP CONSTRAINT IF EXISTS stock_reservations_pkey;
+
+CREATE UNIQUE INDEX IF NOT EXISTS stock_reservations_line_idx ON stock_reservations (cart_id, product_id, COALESCE(variant_id, 0));
+
+-- The SKU is kept on the order item in case the variant is deleted later.
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS sku VARCHAR(100);
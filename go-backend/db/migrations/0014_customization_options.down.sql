@@ -0,0 +1,8 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS customizations;
+
+DELETE FROM cart_items WHERE customizations <> '[]';
+DROP INDEX IF EXISTS cart_items_line_idx;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS customizations;
+CREATE UNIQUE INDEX IF NOT EXISTS cart_items_line_idx ON cart_items (cart_id, product_id, COALESCE(variant_id, 0));
+
+DROP TABLE IF EXISTS customization_options;
@@ -0,0 +1,36 @@
+-- Customization options are what a seller lets buyers customise on a
+-- product. Which rule columns apply depends on the kind: max_length for
+-- TEXT, allowed_values (#rrggbb colours) for COLOUR and the width and height
+-- bounds for DIMENSIONS; zero leaves a rule unset. price is added to the
+-- unit price when the option is chosen, unit_price per character of TEXT
+-- and per square unit of DIMENSIONS.
+CREATE TABLE IF NOT EXISTS customization_options (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('TEXT', 'COLOUR', 'ARTWORK', 'DIMENSIONS')),
+    required BOOLEAN NOT NULL DEFAULT false,
+    max_length INTEGER NOT NULL DEFAULT 0,
+    allowed_values TEXT[] NOT NULL DEFAULT '{}',
+    min_width DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_width DOUBLE PRECISION NOT NULL DEFAULT 0,
+    min_height DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_height DOUBLE PRECISION NOT NULL DEFAULT 0,
+    price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    unit_price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS customization_options_product_name_idx
+    ON customization_options (product_id, lower(name));
+
+-- Cart lines and order items keep the buyer's choices as a JSON array of
+-- {"optionId", "name", "kind", "value", "width", "height", "price"} objects.
+-- A cart holds one line per product, variant and set of customizations.
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS customizations JSONB NOT NULL DEFAULT '[]';
+
+DROP INDEX IF EXISTS cart_items_line_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS cart_items_line_idx
+    ON cart_items (cart_id, product_id, COALESCE(variant_id, 0), customizations);
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS customizations JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+ALTER TABLE product_images DROP COLUMN IF EXISTS image_id;
+DROP TABLE IF EXISTS image_renditions;
+ALTER TABLE images DROP COLUMN IF EXISTS width, DROP COLUMN IF EXISTS height;
@@ -0,0 +1,23 @@
+-- Uploaded photos are kept as renditions in several sizes and formats,
+-- re-encoded without their metadata. images keeps what was uploaded; its
+-- storage_key now names the largest JPEG rendition.
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS width INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS height INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS image_renditions (
+    id SERIAL PRIMARY KEY,
+    image_id INTEGER NOT NULL REFERENCES images(id) ON DELETE CASCADE,
+    size VARCHAR(10) NOT NULL CHECK (size IN ('THUMB', 'MEDIUM', 'LARGE')),
+    format VARCHAR(10) NOT NULL CHECK (format IN ('JPEG', 'WEBP')),
+    storage_key TEXT NOT NULL UNIQUE,
+    width INTEGER NOT NULL CHECK (width > 0),
+    height INTEGER NOT NULL CHECK (height > 0),
+    bytes BIGINT NOT NULL,
+    UNIQUE (image_id, size, format)
+);
+
+-- Product photos picked from the seller's uploads link to them for their
+-- renditions.
+ALTER TABLE product_images
+    ADD COLUMN IF NOT EXISTS image_id INTEGER REFERENCES images(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE images DROP COLUMN IF EXISTS session_id;
+DROP TABLE IF EXISTS upload_sessions;
@@ -0,0 +1,21 @@
+-- QR upload sessions let a phone upload images for a user until they
+-- expire, are revoked or have received max_uploads images. id is the
+-- secret in the QR code's URL. Uploads through a session bound to a
+-- product also become photos of it.
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INTEGER REFERENCES products(id) ON DELETE CASCADE,
+    max_uploads INTEGER NOT NULL CHECK (max_uploads > 0),
+    upload_count INTEGER NOT NULL DEFAULT 0 CHECK (upload_count BETWEEN 0 AND max_uploads),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS upload_sessions_expires_at_idx ON upload_sessions (expires_at);
+
+ALTER TABLE images
+    ADD COLUMN IF NOT EXISTS session_id TEXT REFERENCES upload_sessions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS images_session_id_idx ON images (session_id);
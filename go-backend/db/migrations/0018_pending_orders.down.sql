@@ -0,0 +1 @@
+DROP INDEX IF EXISTS orders_pending_created_at_idx;
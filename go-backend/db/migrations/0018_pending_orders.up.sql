@@ -0,0 +1,3 @@
+-- Orders left PENDING hold their stock; the ones not paid in time are found
+-- by age and cancelled.
+CREATE INDEX IF NOT EXISTS orders_pending_created_at_idx ON orders (created_at) WHERE status = 'PENDING';
@@ -0,0 +1,78 @@
+// Package events names the topics the shop publishes on and what their
+// messages hold. Messages only name the rows that changed: subscribers
+// read the rows back, so they get current data and can be checked against
+// it, and the messages stay well within what a Broker backed by NOTIFY can
+// carry.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go-backend/pubsub"
+)
+
+// ImageUploadedTopic is where uploads to a QR upload session are announced.
+func ImageUploadedTopic(sessionID string) string {
+	return "image_uploaded." + sessionID
+}
+
+// OrderTopic is where changes to an order are announced.
+func OrderTopic(orderID int32) string {
+	return fmt.Sprintf("order.%d", orderID)
+}
+
+// UserOrdersTopic is where changes to any of a user's orders are announced.
+func UserOrdersTopic(userID int32) string {
+	return fmt.Sprintf("user_orders.%d", userID)
+}
+
+// StockTopic is where changes to the stock of a product or its variants
+// are announced, including the product being deleted.
+func StockTopic(productID int32) string {
+	return fmt.Sprintf("stock.%d", productID)
+}
+
+// ImageUploaded is the message on ImageUploadedTopic.
+type ImageUploaded struct {
+	ImageID int32 `json:"imageId"`
+}
+
+// OrderUpdated is the message on OrderTopic and UserOrdersTopic.
+type OrderUpdated struct {
+	OrderID int32 `json:"orderId"`
+}
+
+// StockChanged is the message on StockTopic.
+type StockChanged struct {
+	ProductID int32 `json:"productId"`
+}
+
+// PublishImageUploaded announces that imageID arrived in the QR upload
+// session sessionID.
+func PublishImageUploaded(ctx context.Context, b pubsub.Broker, sessionID string, imageID int32) error {
+	return publish(ctx, b, ImageUploadedTopic(sessionID), ImageUploaded{ImageID: imageID})
+}
+
+// PublishOrderUpdated announces a change to an order of userID.
+func PublishOrderUpdated(ctx context.Context, b pubsub.Broker, orderID, userID int32) error {
+	msg := OrderUpdated{OrderID: orderID}
+	if err := publish(ctx, b, OrderTopic(orderID), msg); err != nil {
+		return err
+	}
+	return publish(ctx, b, UserOrdersTopic(userID), msg)
+}
+
+// PublishStockChanged announces a change to the stock of productID.
+func PublishStockChanged(ctx context.Context, b pubsub.Broker, productID int32) error {
+	return publish(ctx, b, StockTopic(productID), StockChanged{ProductID: productID})
+}
+
+func publish(ctx context.Context, b pubsub.Broker, topic string, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.Publish(ctx, topic, data)
+}
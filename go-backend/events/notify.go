@@ -0,0 +1,145 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"go-backend/models"
+	"go-backend/pubsub"
+	"go-backend/store"
+)
+
+// Notify returns a copy of s whose order and product stores announce the
+// changes they make on b: order changes on OrderTopic and UserOrdersTopic,
+// and stock changes, whether from orders being placed or cancelled or from
+// sellers editing products and variants, on StockTopic. Stock reservations
+// don't change a product's stock and aren't announced.
+//
+// Announcements are made once a write has succeeded. Failing to make one
+// is logged rather than failing the write, which has already happened.
+func Notify(s *store.Store, b pubsub.Broker) *store.Store {
+	notifying := *s
+	notifying.Orders = &orders{OrderStore: s.Orders, b: b}
+	notifying.Products = &products{ProductStore: s.Products, b: b}
+	return &notifying
+}
+
+type orders struct {
+	store.OrderStore
+	b pubsub.Broker
+}
+
+func (s *orders) Create(ctx context.Context, userID int32, order store.NewOrder) (*models.Order, error) {
+	o, err := s.OrderStore.Create(ctx, userID, order)
+	if err != nil {
+		return nil, err
+	}
+	orderUpdated(ctx, s.b, o)
+	seen := make(map[int32]bool)
+	for _, item := range order.Items {
+		if !seen[item.ProductID] {
+			seen[item.ProductID] = true
+			stockChanged(ctx, s.b, item.ProductID)
+		}
+	}
+	return o, nil
+}
+
+func (s *orders) SetStatus(ctx context.Context, id int32, change store.StatusChange) (*models.Order, error) {
+	o, err := s.OrderStore.SetStatus(ctx, id, change)
+	if err != nil {
+		return nil, err
+	}
+	orderUpdated(ctx, s.b, o)
+	if change.Restock {
+		items, err := s.OrderStore.Items(ctx, id)
+		if err != nil {
+			log.Printf("events: listing items of order %d: %v", id, err)
+		}
+		seen := make(map[int32]bool)
+		for _, item := range items {
+			if !seen[item.ProductID] {
+				seen[item.ProductID] = true
+				stockChanged(ctx, s.b, item.ProductID)
+			}
+		}
+	}
+	return o, nil
+}
+
+type products struct {
+	store.ProductStore
+	b pubsub.Broker
+}
+
+func (s *products) Update(ctx context.Context, id int32, input models.ProductInput) (*models.Product, error) {
+	old, err := s.ProductStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.ProductStore.Update(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	if p.StockQuantity != old.StockQuantity {
+		stockChanged(ctx, s.b, id)
+	}
+	return p, nil
+}
+
+func (s *products) Delete(ctx context.Context, id int32) (bool, error) {
+	deleted, err := s.ProductStore.Delete(ctx, id)
+	if deleted {
+		stockChanged(ctx, s.b, id)
+	}
+	return deleted, err
+}
+
+func (s *products) CreateVariant(ctx context.Context, productID int32, input models.ProductVariantInput) (*models.ProductVariant, error) {
+	v, err := s.ProductStore.CreateVariant(ctx, productID, input)
+	if err != nil {
+		return nil, err
+	}
+	stockChanged(ctx, s.b, productID)
+	return v, nil
+}
+
+func (s *products) UpdateVariant(ctx context.Context, id int32, input models.ProductVariantInput) (*models.ProductVariant, error) {
+	old, err := s.ProductStore.GetVariant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.ProductStore.UpdateVariant(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	if v.StockQuantity != old.StockQuantity {
+		stockChanged(ctx, s.b, v.ProductID)
+	}
+	return v, nil
+}
+
+func (s *products) DeleteVariant(ctx context.Context, id int32) (bool, error) {
+	v, err := s.ProductStore.GetVariant(ctx, id)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return false, err
+	}
+	deleted, err := s.ProductStore.DeleteVariant(ctx, id)
+	if deleted && v != nil {
+		stockChanged(ctx, s.b, v.ProductID)
+	}
+	return deleted, err
+}
+
+func orderUpdated(ctx context.Context, b pubsub.Broker, o *models.Order) {
+	if err := PublishOrderUpdated(ctx, b, o.ID, o.UserID); err != nil {
+		log.Printf("events: announcing order %d: %v", o.ID, err)
+	}
+}
+
+func stockChanged(ctx context.Context, b pubsub.Broker, productID int32) {
+	if err := PublishStockChanged(ctx, b, productID); err != nil {
+		log.Printf("events: announcing stock of product %d: %v", productID, err)
+	}
+}
@@ -0,0 +1,133 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-backend/models"
+	"go-backend/orderstatus"
+	"go-backend/store"
+)
+
+// recorder is a Broker that remembers what was published.
+type recorder struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *recorder) Publish(ctx context.Context, topic string, msg []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, topic+" "+string(msg))
+	return nil
+}
+
+func (r *recorder) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	return nil, errors.New("recorder: can't subscribe")
+}
+
+// take returns what was published since it was last called.
+func (r *recorder) take() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msgs := strings.Join(r.msgs, "\n")
+	r.msgs = nil
+	return msgs
+}
+
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+	b := &recorder{}
+	st := Notify(store.NewMemory(), b)
+	u, err := st.Users.Create(ctx, store.NewUser{Email: "ann@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat, err := st.Categories.Create(ctx, "Mugs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := models.ProductInput{Name: "Mug", Price: 10, StockQuantity: 5, CategoryID: cat.ID}
+	p, err := st.Products.Create(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var o *models.Order
+	order := func() string {
+		return fmt.Sprintf("order.%d {\"orderId\":%d}\nuser_orders.%d {\"orderId\":%d}\n", o.ID, o.ID, u.ID, o.ID)
+	}
+	stock := fmt.Sprintf(`stock.%d {"productId":%d}`, p.ID, p.ID)
+	steps := []struct {
+		name string
+		do   func() error
+		want func() string
+	}{
+		{
+			name: "place an order",
+			do: func() (err error) {
+				// The product is announced once, though it is on two lines.
+				o, err = st.Orders.Create(ctx, u.ID, store.NewOrder{Total: 20, Items: []store.NewOrderItem{
+					{ProductID: p.ID, Quantity: 1, UnitPrice: 10, LineTotal: 10},
+					{ProductID: p.ID, Quantity: 1, UnitPrice: 10, LineTotal: 10},
+				}})
+				return err
+			},
+			want: func() string { return order() + stock },
+		},
+		{
+			name: "cancel it",
+			do: func() error {
+				_, err := st.Orders.SetStatus(ctx, o.ID, store.StatusChange{From: string(orderstatus.Pending), To: string(orderstatus.Cancelled), Restock: true})
+				return err
+			},
+			want: func() string { return order() + stock },
+		},
+		{
+			name: "rename the product",
+			do: func() error {
+				input.Name = "Big mug"
+				_, err := st.Products.Update(ctx, p.ID, input)
+				return err
+			},
+			want: func() string { return "" },
+		},
+		{
+			name: "restock the product",
+			do: func() error {
+				input.StockQuantity = 50
+				_, err := st.Products.Update(ctx, p.ID, input)
+				return err
+			},
+			want: func() string { return stock },
+		},
+		{
+			name: "add a variant",
+			do: func() error {
+				_, err := st.Products.CreateVariant(ctx, p.ID, models.ProductVariantInput{SKU: "MUG-L", Price: 12, StockQuantity: 3})
+				return err
+			},
+			want: func() string { return stock },
+		},
+		{
+			name: "delete the product",
+			do: func() error {
+				_, err := st.Products.Delete(ctx, p.ID)
+				return err
+			},
+			want: func() string { return stock },
+		},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got, want := b.take(), step.want(); got != want {
+			t.Errorf("%s published:\n%s\nwant:\n%s", step.name, got, want)
+		}
+	}
+}
@@ -6,18 +6,28 @@ toolchain go1.23.2
 
 require github.com/gorilla/mux v1.8.1
 
-require github.com/gorilla/securecookie v1.1.2 // indirect
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/HugoSmits86/nativewebp v0.9.3
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	golang.org/x/image v0.23.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/gorilla/securecookie v1.1.2 // indirect
+	golang.org/x/crypto v0.31.0
+)
 
 require (
 	github.com/felixge/httpsnoop v1.0.4 // indirect
-	github.com/gorilla/handlers v1.5.2 // indirect
 	github.com/google/uuid v1.6.0
-	github.com/gorilla/mux v1.8.1
-
+	github.com/gorilla/handlers v1.5.2
 	github.com/gorilla/sessions v1.4.0
-	github.com/graph-gophers/graphql-go v1.5.0 // indirect
+	github.com/gorilla/websocket v1.5.3
+	github.com/graph-gophers/graphql-go v1.5.0
 	github.com/graphql-go/graphql v0.8.1
 	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/lib/pq v1.10.9
 	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 )
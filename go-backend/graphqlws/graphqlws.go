@@ -0,0 +1,371 @@
+// Package graphqlws serves GraphQL over WebSocket with the
+// graphql-transport-ws protocol, as spoken by the graphql-ws client:
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+//
+// Subscriptions, queries and mutations are all run through
+// graphql.Schema.Subscribe; each operation gets its own context, cancelled
+// when the client completes it or the connection closes.
+package graphqlws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Protocol is the WebSocket subprotocol the handler speaks.
+const Protocol = "graphql-transport-ws"
+
+const (
+	// initTimeout is how long a client has to send connection_init.
+	initTimeout = 10 * time.Second
+	// keepAliveInterval is how often the server pings an idle client, so
+	// proxies don't drop the connection.
+	keepAliveInterval = 30 * time.Second
+	writeTimeout      = 10 * time.Second
+	// maxMessageSize bounds incoming messages, which hold a query and its
+	// variables.
+	maxMessageSize = 64 << 10
+)
+
+// Close codes defined by the protocol.
+const (
+	closeBadRequest      = 4400
+	closeUnauthorized    = 4401
+	closeForbidden       = 4403
+	closeBadProtocol     = 4406
+	closeInitTimeout     = 4408
+	closeSubscriberTaken = 4409
+	closeTooManyInits    = 4429
+)
+
+// Message types defined by the protocol.
+const (
+	typeConnectionInit = "connection_init"
+	typeConnectionAck  = "connection_ack"
+	typePing           = "ping"
+	typePong           = "pong"
+	typeSubscribe      = "subscribe"
+	typeNext           = "next"
+	typeError          = "error"
+	typeComplete       = "complete"
+)
+
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type subscribePayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler upgrades requests to WebSocket connections and runs the GraphQL
+// operations sent over them against Schema.
+type Handler struct {
+	Schema *graphql.Schema
+	// Init is called with the upgrade request's context and the payload of
+	// connection_init, and returns the context operations run in, for
+	// example carrying a principal authenticated by a token in the
+	// payload; it must be derived from ctx. An error closes the connection
+	// as Forbidden. Nil accepts every connection with the request's
+	// context.
+	Init func(ctx context.Context, payload json.RawMessage) (context.Context, error)
+	// CheckOrigin reports whether a browser on the request's Origin may
+	// connect. Nil allows only the server's own origin.
+	CheckOrigin func(r *http.Request) bool
+
+	mu           sync.Mutex
+	conns        map[*conn]struct{}
+	shuttingDown bool
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{Protocol},
+		CheckOrigin:  h.CheckOrigin,
+	}
+	h.mu.Lock()
+	shuttingDown := h.shuttingDown
+	h.mu.Unlock()
+	if shuttingDown {
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already answered the request.
+		return
+	}
+	c := &conn{h: h, ws: ws, ops: make(map[string]*operation)}
+	if ws.Subprotocol() != Protocol {
+		c.close(closeBadProtocol, "Subprotocol not acceptable")
+		return
+	}
+	if !h.track(c) {
+		c.close(websocket.CloseGoingAway, "Server is shutting down")
+		return
+	}
+	defer h.untrack(c)
+	c.serve(r.Context())
+}
+
+// Shutdown closes every connection as going away, which graphql-ws clients
+// take as a cue to reconnect, elsewhere if they are behind a load balancer,
+// and refuses new ones. http.Server.Shutdown doesn't wait for or close
+// upgraded connections, so register it with RegisterOnShutdown.
+func (h *Handler) Shutdown() {
+	h.mu.Lock()
+	h.shuttingDown = true
+	conns := make([]*conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+	for _, c := range conns {
+		c.close(websocket.CloseGoingAway, "Server is shutting down")
+	}
+}
+
+// track records c as open, unless the handler is shutting down.
+func (h *Handler) track(c *conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.shuttingDown {
+		return false
+	}
+	if h.conns == nil {
+		h.conns = make(map[*conn]struct{})
+	}
+	h.conns[c] = struct{}{}
+	return true
+}
+
+func (h *Handler) untrack(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, c)
+}
+
+// conn is a single client connection.
+type conn struct {
+	h  *Handler
+	ws *websocket.Conn
+
+	// writeMu serialises writes, which gorilla/websocket requires.
+	writeMu sync.Mutex
+
+	mu  sync.Mutex
+	ctx context.Context // set once the connection is acknowledged
+	ops map[string]*operation
+}
+
+// operation is a running operation. A client may complete one and start
+// another with the same ID before the first has finished, so the first
+// checks it is still the one registered before cleaning up after itself.
+type operation struct {
+	cancel context.CancelFunc
+}
+
+func (c *conn) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer c.ws.Close()
+
+	initTimer := time.AfterFunc(initTimeout, func() {
+		if c.operationContext() == nil {
+			c.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+	go c.keepAlive(ctx)
+
+	c.ws.SetReadLimit(maxMessageSize)
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.close(closeBadRequest, "Invalid message received")
+			return
+		}
+		if !c.handle(ctx, msg) {
+			return
+		}
+	}
+}
+
+// handle acts on a message from the client, returning false once the
+// connection has been closed.
+func (c *conn) handle(ctx context.Context, msg message) bool {
+	switch msg.Type {
+	case typeConnectionInit:
+		if c.operationContext() != nil {
+			c.close(closeTooManyInits, "Too many initialisation requests")
+			return false
+		}
+		opCtx := ctx
+		if c.h.Init != nil {
+			var err error
+			opCtx, err = c.h.Init(ctx, msg.Payload)
+			if err != nil {
+				c.close(closeForbidden, "Forbidden")
+				return false
+			}
+		}
+		c.mu.Lock()
+		c.ctx = opCtx
+		c.mu.Unlock()
+		c.write(message{Type: typeConnectionAck})
+
+	case typePing:
+		c.write(message{Type: typePong})
+
+	case typePong:
+
+	case typeSubscribe:
+		opCtx := c.operationContext()
+		if opCtx == nil {
+			c.close(closeUnauthorized, "Unauthorized")
+			return false
+		}
+		var payload subscribePayload
+		if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil || payload.Query == "" {
+			c.close(closeBadRequest, "Invalid message received")
+			return false
+		}
+		c.mu.Lock()
+		if _, taken := c.ops[msg.ID]; taken {
+			c.mu.Unlock()
+			c.close(closeSubscriberTaken, "Subscriber for "+msg.ID+" already exists")
+			return false
+		}
+		opCtx, cancel := context.WithCancel(opCtx)
+		op := &operation{cancel: cancel}
+		c.ops[msg.ID] = op
+		c.mu.Unlock()
+		go c.run(opCtx, msg.ID, op, payload)
+
+	case typeComplete:
+		c.mu.Lock()
+		op, ok := c.ops[msg.ID]
+		delete(c.ops, msg.ID)
+		c.mu.Unlock()
+		if ok {
+			op.cancel()
+		}
+
+	default:
+		c.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+	return true
+}
+
+// run executes an operation, sending each result as it arrives. Results
+// holding only errors, such as those of a query that doesn't validate, are
+// sent as they are, which graphql-ws clients report like any other
+// failed operation.
+func (c *conn) run(ctx context.Context, id string, op *operation, payload subscribePayload) {
+	defer func() {
+		if c.end(id, op) {
+			// The client didn't complete the operation itself, so tell it
+			// there is nothing more to come.
+			c.write(message{ID: id, Type: typeComplete})
+		}
+	}()
+
+	results, err := c.h.Schema.Subscribe(ctx, payload.Query, payload.OperationName, payload.Variables)
+	if err != nil {
+		c.sendError(ctx, id, op, []map[string]string{{"message": err.Error()}})
+		return
+	}
+	for result := range results {
+		resp, ok := result.(*graphql.Response)
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(resp)
+		if err != nil {
+			log.Printf("graphqlws: encoding result: %v", err)
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		c.write(message{ID: id, Type: typeNext, Payload: data})
+	}
+}
+
+// sendError sends an error message, which also ends the operation, so no
+// complete follows.
+func (c *conn) sendError(ctx context.Context, id string, op *operation, errs interface{}) {
+	if ctx.Err() == nil {
+		data, _ := json.Marshal(errs)
+		c.write(message{ID: id, Type: typeError, Payload: data})
+	}
+	c.end(id, op)
+}
+
+// end unregisters and cancels op, reporting whether it was still running
+// under id rather than completed by the client.
+func (c *conn) end(id string, op *operation) bool {
+	c.mu.Lock()
+	running := c.ops[id] == op
+	if running {
+		delete(c.ops, id)
+	}
+	c.mu.Unlock()
+	op.cancel()
+	return running
+}
+
+func (c *conn) operationContext() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ctx
+}
+
+func (c *conn) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.write(message{Type: typePing})
+		}
+	}
+}
+
+func (c *conn) write(msg message) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.ws.WriteJSON(msg); err != nil {
+		// The read loop notices the broken connection and cleans up.
+		c.ws.Close()
+	}
+}
+
+// close ends the connection with a protocol close code. Closing the
+// underlying connection stops the read loop, which cancels every
+// operation.
+func (c *conn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+	c.ws.Close()
+}
@@ -0,0 +1,167 @@
+package graphqlws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const testSchema = `
+	schema { query: Query subscription: Subscription }
+	type Query { hello: String! }
+	type Subscription {
+		# Counts up to to, or waits for the operation to end if it is 0.
+		count(to: Int!): Int!
+	}
+`
+
+type testResolver struct{}
+
+func (*testResolver) Hello() string { return "hello" }
+
+func (*testResolver) Count(ctx context.Context, args struct{ To int32 }) <-chan int32 {
+	out := make(chan int32)
+	go func() {
+		defer close(out)
+		if args.To == 0 {
+			<-ctx.Done()
+		}
+		for i := int32(1); i <= args.To; i++ {
+			select {
+			case out <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// dial starts a Handler accepting connection_init payloads of
+// {"token":"ok"} and connects to it with the given subprotocols.
+func dial(t *testing.T, subprotocols ...string) *websocket.Conn {
+	t.Helper()
+	h := &Handler{
+		Schema: graphql.MustParseSchema(testSchema, &testResolver{}),
+		Init: func(ctx context.Context, payload json.RawMessage) (context.Context, error) {
+			var p struct{ Token string }
+			if json.Unmarshal(payload, &p) != nil || p.Token != "ok" {
+				return nil, errors.New("bad token")
+			}
+			return ctx, nil
+		},
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	d := websocket.Dialer{Subprotocols: subprotocols}
+	ws, _, err := d.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func send(t *testing.T, ws *websocket.Conn, msg string) {
+	t.Helper()
+	if err := ws.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expect reads the next message and compares it, as JSON, with want.
+func expect(t *testing.T, ws *websocket.Conn, want string) {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, got, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading %s: %v", want, err)
+	}
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ws := dial(t, Protocol)
+	send(t, ws, `{"type":"connection_init","payload":{"token":"ok"}}`)
+	expect(t, ws, `{"type":"connection_ack"}`)
+	send(t, ws, `{"type":"ping"}`)
+	expect(t, ws, `{"type":"pong"}`)
+
+	send(t, ws, `{"id":"1","type":"subscribe","payload":{"query":"subscription { count(to: 2) }"}}`)
+	expect(t, ws, `{"id":"1","type":"next","payload":{"data":{"count":1}}}`)
+	expect(t, ws, `{"id":"1","type":"next","payload":{"data":{"count":2}}}`)
+	expect(t, ws, `{"id":"1","type":"complete"}`)
+
+	send(t, ws, `{"id":"2","type":"subscribe","payload":{"query":"{ hello }"}}`)
+	expect(t, ws, `{"id":"2","type":"next","payload":{"data":{"hello":"hello"}}}`)
+	expect(t, ws, `{"id":"2","type":"complete"}`)
+
+	// An operation the client completes gets no complete back, and its ID
+	// can be used again.
+	send(t, ws, `{"id":"3","type":"subscribe","payload":{"query":"subscription { count(to: 0) }"}}`)
+	send(t, ws, `{"id":"3","type":"complete"}`)
+	send(t, ws, `{"id":"3","type":"subscribe","payload":{"query":"subscription { count(to: 1) }"}}`)
+	expect(t, ws, `{"id":"3","type":"next","payload":{"data":{"count":1}}}`)
+	expect(t, ws, `{"id":"3","type":"complete"}`)
+}
+
+func TestHandlerCloses(t *testing.T) {
+	const (
+		init      = `{"type":"connection_init","payload":{"token":"ok"}}`
+		subscribe = `{"id":"1","type":"subscribe","payload":{"query":"subscription { count(to: 0) }"}}`
+	)
+	tests := []struct {
+		name         string
+		subprotocols []string
+		messages     []string
+		want         int
+	}{
+		{name: "no subprotocol", want: closeBadProtocol},
+		{name: "subscribe before init", subprotocols: []string{Protocol}, messages: []string{subscribe}, want: closeUnauthorized},
+		{name: "init refused", subprotocols: []string{Protocol}, messages: []string{`{"type":"connection_init","payload":{"token":"no"}}`}, want: closeForbidden},
+		{name: "init twice", subprotocols: []string{Protocol}, messages: []string{init, init}, want: closeTooManyInits},
+		{name: "ID in use", subprotocols: []string{Protocol}, messages: []string{init, subscribe, subscribe}, want: closeSubscriberTaken},
+		{name: "not JSON", subprotocols: []string{Protocol}, messages: []string{init, `{"type":`}, want: closeBadRequest},
+		{name: "unknown type", subprotocols: []string{Protocol}, messages: []string{init, `{"type":"shout"}`}, want: closeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := dial(t, tt.subprotocols...)
+			for _, msg := range tt.messages {
+				send(t, ws, msg)
+			}
+			ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for {
+				_, _, err := ws.ReadMessage()
+				var closeErr *websocket.CloseError
+				if errors.As(err, &closeErr) {
+					if closeErr.Code != tt.want {
+						t.Errorf("closed with %d %s, want %d", closeErr.Code, closeErr.Text, tt.want)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("got %v, want close %d", err, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"go-backend/config"
+	"go-backend/imaging"
+	"go-backend/models"
+	"go-backend/storage"
+
+	"github.com/google/uuid"
+)
+
+// UploadedImage is an image as listed by /user/images.
+type UploadedImage struct {
+	ID       int32  `json:"id"`
+	UserID   int32  `json:"userId"`
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+	// ThumbnailURL is the URL of the THUMB JPEG rendition.
+	ThumbnailURL string `json:"thumbnailUrl"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+func uploadedImage(img *models.Image, renditions []*models.ImageRendition) UploadedImage {
+	u := UploadedImage{
+		ID:           img.ID,
+		UserID:       img.OwnerID,
+		URL:          imageStorage.URL(img.Key),
+		Filename:     img.Filename,
+		MimeType:     img.MimeType,
+		Size:         img.Size,
+		Checksum:     img.Checksum,
+		Width:        img.Width,
+		Height:       img.Height,
+		ThumbnailURL: imageStorage.URL(img.Key),
+		CreatedAt:    img.CreatedAt,
+	}
+	for _, r := range renditions {
+		if r.Size == string(imaging.Thumb) && r.Format == string(imaging.JPEG) {
+			u.ThumbnailURL = imageStorage.URL(r.Key)
+		}
+	}
+	return u
+}
+
+// newImageStorage sets up where uploaded images are kept: on local disk,
+// served from cfg.BaseURL, or in any S3-compatible service.
+func newImageStorage(cfg config.Images) (storage.Storage, error) {
+	switch cfg.Storage {
+	case "local":
+		log.Printf("Storing images in %s", cfg.Dir)
+		return storage.NewLocal(cfg.Dir, cfg.BaseURL)
+	case "s3":
+		log.Printf("Storing images in bucket %s", cfg.S3.Bucket)
+		return storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKeyID,
+			SecretKey: cfg.S3.SecretAccessKey.Reveal(),
+			PathStyle: cfg.S3.PathStyle,
+			PublicURL: cfg.S3.PublicURL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown image storage %q", cfg.Storage)
+	}
+}
+
+// saveImage processes an uploaded image, stores its renditions and records
+// it as owned by ownerID and, if sessionID isn't nil, as one of the uploads
+// of that QR upload session. The renditions are removed again if the image
+// can't be recorded, including when the session has closed.
+func saveImage(ctx context.Context, ownerID int32, sessionID *string, filename string, data []byte) (*models.Image, error) {
+	processed, err := imaging.Process(data, imaging.DefaultLimits())
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := "images/" + uuid.New().String() + "/"
+	var renditions []models.ImageRendition
+	// largest is the key of the LARGE JPEG, which stands for the image.
+	var largest string
+	removeStored := func() {
+		for _, r := range renditions {
+			if err := imageStorage.Delete(ctx, r.Key); err != nil {
+				log.Printf("Failed to delete unrecorded rendition %s: %v", r.Key, err)
+			}
+		}
+	}
+	for _, r := range processed.Renditions {
+		key := prefix + strings.ToLower(string(r.Size)) + r.Format.Ext()
+		if err := imageStorage.Put(ctx, key, r.Data, r.Format.MimeType()); err != nil {
+			removeStored()
+			return nil, fmt.Errorf("storing image: %w", err)
+		}
+		renditions = append(renditions, models.ImageRendition{
+			Size:   string(r.Size),
+			Format: string(r.Format),
+			Key:    key,
+			Width:  int32(r.Width),
+			Height: int32(r.Height),
+			Bytes:  int64(len(r.Data)),
+		})
+		if r.Size == imaging.Large && r.Format == imaging.JPEG {
+			largest = key
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	img, err := images.Create(ctx, models.Image{
+		OwnerID:   ownerID,
+		SessionID: sessionID,
+		Key:       largest,
+		Filename:  truncate(filename, 255),
+		MimeType:  processed.MimeType,
+		Size:      int64(len(data)),
+		Checksum:  hex.EncodeToString(sum[:]),
+		Width:     int32(processed.Width),
+		Height:    int32(processed.Height),
+	}, renditions)
+	if err != nil {
+		removeStored()
+		return nil, fmt.Errorf("recording image: %w", err)
+	}
+	return img, nil
+}
+
+// truncate cuts s to at most n characters.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,259 @@
+// Package imaging turns uploaded photos into the renditions the shop
+// serves. Uploads are identified by their content, not their name or
+// declared type, checked against dimension limits before they are decoded,
+// turned upright according to their EXIF orientation and re-encoded, which
+// drops EXIF and every other kind of metadata, such as the location a
+// phone recorded.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Size names a rendition size.
+type Size string
+
+const (
+	Thumb  Size = "THUMB"
+	Medium Size = "MEDIUM"
+	Large  Size = "LARGE"
+)
+
+// Sizes lists the rendition sizes, largest first, with the square each
+// fits within. Images smaller than that are not enlarged.
+var Sizes = []struct {
+	Size Size
+	Max  int
+}{
+	{Large, 1600},
+	{Medium, 800},
+	{Thumb, 200},
+}
+
+// Format names the encoding of a rendition.
+type Format string
+
+const (
+	JPEG Format = "JPEG"
+	WebP Format = "WEBP"
+)
+
+// Formats lists the formats every size is rendered in.
+var Formats = []Format{JPEG, WebP}
+
+// MimeType is the content type of the format.
+func (f Format) MimeType() string {
+	if f == WebP {
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
+
+// Ext is the file extension of the format.
+func (f Format) Ext() string {
+	if f == WebP {
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+// jpegQuality is the quality JPEG renditions are encoded at. WebP
+// renditions are lossless.
+const jpegQuality = 85
+
+// sourceTypes are the content types accepted for uploads.
+var sourceTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ErrUnsupported is returned for uploads that aren't a JPEG, PNG, GIF or
+// WebP image.
+var ErrUnsupported = errors.New("file is not a JPEG, PNG, GIF or WebP image")
+
+// ErrCorrupt is returned for uploads that look like an image but can't be
+// decoded.
+var ErrCorrupt = errors.New("image is damaged or truncated")
+
+// Limits bounds the dimensions of uploads. Uploads are checked as stored,
+// before they are turned upright.
+type Limits struct {
+	MinWidth, MinHeight int
+	MaxWidth, MaxHeight int
+	// MaxPixels bounds width × height, and so the memory decoding takes.
+	MaxPixels int
+}
+
+// DefaultLimits accepts anything from 100×100 up to photos of 40
+// megapixels.
+func DefaultLimits() Limits {
+	return Limits{
+		MinWidth:  100,
+		MinHeight: 100,
+		MaxWidth:  10000,
+		MaxHeight: 10000,
+		MaxPixels: 40_000_000,
+	}
+}
+
+// LimitError is returned for uploads whose dimensions are out of bounds.
+type LimitError struct {
+	Width, Height int
+	Reason        string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("image is %d×%d pixels; %s", e.Width, e.Height, e.Reason)
+}
+
+func (l Limits) check(width, height int) error {
+	switch {
+	case width < l.MinWidth || height < l.MinHeight:
+		return &LimitError{width, height, fmt.Sprintf("it must be at least %d×%d", l.MinWidth, l.MinHeight)}
+	case width > l.MaxWidth || height > l.MaxHeight:
+		return &LimitError{width, height, fmt.Sprintf("it must be at most %d×%d", l.MaxWidth, l.MaxHeight)}
+	case width*height > l.MaxPixels:
+		return &LimitError{width, height, fmt.Sprintf("it must have at most %d pixels", l.MaxPixels)}
+	}
+	return nil
+}
+
+// Rendition is an encoded rendition of an upload.
+type Rendition struct {
+	Size          Size
+	Format        Format
+	Width, Height int
+	Data          []byte
+}
+
+// Result is a processed upload.
+type Result struct {
+	// MimeType is the sniffed type of the upload.
+	MimeType string
+	// Width and Height are the dimensions of the upright image.
+	Width, Height int
+	// Renditions holds every size in every format, largest first.
+	Renditions []Rendition
+}
+
+// Sniff returns the content type of an upload if it is one this package
+// accepts, and ErrUnsupported otherwise.
+func Sniff(data []byte) (string, error) {
+	mimeType := http.DetectContentType(data)
+	if !sourceTypes[mimeType] {
+		return "", ErrUnsupported
+	}
+	return mimeType, nil
+}
+
+// Process checks an upload against limits and renders it in every size and
+// format. Animated GIFs are reduced to their first frame.
+func Process(data []byte, limits Limits) (*Result, error) {
+	mimeType, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if err := limits.check(cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+
+	res := &Result{MimeType: mimeType, Width: cfg.Width, Height: cfg.Height}
+	orientation := 1
+	if mimeType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	if orientation >= 5 {
+		res.Width, res.Height = res.Height, res.Width
+	}
+	// Every size fits a square, so the image can be scaled before it is
+	// turned upright, which is cheaper. Each size is scaled down from the
+	// one before rather than from the full image for the same reason.
+	src := img
+	for i, s := range Sizes {
+		src = fit(src, s.Max)
+		if i == 0 {
+			src = orient(src, orientation)
+		}
+		for _, f := range Formats {
+			encoded, err := encode(src, f)
+			if err != nil {
+				return nil, fmt.Errorf("encoding %s %s rendition: %w", s.Size, f, err)
+			}
+			b := src.Bounds()
+			res.Renditions = append(res.Renditions, Rendition{Size: s.Size, Format: f, Width: b.Dx(), Height: b.Dy(), Data: encoded})
+		}
+	}
+	return res, nil
+}
+
+// fit scales img down to fit within a max × max square, keeping its aspect
+// ratio. Smaller images are returned as they are.
+func fit(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return img
+	}
+	if w >= h {
+		w, h = max, maxInt(1, (h*max+w/2)/w)
+	} else {
+		w, h = maxInt(1, (w*max+h/2)/h), max
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func encode(img image.Image, f Format) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch f {
+	case WebP:
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: jpegQuality})
+	}
+	return buf.Bytes(), err
+}
+
+// flatten draws img over white, as JPEG has no transparency.
+func flatten(img image.Image) image.Image {
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return img
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
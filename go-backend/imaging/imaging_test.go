@@ -0,0 +1,215 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns a w×h image with a distinct colour in every pixel.
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// exifSegment returns an APP1 segment holding an EXIF orientation tag.
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // offset of the first IFD
+	order.PutUint16(tiff[8:], 1) // one entry
+	order.PutUint16(tiff[10:], orientationTag)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1) // one value
+	order.PutUint16(tiff[18:], orientation)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	segment = append(segment, "Exif\x00\x00"...)
+	segment = append(segment, tiff...)
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(segment)-2))
+	return segment
+}
+
+// withSegment inserts a segment into a JPEG right after its SOI marker.
+func withSegment(data, segment []byte) []byte {
+	out := append([]byte(nil), data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestSniff(t *testing.T) {
+	img := testImage(4, 4)
+	var pngData, gifData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.Encode(&gifData, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"jpeg", encodeJPEG(t, img), "image/jpeg"},
+		{"png", pngData.Bytes(), "image/png"},
+		{"gif", gifData.Bytes(), "image/gif"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image/webp"},
+		{"text", []byte("just some text"), ""},
+		{"pdf", []byte("%PDF-1.7\n"), ""},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), ""},
+		{"bmp", []byte("BM\x00\x00\x00\x00"), ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		got, err := Sniff(tt.data)
+		if tt.want == "" {
+			if !errors.Is(err, ErrUnsupported) {
+				t.Errorf("%s: Sniff() = %q, %v, want ErrUnsupported", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Sniff() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := encodeJPEG(t, testImage(8, 8))
+	truncated := exifSegment(binary.BigEndian, 6)
+	binary.BigEndian.PutUint16(truncated[2:], 0xFFF0)
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no EXIF", plain, 1},
+		{"little-endian", withSegment(plain, exifSegment(binary.LittleEndian, 6)), 6},
+		{"big-endian", withSegment(plain, exifSegment(binary.BigEndian, 8)), 8},
+		{"mirrored", withSegment(plain, exifSegment(binary.BigEndian, 2)), 2},
+		{"out of range", withSegment(plain, exifSegment(binary.BigEndian, 9)), 1},
+		{"segment overruns the file", withSegment(plain, truncated), 1},
+		{"after other segments", withSegment(plain, append([]byte{0xFF, 0xE0, 0, 4, 'J', 'F'}, exifSegment(binary.LittleEndian, 3)...)), 3},
+		{"not a JPEG", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		if got := jpegOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: jpegOrientation() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	const w, h = 3, 2
+	src := testImage(w, h)
+	corner := src.At(0, 0)
+	tests := []struct {
+		orientation int
+		// wantW and wantH are the upright size, x and y where the stored
+		// top-left pixel ends up.
+		wantW, wantH int
+		x, y         int
+	}{
+		{1, w, h, 0, 0},
+		{2, w, h, w - 1, 0},
+		{3, w, h, w - 1, h - 1},
+		{4, w, h, 0, h - 1},
+		{5, h, w, 0, 0},
+		{6, h, w, h - 1, 0},
+		{7, h, w, h - 1, w - 1},
+		{8, h, w, 0, w - 1},
+	}
+	for _, tt := range tests {
+		img := orient(src, tt.orientation)
+		b := img.Bounds()
+		if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("orientation %d: %d×%d, want %d×%d", tt.orientation, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			continue
+		}
+		if got := color.NRGBAModel.Convert(img.At(tt.x, tt.y)); got != corner {
+			t.Errorf("orientation %d: (%d, %d) is %v, want the top-left pixel %v", tt.orientation, tt.x, tt.y, got, corner)
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	// Stored sideways: the camera was turned a quarter clockwise.
+	sideways := withSegment(encodeJPEG(t, testImage(300, 200)), exifSegment(binary.LittleEndian, 6))
+	res, err := Process(sideways, DefaultLimits())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.MimeType != "image/jpeg" || res.Width != 200 || res.Height != 300 {
+		t.Errorf("Process() = %s %d×%d, want image/jpeg 200×300", res.MimeType, res.Width, res.Height)
+	}
+	want := map[Size][2]int{Large: {200, 300}, Medium: {200, 300}, Thumb: {133, 200}}
+	if len(res.Renditions) != len(Sizes)*len(Formats) {
+		t.Errorf("got %d renditions, want %d", len(res.Renditions), len(Sizes)*len(Formats))
+	}
+	for _, r := range res.Renditions {
+		if size := want[r.Size]; r.Width != size[0] || r.Height != size[1] {
+			t.Errorf("%s %s rendition is %d×%d, want %d×%d", r.Size, r.Format, r.Width, r.Height, size[0], size[1])
+		}
+		if _, format, err := image.DecodeConfig(bytes.NewReader(r.Data)); err != nil || (format == "jpeg") != (r.Format == JPEG) {
+			t.Errorf("%s %s rendition decodes as %q: %v", r.Size, r.Format, format, err)
+		}
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	plain := encodeJPEG(t, testImage(300, 200))
+	tests := []struct {
+		name   string
+		data   []byte
+		limits Limits
+		want   error
+	}{
+		{"not an image", []byte("just some text"), DefaultLimits(), ErrUnsupported},
+		{"truncated", plain[:len(plain)/2], DefaultLimits(), ErrCorrupt},
+		{"header only", plain[:20], DefaultLimits(), ErrCorrupt},
+		{"too small", encodeJPEG(t, testImage(50, 300)), DefaultLimits(), &LimitError{}},
+		{"too wide", plain, Limits{MaxWidth: 299, MaxHeight: 1000, MaxPixels: 1 << 20}, &LimitError{}},
+		{"too many pixels", plain, Limits{MaxWidth: 1000, MaxHeight: 1000, MaxPixels: 300*200 - 1}, &LimitError{}},
+	}
+	for _, tt := range tests {
+		_, err := Process(tt.data, tt.limits)
+		var limitErr *LimitError
+		switch want := tt.want.(type) {
+		case *LimitError:
+			if !errors.As(err, &limitErr) {
+				t.Errorf("%s: Process() error = %v, want a *LimitError", tt.name, err)
+			}
+		default:
+			if !errors.Is(err, want) {
+				t.Errorf("%s: Process() error = %v, want %v", tt.name, err, want)
+			}
+		}
+	}
+}
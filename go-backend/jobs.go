@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// cartCleanupInterval is how often expired carts are deleted.
+const cartCleanupInterval = time.Hour
+
+// reservationCleanupInterval is how often expired stock reservations are
+// deleted. They stop holding stock when they expire; this only reclaims
+// the rows.
+const reservationCleanupInterval = 5 * time.Minute
+
+// unpaidOrderCleanupInterval is how often orders left unpaid for longer
+// than the configured payments.pending_order_ttl are cancelled, releasing
+// their stock.
+const unpaidOrderCleanupInterval = 5 * time.Minute
+
+// idempotencyCleanupInterval is how often expired idempotency keys are
+// deleted.
+const idempotencyCleanupInterval = time.Hour
+
+// cleanupExpired calls deleteExpired with the current time every interval
+// until ctx is done, logging how many rows of what it cleaned up. A deletion
+// cut short by ctx isn't reported as a failure.
+func cleanupExpired(ctx context.Context, what string, interval time.Duration, deleteExpired func(context.Context, time.Time) (int64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := deleteExpired(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to clean up expired %s: %v", what, err)
+		} else if n > 0 {
+			log.Printf("Cleaned up %d expired %s", n, what)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// workers runs the server's background jobs until they are stopped.
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkers() *workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workers{ctx: ctx, cancel: cancel}
+}
+
+// cleanupExpired runs cleanupExpired in the background.
+func (w *workers) cleanupExpired(what string, interval time.Duration, deleteExpired func(context.Context, time.Time) (int64, error)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		cleanupExpired(w.ctx, what, interval, deleteExpired)
+	}()
+}
+
+// Stop cancels the jobs, interrupting any work in progress, and waits for
+// them to return.
+func (w *workers) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
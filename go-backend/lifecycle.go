@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go-backend/config"
+	"go-backend/db"
+)
+
+// readinessPingTimeout bounds the database check of the readiness probe.
+const readinessPingTimeout = 2 * time.Second
+
+// shuttingDown is set once the server has been told to stop, from when it
+// reports itself not ready.
+var shuttingDown atomic.Bool
+
+// livenessHandler answers as long as the process is serving at all.
+func livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, "ok\n")
+}
+
+// readinessHandler answers 200 while the server should be sent traffic:
+// until it starts shutting down, and while it can reach the database.
+func readinessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if shuttingDown.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), readinessPingTimeout)
+	defer cancel()
+	if err := db.DB.PingContext(ctx); err != nil {
+		log.Printf("readiness: %v", err)
+		http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(w, "ok\n")
+}
+
+// shutdown stops the server once it has been signalled. It reports itself
+// not ready and keeps serving for cfg.DrainDelay, so load balancers stop
+// sending it requests, then stops listening and waits for the requests in
+// flight, cutting those still running when cfg.ShutdownTimeout is up or a
+// second signal arrives. Then it stops the background jobs and closes
+// resources, such as the event broker and the database pool, in the order
+// given.
+func shutdown(srv *http.Server, cfg config.Server, signals <-chan os.Signal, jobs *workers, resources ...io.Closer) {
+	shuttingDown.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %v again, stopping now", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	select {
+	case <-time.After(cfg.DrainDelay):
+	case <-ctx.Done():
+	}
+
+	log.Print("Waiting for requests in flight")
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Cutting requests still in flight: %v", err)
+		srv.Close()
+	}
+
+	jobs.Stop()
+	for _, r := range resources {
+		if err := r.Close(); err != nil {
+			log.Printf("Shutdown: %v", err)
+		}
+	}
+	log.Print("Server stopped")
+}
@@ -0,0 +1,115 @@
+// Package loaders batches the per-field lookups made by nested GraphQL
+// resolvers into a few set-based store calls, caching results for the
+// lifetime of a single request.
+package loaders
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc fetches the values for keys. Keys absent from the returned map
+// resolve to the zero value.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader coalesces Load calls made within a short window into one BatchFunc
+// call and memoises the results.
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+	wait  time.Duration
+
+	mu       sync.Mutex
+	cache    map[K]*result[V]
+	pending  []K
+	expected map[K]struct{}
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewLoader returns a Loader that waits up to wait after the first uncached
+// Load before dispatching the batch.
+func NewLoader[K comparable, V any](wait time.Duration, fetch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     wait,
+		cache:    make(map[K]*result[V]),
+		expected: make(map[K]struct{}),
+	}
+}
+
+// Load returns the value for key, batching it with other concurrent loads.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	res, ok := l.cache[key]
+	if !ok {
+		res = &result[V]{done: make(chan struct{})}
+		l.cache[key] = res
+		l.pending = append(l.pending, key)
+		if len(l.pending) == 1 {
+			time.AfterFunc(l.wait, func() { l.dispatch(ctx) })
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// Expect registers keys that are likely to be loaded soon, such as the IDs
+// of every item in a list that was just resolved. They are folded into the
+// next dispatched batch so a whole list costs one fetch regardless of how
+// the executor schedules the individual field resolvers.
+func (l *Loader[K, V]) Expect(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := l.cache[key]; !ok {
+			l.expected[key] = struct{}{}
+		}
+	}
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	keys := l.pending
+	l.pending = nil
+	for key := range l.expected {
+		if _, ok := l.cache[key]; !ok {
+			l.cache[key] = &result[V]{done: make(chan struct{})}
+			keys = append(keys, key)
+		}
+	}
+	l.expected = make(map[K]struct{})
+	results := make([]*result[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.cache[key]
+	}
+	l.mu.Unlock()
+
+	values, err := l.fetch(ctx, keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, key := range keys {
+		res := results[i]
+		if err != nil {
+			res.err = err
+			// Don't memoise failures; a later Load retries the key.
+			delete(l.cache, key)
+		} else {
+			res.value = values[key]
+		}
+		close(res.done)
+	}
+}
@@ -0,0 +1,125 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a BatchFunc that remembers the batches it was called with and
+// resolves each key to its square, leaving out keys above 100.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	fail    error
+}
+
+func (r *recorder) fetch(ctx context.Context, keys []int) (map[int]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	batch := append([]int(nil), keys...)
+	sort.Ints(batch)
+	r.batches = append(r.batches, batch)
+	if r.fail != nil {
+		return nil, r.fail
+	}
+	values := make(map[int]int)
+	for _, k := range keys {
+		if k <= 100 {
+			values[k] = k * k
+		}
+	}
+	return values, nil
+}
+
+func (r *recorder) calls() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fmt.Sprint(r.batches)
+}
+
+// loadAll loads keys concurrently and returns the values in order.
+func loadAll(t *testing.T, l *Loader[int, int], keys ...int) []int {
+	t.Helper()
+	values := make([]int, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i, key int) {
+			defer wg.Done()
+			values[i], errs[i] = l.Load(context.Background(), key)
+		}(i, key)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return values
+}
+
+func TestLoaderBatches(t *testing.T) {
+	var r recorder
+	l := NewLoader(50*time.Millisecond, r.fetch)
+
+	got := loadAll(t, l, 3, 1, 2, 1, 101)
+	if fmt.Sprint(got) != "[9 1 4 1 0]" {
+		t.Errorf("loaded %v, want [9 1 4 1 0]", got)
+	}
+	if calls := r.calls(); calls != "[[1 2 3 101]]" {
+		t.Errorf("fetched %s, want one batch of the distinct keys", calls)
+	}
+
+	// Cached keys aren't fetched again; new ones are.
+	loadAll(t, l, 2, 4)
+	if calls := r.calls(); calls != "[[1 2 3 101] [4]]" {
+		t.Errorf("fetched %s, want only 4 the second time", calls)
+	}
+}
+
+func TestLoaderExpect(t *testing.T) {
+	var r recorder
+	l := NewLoader(time.Millisecond, r.fetch)
+
+	l.Expect(5, 6, 7)
+	loadAll(t, l, 5)
+	loadAll(t, l, 6, 7)
+	if calls := r.calls(); calls != "[[5 6 7]]" {
+		t.Errorf("fetched %s, want the expected keys in the first batch", calls)
+	}
+}
+
+// A failed fetch fails its loads but isn't cached, so the keys can be tried
+// again.
+func TestLoaderRetriesFailures(t *testing.T) {
+	r := recorder{fail: errors.New("database unavailable")}
+	l := NewLoader(time.Millisecond, r.fetch)
+
+	if _, err := l.Load(context.Background(), 1); !errors.Is(err, r.fail) {
+		t.Fatalf("Load() error = %v, want %v", err, r.fail)
+	}
+	r.mu.Lock()
+	r.fail = nil
+	r.mu.Unlock()
+	if got := loadAll(t, l, 1); got[0] != 1 {
+		t.Errorf("Load() after recovering = %d, want 1", got[0])
+	}
+	if calls := r.calls(); calls != "[[1] [1]]" {
+		t.Errorf("fetched %s, want the key fetched again", calls)
+	}
+}
+
+func TestLoaderContextDone(t *testing.T) {
+	l := NewLoader(time.Hour, (&recorder{}).fetch)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Load(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Load() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,90 @@
+package loaders
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go-backend/models"
+	"go-backend/store"
+)
+
+// batchWait is how long a loader waits for more keys before fetching.
+const batchWait = time.Millisecond
+
+// Loaders holds the request-scoped loaders used by the type resolvers.
+type Loaders struct {
+	ProductByID              *Loader[int32, *models.Product]
+	CategoryByID             *Loader[int32, *models.Category]
+	ChildrenByCategoryID     *Loader[int32, []*models.Category]
+	ProductCountByCategoryID *Loader[int32, int32]
+	DefinitionsByCategoryID  *Loader[int32, []*models.AttributeDefinition]
+	VariantByID              *Loader[int32, *models.ProductVariant]
+	VariantsByProductID      *Loader[int32, []*models.ProductVariant]
+	ImagesByVariantID        *Loader[int32, []*models.ProductImage]
+	OptionsByProductID       *Loader[int32, []*models.CustomizationOption]
+	UserByID                 *Loader[int32, *models.User]
+	ImagesByProductID        *Loader[int32, []*models.ProductImage]
+	AttributesByProductID    *Loader[int32, []*models.ProductAttribute]
+	ReviewsByProductID       *Loader[int32, []*models.Review]
+	RenditionsByImageID      *Loader[int32, []*models.ImageRendition]
+}
+
+// New creates a fresh set of loaders over s. Each request should get its
+// own set so cached rows never outlive the request.
+func New(s *store.Store) *Loaders {
+	return &Loaders{
+		ProductByID:              NewLoader(batchWait, s.Products.GetMany),
+		CategoryByID:             NewLoader(batchWait, s.Categories.GetMany),
+		ChildrenByCategoryID:     NewLoader(batchWait, s.Categories.ChildrenByParents),
+		ProductCountByCategoryID: NewLoader(batchWait, s.Categories.ProductCounts),
+		DefinitionsByCategoryID:  NewLoader(batchWait, s.Categories.DefinitionsByCategories),
+		VariantByID:              NewLoader(batchWait, s.Products.GetVariants),
+		VariantsByProductID:      NewLoader(batchWait, s.Products.VariantsByProducts),
+		ImagesByVariantID:        NewLoader(batchWait, s.Products.ImagesByVariants),
+		OptionsByProductID:       NewLoader(batchWait, s.Products.CustomizationOptionsByProducts),
+		UserByID:                 NewLoader(batchWait, s.Users.GetMany),
+		ImagesByProductID:        NewLoader(batchWait, s.Products.ImagesByProducts),
+		AttributesByProductID:    NewLoader(batchWait, s.Products.AttributesByProducts),
+		ReviewsByProductID:       NewLoader(batchWait, s.Reviews.ListByProducts),
+		RenditionsByImageID:      NewLoader(batchWait, s.Images.RenditionsByImages),
+	}
+}
+
+// ExpectProducts primes the product-keyed loaders with a list of products
+// that is about to be resolved.
+func (l *Loaders) ExpectProducts(products []*models.Product) {
+	ids := make([]int32, 0, len(products))
+	var categoryIDs []int32
+	for _, p := range products {
+		ids = append(ids, p.ID)
+		if p.CategoryID != 0 {
+			categoryIDs = append(categoryIDs, p.CategoryID)
+		}
+	}
+	l.ImagesByProductID.Expect(ids...)
+	l.AttributesByProductID.Expect(ids...)
+	l.ReviewsByProductID.Expect(ids...)
+	l.VariantsByProductID.Expect(ids...)
+	l.CategoryByID.Expect(categoryIDs...)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the loaders attached to ctx, or nil.
+func FromContext(ctx context.Context) *Loaders {
+	l, _ := ctx.Value(contextKey{}).(*Loaders)
+	return l
+}
+
+// Middleware attaches a fresh set of loaders to every request.
+func Middleware(s *store.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), New(s))))
+	})
+}
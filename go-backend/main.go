@@ -1,55 +1,51 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
-	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
 	"os"
-	"sync"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"go-backend/auth"
+	"go-backend/config"
 	"go-backend/db"
+	"go-backend/events"
+	"go-backend/graphqlws"
+	"go-backend/loaders"
+	"go-backend/payments"
+	"go-backend/pubsub"
 	"go-backend/resolvers"
+	"go-backend/storage"
+	"go-backend/store"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
-	"github.com/gorilla/sessions"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
-	"github.com/skip2/go-qrcode"
 )
 
-type User struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-}
-
-type QRCode struct {
-	ID        string    `json:"id"`
-	URL       string    `json:"url"`
-	ExpiresAt time.Time `json:"expiresAt"`
-	UserID    string    `json:"userId"`
-}
-
-type UploadedImage struct {
-	ID     string `json:"id"`
-	UserID string `json:"userId"`
-	URL    string `json:"url"`
-}
-
 var (
-	users          = make(map[string]User)
-	qrCodes        = make(map[string]QRCode)
-	uploadedImages = make(map[string][]UploadedImage)
-	store          = sessions.NewCookieStore([]byte("secret-key"))
-	mu             sync.Mutex
+	sessionStore   *auth.Sessions
+	accounts       *auth.Accounts
+	tokens         *auth.Tokens
+	carts          store.CartStore
+	images         store.ImageStore
+	products       store.ProductStore
+	uploadSessions store.UploadSessionStore
+	uploadConfig   config.Uploads
+	imageStorage   storage.Storage
+	broker         pubsub.Broker
+	payProvider    payments.PaymentProvider
+	payProcessor   *payments.Processor
 )
 
 func LoadSchema(schemaPath string) string {
@@ -61,338 +57,280 @@ func LoadSchema(schemaPath string) string {
 }
 
 func main() {
-	// Initialize the database connection
-	db.InitDB()
+	cfg, err := config.Load(os.Args[1:], os.Getenv, os.Stderr)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return
+	case cfg == nil:
+		// The flags couldn't be parsed, which has already been reported
+		os.Exit(2)
+	case cfg.PrintConfig:
+		cfg.Print(os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	case err != nil:
+		log.Fatal(err)
+	}
+	for _, warning := range cfg.Warnings {
+		log.Print(warning)
+	}
+
+	if len(cfg.Args) > 0 {
+		if cfg.Args[0] != "migrate" {
+			log.Fatalf("unknown command %q", cfg.Args[0])
+		}
+		runMigrate(cfg, cfg.Args[1:])
+		return
+	}
+
+	var printed strings.Builder
+	cfg.Print(&printed)
+	log.Printf("Configuration:\n%s", printed.String())
+
+	// Initialize the database connection and bring the schema up to date
+	db.InitDB(cfg.Database)
+	if _, err := db.MigrateUp(context.Background(), db.DB); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
 	db.CheckTables()
 
 	// Load the GraphQL schema from the file
 	schemaPath := "schema/schema.graphql"
 	schemaString := LoadSchema(schemaPath)
 
+	// Events reach the subscribers on every instance through the database
+	pgBroker, err := pubsub.NewPostgres(db.DB, cfg.Database.URL.Reveal())
+	if err != nil {
+		log.Fatalf("Failed to listen for events: %v", err)
+	}
+	broker = pgBroker
+
+	// Writes through the store publish their events to the broker
+	st := events.Notify(store.NewPostgres(db.DB), broker)
+	accounts = auth.NewAccounts(st.Users)
+	sessionStore = auth.NewSessions([]byte(cfg.Auth.SessionSecret.Reveal()))
+	tokens = auth.NewTokens(st.Tokens, st.Users, auth.TokenConfig{
+		Issuer:      cfg.Auth.TokenIssuer,
+		AccessTTL:   cfg.Auth.AccessTokenTTL,
+		RefreshTTL:  cfg.Auth.RefreshTokenTTL,
+		KeyRotation: cfg.Auth.SigningKeyRotation,
+	})
+	authenticator := auth.NewAuthenticator(sessionStore, st.Users, tokens)
+	carts = st.Carts
+	images = st.Images
+	products = st.Products
+	uploadSessions = st.Sessions
+	uploadConfig = cfg.Uploads
+
+	imageStorage, err = newImageStorage(cfg.Images)
+	if err != nil {
+		log.Fatalf("Failed to set up image storage: %v", err)
+	}
+
+	// Payments go through the in-process fake gateway until a real
+	// provider is configured. The webhook handler and the resolvers share
+	// one processor
+	payProvider = payments.NewFake([]byte(cfg.Payments.WebhookSecret.Reveal()))
+	payProcessor = payments.NewProcessor(payProvider, st.Orders, st.Payments)
+
+	jobs := newWorkers()
+	jobs.cleanupExpired("carts", cartCleanupInterval, st.Carts.DeleteExpired)
+	jobs.cleanupExpired("stock reservations", reservationCleanupInterval, st.Inventory.DeleteExpired)
+	jobs.cleanupExpired("unpaid orders", unpaidOrderCleanupInterval, func(ctx context.Context, t time.Time) (int64, error) {
+		return payProcessor.CancelUnpaid(ctx, t.Add(-cfg.Payments.PendingOrderTTL))
+	})
+	jobs.cleanupExpired("idempotency keys", idempotencyCleanupInterval, st.Idempotency.DeleteExpired)
+	jobs.cleanupExpired("upload sessions", uploadSessionCleanupInterval, func(ctx context.Context, t time.Time) (int64, error) {
+		return st.Sessions.DeleteExpired(ctx, t.Add(-qrSessionRetention))
+	})
+
 	// Parse the schema
-	schema := graphql.MustParseSchema(schemaString, resolvers.NewResolver())
+	schema := graphql.MustParseSchema(schemaString, resolvers.NewResolver(st, payProcessor, imageStorage, broker))
 
 	// Create a new mux router
 	r := mux.NewRouter()
 
+	// The token endpoints authenticate with the credentials in their body and
+	// must keep working once the caller's access token has expired, so they
+	// sit outside the authenticator
+	r.HandleFunc("/auth/token", tokenHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/auth/refresh", refreshHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/auth/revoke", revokeHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/.well-known/jwks.json", jwksHandler).Methods("GET", "OPTIONS")
+
+	// Probes for the orchestrator
+	r.HandleFunc("/healthz", livenessHandler).Methods("GET", "HEAD")
+	r.HandleFunc("/readyz", readinessHandler).Methods("GET", "HEAD")
+
+	// Webhooks are authenticated by the provider's signature
+	r.HandleFunc("/payments/webhook", paymentWebhookHandler).Methods("POST")
+
+	// Images kept on local disk are public, like a bucket's would be
+	if local, ok := imageStorage.(*storage.Local); ok {
+		r.PathPrefix(local.Path()+"/").Handler(http.StripPrefix(local.Path(), local)).Methods("GET", "HEAD")
+	}
+
+	// Every other route sees the authenticated caller
+	api := r.NewRoute().Subrouter()
+	api.Use(authenticator.Middleware)
+
 	// CORS configuration
-	corsHeaders := handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization"})
-	corsOrigins := handlers.AllowedOrigins([]string{"http://localhost:5173", "https://yourdomain.com"}) // Update with your frontend URLs
+	corsHeaders := handlers.AllowedHeaders([]string{"X-Requested-With", "Content-Type", "Authorization", resolvers.IdempotencyKeyHeader})
+	allowedOrigins := cfg.Server.CORSOrigins
+	corsOrigins := handlers.AllowedOrigins(allowedOrigins)
 	corsMethods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	corsExposed := handlers.ExposedHeaders([]string{"X-Upload-Session", "X-Upload-Session-Expires"})
+
+	// Set up the GraphQL endpoint with CORS and per-request loaders
+	graphqlHandler := handlers.CORS(corsHeaders, corsOrigins, corsMethods)(loaders.Middleware(st, resolvers.IdempotencyKeys(&relay.Handler{Schema: schema})))
+	api.Handle("/graphql", graphqlHandler).Methods("POST", "OPTIONS")
 
-	// Set up the GraphQL endpoint with CORS
-	graphqlHandler := handlers.CORS(corsHeaders, corsOrigins, corsMethods)(&relay.Handler{Schema: schema})
-	r.Handle("/graphql", graphqlHandler).Methods("POST", "OPTIONS")
+	// Subscriptions, and any other operation, over WebSocket
+	wsHandler := &graphqlws.Handler{
+		Schema:      schema,
+		Init:        subscriptionInit,
+		CheckOrigin: allowOrigins(allowedOrigins),
+	}
+	api.Handle("/graphql", wsHandler).Methods("GET").MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return websocket.IsWebSocketUpgrade(r)
+	})
 
 	// Add a specific handler for OPTIONS requests to the GraphQL endpoint
-	r.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+resolvers.IdempotencyKeyHeader)
 		w.WriteHeader(http.StatusOK)
 	}).Methods("OPTIONS")
 
-	r.HandleFunc("/register", registerHandler).Methods("POST", "OPTIONS")
-	r.HandleFunc("/login", loginHandler).Methods("POST", "OPTIONS")
-	r.HandleFunc("/generate-qr", generateQRHandler).Methods("GET", "OPTIONS")
-	r.HandleFunc("/upload/{id}", uploadHandlerGET).Methods("GET", "OPTIONS")
-	r.HandleFunc("/upload/{id}", uploadHandlerPOST).Methods("POST", "OPTIONS")
-	r.HandleFunc("/user/images", getUserImagesHandler).Methods("GET", "OPTIONS")
+	api.HandleFunc("/register", registerHandler).Methods("POST", "OPTIONS")
+	api.HandleFunc("/login", loginHandler).Methods("POST", "OPTIONS")
+	api.HandleFunc("/logout", logoutHandler).Methods("POST", "OPTIONS")
+	api.HandleFunc("/generate-qr", generateQRHandler).Methods("GET", "OPTIONS")
+	api.HandleFunc("/upload/{id}", uploadHandlerGET).Methods("GET", "OPTIONS")
+	api.HandleFunc("/upload/{id}", uploadHandlerPOST).Methods("POST", "OPTIONS")
+	api.HandleFunc("/user/images", getUserImagesHandler).Methods("GET", "OPTIONS")
 
 	// Set up the REST endpoint
-	r.HandleFunc("/api/data", GetData).Methods("GET")
+	api.HandleFunc("/api/data", GetData).Methods("GET")
 
 	// Apply CORS middleware to the entire router
-	corsRouter := handlers.CORS(corsHeaders, corsOrigins, corsMethods)(r)
-
-	// Determine port for HTTP service
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-		log.Printf("Defaulting to port %s", port)
-	}
+	corsRouter := handlers.CORS(corsHeaders, corsOrigins, corsMethods, corsExposed)(r)
 
 	// Start the server with the CORS-enabled router
-	log.Printf("Server is running on http://localhost:%s", port)
-	log.Fatal(http.ListenAndServe(":"+port, corsRouter))
-}
-
-func registerHandler(w http.ResponseWriter, r *http.Request) {
-	var user User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler:           corsRouter,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
-
-	user.ID = uuid.New().String()
-	users[user.ID] = user
-
-	session, _ := store.Get(r, "session")
-	session.Values["userID"] = user.ID
-	session.Options.SameSite = http.SameSiteNoneMode
-	session.Options.Secure = true
-	session.Save(r, w)
-
-	json.NewEncoder(w).Encode(user)
+	srv.RegisterOnShutdown(wsHandler.Shutdown)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	served := make(chan error, 1)
+	go func() { served <- srv.ListenAndServe() }()
+	log.Printf("Server is running on http://localhost:%d", cfg.Server.Port)
+
+	select {
+	case err := <-served:
+		log.Fatalf("Server failed: %v", err)
+	case sig := <-signals:
+		log.Printf("Received %v, shutting down", sig)
+	}
+	shutdown(srv, cfg.Server, signals, jobs, pgBroker, db.DB)
 }
 
-func loginHandler(w http.ResponseWriter, r *http.Request) {
-	var credentials struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+func registerHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email     string `json:"email"`
+		Password  string `json:"password"`
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var user User
-	for _, u := range users {
-		if u.Username == credentials.Username {
-			user = u
-			break
-		}
-	}
-
-	if user.ID == "" {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	user, err := accounts.Register(r.Context(), auth.Registration{
+		Email:     body.Email,
+		Password:  body.Password,
+		FirstName: body.FirstName,
+		LastName:  body.LastName,
+	})
+	var pwErr *auth.PasswordError
+	switch {
+	case errors.Is(err, store.ErrEmailTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
 		return
-	}
-
-	session, _ := store.Get(r, "session")
-	session.Values["userID"] = user.ID
-	session.Options.SameSite = http.SameSiteNoneMode
-	session.Options.Secure = true
-	session.Save(r, w)
-
-	json.NewEncoder(w).Encode(user)
-}
-
-func generateQRHandler(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	userID, ok := session.Values["userID"].(string)
-	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	id := uuid.New().String()
-	expiresAt := time.Now().Add(5 * time.Minute)
-	uploadURL := fmt.Sprintf("https://%s/upload/%s", r.Host, id) // Use the actual host
-
-	qrCode := QRCode{
-		ID:        id,
-		URL:       uploadURL,
-		ExpiresAt: expiresAt,
-		UserID:    userID,
-	}
-
-	mu.Lock()
-	qrCodes[id] = qrCode
-	mu.Unlock()
-
-	qr, err := qrcode.Encode(uploadURL, qrcode.Medium, 256)
-	if err != nil {
-		http.Error(w, "Failed to generate QR code", http.StatusInternalServerError)
+	case errors.Is(err, auth.ErrInvalidEmail), errors.As(err, &pwErr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
-	}
-
-	w.Header().Set("Content-Type", "image/png")
-	w.Write(qr)
-}
-
-func uploadHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	mu.Lock()
-	qrCode, exists := qrCodes[id]
-	mu.Unlock()
-
-	if !exists || time.Now().After(qrCode.ExpiresAt) {
-		http.Error(w, "Invalid or expired QR code", http.StatusBadRequest)
+	case err != nil:
+		log.Printf("register: %v", err)
+		http.Error(w, "Registration failed", http.StatusInternalServerError)
 		return
 	}
 
-	// Handle file upload here
-	// For simplicity, we'll just create a dummy UploadedImage
-	imageID := uuid.New().String()
-	image := UploadedImage{
-		ID:     imageID,
-		UserID: qrCode.UserID,
-		URL:    fmt.Sprintf("http://example.com/images/%s", imageID),
-	}
-
-	mu.Lock()
-	uploadedImages[qrCode.UserID] = append(uploadedImages[qrCode.UserID], image)
-	delete(qrCodes, id)
-	mu.Unlock()
-
-	response := map[string]string{"message": "File uploaded successfully", "imageUrl": image.URL}
-	json.NewEncoder(w).Encode(response)
-}
-func uploadHandlerGET(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	mu.Lock()
-	_, exists := qrCodes[id]
-	mu.Unlock()
-
-	if !exists {
-		http.Error(w, "Invalid or expired QR code", http.StatusBadRequest)
-		return
-	}
-
-	// Serve a simple HTML page for file upload
-	html := `
-	<!DOCTYPE html>
-	<html>
-	<head>
-		<title>Upload Image</title>
-	</head>
-	<body>
-		<h1>Upload Image</h1>
-		<form action="/upload/{{.ID}}" method="post" enctype="multipart/form-data">
-			<input type="file" name="file" accept="image/*">
-			<button type="submit">Upload</button>
-			<input type="file" name="file" accept="image/*" capture="camera">
-			<button type="submit">Take Photo</button>
-		</form>
-	</body>
-	</html>
-	`
-
-	tmpl, err := template.New("upload").Parse(html)
-	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if err := sessionStore.Start(w, r, user.ID); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/html")
-	tmpl.Execute(w, struct{ ID string }{ID: id})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
 }
 
-func uploadHandlerPOST(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-
-	id := vars["id"]
-
-	mu.Lock()
-	qrCode, exists := qrCodes[id]
-	mu.Unlock()
-
-	if !exists || time.Now().After(qrCode.ExpiresAt) {
-		http.Error(w, "Invalid or expired QR code", http.StatusBadRequest)
-		return
-	}
-
-	// Parse the multipart form data
-	err := r.ParseMultipartForm(10 << 20) // 10 MB max
-	if err != nil {
-		http.Error(w, "Unable to parse form", http.StatusBadRequest)
-		return
-	}
-
-	file, fileHeader, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "Error retrieving the file", http.StatusBadRequest)
-		return
-	}
-	defer file.Close()
-
-	uploadURL := "https://upload.uploadcare.com/base/"
-	apiKey := "3cb90b7ae33a12d4d266"
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	err = writer.WriteField("UPLOADCARE_PUB_KEY", apiKey)
-	if err != nil {
-		http.Error(w, "Error creating form", http.StatusInternalServerError)
-		return
-	}
-	filePart, err := writer.CreateFormFile("file", fileHeader.Filename)
-	if err != nil {
-		http.Error(w, "Error creating form file part", http.StatusInternalServerError)
-		return
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials struct {
+		Email     string `json:"email"`
+		Password  string `json:"password"`
+		CartToken string `json:"cartToken"`
 	}
-
-	_, err = io.Copy(filePart, file)
-	if err != nil {
-		http.Error(w, "Error copying file", http.StatusInternalServerError)
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	writer.Close()
-	req, err := http.NewRequest("POST", uploadURL, body)
-	if err != nil {
-		http.Error(w, "Error creating upload request", http.StatusInternalServerError)
+	user, err := accounts.Login(r.Context(), credentials.Email, credentials.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
 	if err != nil {
-		http.Error(w, "Error uploading to Uploadcare", http.StatusInternalServerError)
+		log.Printf("login: %v", err)
+		http.Error(w, "Login failed", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		http.Error(w, "Error from Uploadcare", resp.StatusCode)
-		return
+	// Carry the anonymous cart over to the user's
+	if credentials.CartToken != "" {
+		if _, err := carts.Merge(r.Context(), credentials.CartToken, user.ID, time.Now().Add(resolvers.CartTTL)); err != nil {
+			log.Printf("login: merging cart: %v", err)
+		}
 	}
 
-	var uploadResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&uploadResponse); err != nil {
-		http.Error(w, "Error decoding upload response", http.StatusInternalServerError)
+	if err := sessionStore.Start(w, r, user.ID); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
 		return
 	}
 
-	imageURL := fmt.Sprintf("https://ucarecdn.com/%s/", uploadResponse["file"])
-
-	// Create the UploadedImage entry
-	imageID := uuid.New().String()
-	image := UploadedImage{
-		ID:     imageID,
-		UserID: qrCode.UserID,
-		URL:    imageURL,
-	}
-
-	// // Here you would typically save the file to a storage service
-	// // For this example, we'll just create a dummy UploadedImage
-	// imageID := uuid.New().String()
-	// image := UploadedImage{
-	// 	ID:     imageID,
-	// 	UserID: qrCode.UserID,
-	// 	URL:    fmt.Sprintf("http://example.com/images/%s", imageID),
-	// }
-
-	mu.Lock()
-	uploadedImages[qrCode.UserID] = append(uploadedImages[qrCode.UserID], image)
-	delete(qrCodes, id)
-	mu.Unlock()
-
-	response := map[string]string{"message": "File uploaded successfully", "imageUrl": image.URL}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(user)
 }
 
-func getUserImagesHandler(w http.ResponseWriter, r *http.Request) {
-	session, _ := store.Get(r, "session")
-	userID, ok := session.Values["userID"].(string)
-	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if err := sessionStore.End(w, r); err != nil {
+		http.Error(w, "Failed to end session", http.StatusInternalServerError)
 		return
 	}
-
-	mu.Lock()
-	images := uploadedImages[userID]
-	mu.Unlock()
-
-	json.NewEncoder(w).Encode(images)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func GetData(w http.ResponseWriter, r *http.Request) {
@@ -1,5 +1,7 @@
 package models
 
+import "github.com/graph-gophers/graphql-go"
+
 type Category struct {
 	ID             int32      `json:"id"`
 	Name           string     `json:"name"`
@@ -18,17 +20,102 @@ type Product struct {
 	Images        []*ProductImage     `json:"images,omitempty"`
 	Attributes    []*ProductAttribute `json:"attributes,omitempty"`
 	Reviews       []*Review           `json:"reviews,omitempty"`
+	CreatedAt     string              `json:"createdAt"`
+}
+
+// SearchHit is a product matched by full-text search.
+type SearchHit struct {
+	Product Product
+	Score   float64
+	// Snippet is an HTML-escaped excerpt with the matched terms wrapped in
+	// <b></b>, so it can be rendered as HTML.
+	Snippet string
+	// Fuzzy is set when the hit came from the typo-tolerant fallback.
+	Fuzzy bool
 }
 
+// ProductImage is an image of a product, or of one of its variants when
+// VariantID is set.
+// ProductImage is a photo of a product or variant. Photos uploaded to the
+// shop have an ImageID and renditions in several sizes; ImageUrl is then
+// the URL of the largest JPEG rendition.
 type ProductImage struct {
 	ID        int32  `json:"id"`
 	ProductID int32  `json:"-"`
+	VariantID *int32 `json:"-"`
+	ImageID   *int32 `json:"-"`
 	ImageUrl  string `json:"imageUrl"`
 	IsPrimary bool   `json:"isPrimary"`
 }
 
 type ProductAttribute struct {
-	ID    int32  `json:"id"`
+	ID        int32  `json:"id"`
+	ProductID int32  `json:"-"`
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+}
+
+// AttributeDefinition describes an attribute that products in a category
+// and its subcategories may have. AllowedValues is only set for ENUM
+// attributes.
+type AttributeDefinition struct {
+	ID            int32    `json:"id"`
+	CategoryID    int32    `json:"-"`
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	AllowedValues []string `json:"allowedValues"`
+	Required      bool     `json:"required"`
+}
+
+// CustomizationOption is a customisation a seller offers on a product,
+// such as an engraving or an uploaded artwork. Which of the rule fields
+// apply depends on Kind; zero values are unset.
+type CustomizationOption struct {
+	ID            int32    `json:"id"`
+	ProductID     int32    `json:"-"`
+	Name          string   `json:"name"`
+	Kind          string   `json:"kind"`
+	Required      bool     `json:"required"`
+	MaxLength     int32    `json:"maxLength"`
+	AllowedValues []string `json:"allowedValues"`
+	MinWidth      float64  `json:"minWidth"`
+	MaxWidth      float64  `json:"maxWidth"`
+	MinHeight     float64  `json:"minHeight"`
+	MaxHeight     float64  `json:"maxHeight"`
+	Price         float64  `json:"price"`
+	UnitPrice     float64  `json:"unitPrice"`
+	CreatedAt     string   `json:"createdAt"`
+}
+
+// Customization is what a buyer chose for a CustomizationOption, as kept on
+// cart lines and order items. Name and Kind are copied from the option so
+// orders still show them once it changes; Price is what the choice added to
+// the unit price.
+type Customization struct {
+	OptionID int32   `json:"optionId"`
+	Name     string  `json:"name"`
+	Kind     string  `json:"kind"`
+	Value    string  `json:"value,omitempty"`
+	Width    float64 `json:"width,omitempty"`
+	Height   float64 `json:"height,omitempty"`
+	Price    float64 `json:"price"`
+}
+
+// ProductVariant is a purchasable version of a product, such as one size
+// and colour, with its own SKU, price and stock. Options hold the attribute
+// values that set it apart.
+type ProductVariant struct {
+	ID            int32           `json:"id"`
+	ProductID     int32           `json:"-"`
+	SKU           string          `json:"sku"`
+	Price         float64         `json:"price"`
+	StockQuantity int32           `json:"stockQuantity"`
+	Options       []VariantOption `json:"options"`
+	Images        []*ProductImage `json:"images,omitempty"`
+	CreatedAt     string          `json:"createdAt"`
+}
+
+type VariantOption struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
 }
@@ -38,24 +125,137 @@ type User struct {
 	Email     string `json:"email"`
 	FirstName string `json:"firstName"`
 	LastName  string `json:"lastName"`
+	Role      string `json:"role"`
 }
 
 type Order struct {
-	ID          int32        `json:"id"`
-	UserID      int32        `json:"-"`
-	User        *User        `json:"user"`
-	TotalAmount float64      `json:"totalAmount"`
-	Status      string       `json:"status"`
-	Items       []*OrderItem `json:"items"`
-	CreatedAt   string       `json:"createdAt"`
+	ID            int32        `json:"id"`
+	UserID        int32        `json:"-"`
+	User          *User        `json:"user"`
+	Subtotal      float64      `json:"subtotal"`
+	DiscountTotal float64      `json:"discountTotal"`
+	TaxTotal      float64      `json:"taxTotal"`
+	ShippingTotal float64      `json:"shippingTotal"`
+	TotalAmount   float64      `json:"totalAmount"`
+	Status        string       `json:"status"`
+	Items         []*OrderItem `json:"items"`
+	CreatedAt     string       `json:"createdAt"`
+}
+
+type OrderStatusChange struct {
+	ID         int32   `json:"id"`
+	OrderID    int32   `json:"-"`
+	FromStatus *string `json:"fromStatus"`
+	ToStatus   string  `json:"toStatus"`
+	ActorID    *int32  `json:"-"`
+	Note       *string `json:"note"`
+	CreatedAt  string  `json:"createdAt"`
 }
 
 type OrderItem struct {
-	ID          int32    `json:"id"`
-	ProductID   int32    `json:"-"`
-	Product     *Product `json:"product"`
-	Quantity    int32    `json:"quantity"`
-	PriceAtTime float64  `json:"priceAtTime"`
+	ID        int32    `json:"id"`
+	OrderID   int32    `json:"-"`
+	ProductID int32    `json:"-"`
+	Product   *Product `json:"product"`
+	VariantID *int32   `json:"-"`
+	SKU       *string  `json:"sku"`
+	// Customizations are included in PriceAtTime.
+	Customizations []Customization `json:"customizations"`
+	Quantity       int32           `json:"quantity"`
+	PriceAtTime    float64         `json:"priceAtTime"`
+	Discount       float64         `json:"discount"`
+	Tax            float64         `json:"tax"`
+	LineTotal      float64         `json:"lineTotal"`
+}
+
+// Payment is a payment intent: an attempt to collect an order's total
+// through a payment provider. Reference is the provider's ID for it, set
+// once the provider has seen it.
+type Payment struct {
+	ID             int32   `json:"id"`
+	OrderID        int32   `json:"-"`
+	Provider       string  `json:"provider"`
+	Reference      *string `json:"reference"`
+	Amount         float64 `json:"amount"`
+	AmountRefunded float64 `json:"amountRefunded"`
+	Status         string  `json:"status"`
+	FailureReason  *string `json:"failureReason"`
+	CreatedAt      string  `json:"createdAt"`
+	UpdatedAt      string  `json:"updatedAt"`
+}
+
+// Cart is a shopping cart. Anonymous carts are identified by Token; once a
+// user owns the cart, Token is empty and UserID set.
+type Cart struct {
+	ID        int32       `json:"id"`
+	Token     string      `json:"token,omitempty"`
+	UserID    int32       `json:"-"`
+	Items     []*CartItem `json:"items"`
+	UpdatedAt string      `json:"updatedAt"`
+	ExpiresAt string      `json:"expiresAt"`
+}
+
+// CartItem is a line of a cart. PriceAtAdd is the product price when the
+// line was first added, kept to tell the shopper about price changes.
+type CartItem struct {
+	ID        int32    `json:"id"`
+	CartID    int32    `json:"-"`
+	ProductID int32    `json:"-"`
+	Product   *Product `json:"product"`
+	VariantID *int32   `json:"-"`
+	// A cart holds a line per product, variant and set of customizations.
+	Customizations []Customization `json:"customizations"`
+	Quantity       int32           `json:"quantity"`
+	PriceAtAdd     float64         `json:"priceAtAdd"`
+	AddedAt        string          `json:"addedAt"`
+}
+
+// Image is an uploaded photo. Filename, MimeType, Size and Checksum, the
+// hex SHA-256, describe the file as uploaded; Width and Height are those of
+// the upright image. What is kept in object storage are its renditions;
+// Key is that of the largest JPEG.
+type Image struct {
+	ID       int32  `json:"id"`
+	OwnerID  int32  `json:"ownerId"`
+	Key      string `json:"-"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+	// SessionID is the QR upload session the image was received through.
+	SessionID *string `json:"-"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// UploadSession lets a phone upload images for a user by scanning a QR
+// code. ID is the secret in the QR code's URL. Uploads go to the user's
+// images and, when ProductID is set, also become photos of that product.
+type UploadSession struct {
+	ID          string `json:"id"`
+	UserID      int32  `json:"-"`
+	ProductID   *int32 `json:"productId"`
+	MaxUploads  int32  `json:"maxUploads"`
+	UploadCount int32  `json:"uploadCount"`
+	// Status is ACTIVE while the session accepts uploads, then COMPLETED,
+	// EXPIRED or REVOKED.
+	Status    string  `json:"status"`
+	ExpiresAt string  `json:"expiresAt"`
+	RevokedAt *string `json:"revokedAt"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// ImageRendition is an image scaled to one of the sizes in package imaging
+// and encoded in one of its formats.
+type ImageRendition struct {
+	ImageID int32  `json:"-"`
+	Size    string `json:"size"`
+	Format  string `json:"format"`
+	Key     string `json:"-"`
+	Width   int32  `json:"width"`
+	Height  int32  `json:"height"`
+	Bytes   int64  `json:"bytes"`
 }
 
 type Review struct {
@@ -69,23 +269,6 @@ type Review struct {
 	CreatedAt string   `json:"createdAt"`
 }
 
-type Query struct {
-	Product    func(id int32) (*Product, error)
-	Products   func(category *int32, search *string) ([]*Product, error)
-	Categories func() ([]*Category, error)
-	Order      func(id int32) (*Order, error)
-	UserOrders func(userId int32) ([]*Order, error)
-}
-
-type Mutation struct {
-	CreateProduct  func(input ProductInput) (*Product, error)
-	UpdateProduct  func(id int32, input ProductInput) (*Product, error)
-	DeleteProduct  func(id int32) (bool, error)
-	CreateOrder    func(input OrderInput) (*Order, error)
-	CreateReview   func(input ReviewInput) (*Review, error)
-	CreateCategory func(name string, parentID *int32) (*Category, error)
-}
-
 type ProductInput struct {
 	Name          string              `json:"name"`
 	Description   *string             `json:"description,omitempty"`
@@ -93,17 +276,71 @@ type ProductInput struct {
 	StockQuantity int32               `json:"stockQuantity"`
 	CategoryID    int32               `json:"categoryId"`
 	Images        []ProductImageInput `json:"images"`
+	// Attributes, when set, replace the product's attributes.
+	Attributes *[]ProductAttributeInput `json:"attributes"`
+}
+
+type ProductAttributeInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type AttributeDefinitionInput struct {
+	Name          string    `json:"name"`
+	Type          string    `json:"type"`
+	AllowedValues *[]string `json:"allowedValues"`
+	Required      *bool     `json:"required"`
+}
+
+type CustomizationOptionInput struct {
+	Name          string    `json:"name"`
+	Kind          string    `json:"kind"`
+	Required      *bool     `json:"required"`
+	MaxLength     *int32    `json:"maxLength"`
+	AllowedValues *[]string `json:"allowedValues"`
+	MinWidth      *float64  `json:"minWidth"`
+	MaxWidth      *float64  `json:"maxWidth"`
+	MinHeight     *float64  `json:"minHeight"`
+	MaxHeight     *float64  `json:"maxHeight"`
+	Price         *float64  `json:"price"`
+	UnitPrice     *float64  `json:"unitPrice"`
+}
+
+// CustomizationInput is a buyer's choice for an option: Width and Height
+// for DIMENSIONS options, Value for the others.
+type CustomizationInput struct {
+	OptionID int32    `json:"optionId"`
+	Value    *string  `json:"value"`
+	Width    *float64 `json:"width"`
+	Height   *float64 `json:"height"`
+}
+
+type ProductVariantInput struct {
+	SKU           string               `json:"sku"`
+	Price         float64              `json:"price"`
+	StockQuantity int32                `json:"stockQuantity"`
+	Options       []VariantOptionInput `json:"options"`
+	Images        []ProductImageInput  `json:"images"`
+}
+
+type VariantOptionInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
+// ProductImageInput adds a photo by URL or, with ImageID, one the seller
+// uploaded. The resolvers fill in ImageUrl for uploaded photos, so it is
+// always set when the input reaches a store.
 type ProductImageInput struct {
-	ImageUrl  string `json:"imageUrl"`
-	IsPrimary bool   `json:"isPrimary"`
+	ImageUrl  *string `json:"imageUrl"`
+	ImageID   *int32  `json:"imageId"`
+	IsPrimary bool    `json:"isPrimary"`
 }
 
 type OrderInput struct {
-	UserID      int32             `json:"userId"`
+	UserID      *graphql.ID       `json:"userId"`
 	Items       []*OrderItemInput `json:"items"`
-	TotalAmount float64           `json:"totalAmount"`
+	TotalAmount *float64          `json:"totalAmount"`
 }
 
 type CategoryInput struct {
@@ -111,14 +348,45 @@ type CategoryInput struct {
 	ParentID *string `json:"parentId"`
 }
 
+type ProductFilterInput struct {
+	CategoryID           *graphql.ID             `json:"categoryId"`
+	IncludeSubcategories *bool                   `json:"includeSubcategories"`
+	Search               *string                 `json:"search"`
+	MinPrice             *float64                `json:"minPrice"`
+	MaxPrice             *float64                `json:"maxPrice"`
+	InStock              *bool                   `json:"inStock"`
+	Attributes           *[]AttributeFilterInput `json:"attributes"`
+	MinRating            *float64                `json:"minRating"`
+}
+
+type AttributeFilterInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
 type OrderItemInput struct {
-	ProductID int32 `json:"productId"`
-	Quantity  int32 `json:"quantity"`
+	ProductID      int32                 `json:"productId"`
+	VariantID      *int32                `json:"variantId"`
+	Customizations *[]CustomizationInput `json:"customizations"`
+	Quantity       int32                 `json:"quantity"`
 }
 
 type ReviewInput struct {
-	ProductID int32   `json:"productId"`
-	UserID    int32   `json:"userId"`
-	Rating    int32   `json:"rating"`
-	Comment   *string `json:"comment"`
+	ProductID int32       `json:"productId"`
+	UserID    *graphql.ID `json:"userId"`
+	Rating    int32       `json:"rating"`
+	Comment   *string     `json:"comment"`
+}
+
+type CheckoutInput struct {
+	CartToken     *string
+	PaymentMethod string
+	TotalAmount   *float64
+}
+
+type RegisterInput struct {
+	Email     string
+	Password  string
+	FirstName *string
+	LastName  *string
 }
@@ -0,0 +1,83 @@
+// Package orderstatus defines the order lifecycle: the statuses an order
+// moves through and which moves between them are allowed.
+//
+//	PENDING → PAID → FULFILLING → SHIPPED → DELIVERED
+//
+// A pending order can be CANCELLED; a paid one can be REFUNDED at any later
+// point. Both are final.
+package orderstatus
+
+import "fmt"
+
+// Status is the state of an order, stored in orders.status.
+type Status string
+
+const (
+	Pending    Status = "PENDING"
+	Paid       Status = "PAID"
+	Fulfilling Status = "FULFILLING"
+	Shipped    Status = "SHIPPED"
+	Delivered  Status = "DELIVERED"
+	Cancelled  Status = "CANCELLED"
+	Refunded   Status = "REFUNDED"
+)
+
+// transitions lists the statuses reachable from each status in one step.
+var transitions = map[Status][]Status{
+	Pending:    {Paid, Cancelled},
+	Paid:       {Fulfilling, Refunded},
+	Fulfilling: {Shipped, Refunded},
+	Shipped:    {Delivered, Refunded},
+	Delivered:  {Refunded},
+	Cancelled:  nil,
+	Refunded:   nil,
+}
+
+// Valid reports whether s is a known status.
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// Final reports whether no transitions lead out of s.
+func (s Status) Final() bool {
+	return len(transitions[s]) == 0
+}
+
+// Next returns the statuses an order in status s can move to.
+func (s Status) Next() []Status {
+	return append([]Status(nil), transitions[s]...)
+}
+
+// TransitionError is returned for a move the lifecycle doesn't allow.
+type TransitionError struct {
+	From, To Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("order is %s and can't become %s", e.From, e.To)
+}
+
+// Check returns a *TransitionError unless an order can move from from to
+// to in one step.
+func Check(from, to Status) error {
+	for _, next := range transitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to}
+}
+
+// ReturnsStock reports whether moving from from to to puts the order's
+// items back in stock: they were taken out when the order was placed and
+// stay out once they have left the warehouse.
+func ReturnsStock(from, to Status) bool {
+	switch to {
+	case Cancelled:
+		return true
+	case Refunded:
+		return from == Paid || from == Fulfilling
+	}
+	return false
+}
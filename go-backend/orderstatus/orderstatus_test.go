@@ -0,0 +1,113 @@
+package orderstatus
+
+import (
+	"errors"
+	"testing"
+)
+
+var all = []Status{Pending, Paid, Fulfilling, Shipped, Delivered, Cancelled, Refunded}
+
+func TestCheck(t *testing.T) {
+	allowed := map[[2]Status]bool{
+		{Pending, Paid}:        true,
+		{Pending, Cancelled}:   true,
+		{Paid, Fulfilling}:     true,
+		{Paid, Refunded}:       true,
+		{Fulfilling, Shipped}:  true,
+		{Fulfilling, Refunded}: true,
+		{Shipped, Delivered}:   true,
+		{Shipped, Refunded}:    true,
+		{Delivered, Refunded}:  true,
+	}
+	unknown := []Status{"", "LOST", "pending"}
+
+	check := func(from, to Status, want bool) {
+		t.Helper()
+		err := Check(from, to)
+		if want {
+			if err != nil {
+				t.Errorf("Check(%s, %s) = %v, want nil", from, to, err)
+			}
+			return
+		}
+		var te *TransitionError
+		if !errors.As(err, &te) || te.From != from || te.To != to {
+			t.Errorf("Check(%q, %q) = %v, want a *TransitionError", from, to, err)
+		}
+	}
+	for _, from := range all {
+		for _, to := range all {
+			check(from, to, allowed[[2]Status{from, to}])
+		}
+		for _, s := range unknown {
+			check(from, s, false)
+			check(s, from, false)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		s     Status
+		valid bool
+		final bool
+		next  []Status
+	}{
+		{Pending, true, false, []Status{Paid, Cancelled}},
+		{Paid, true, false, []Status{Fulfilling, Refunded}},
+		{Fulfilling, true, false, []Status{Shipped, Refunded}},
+		{Shipped, true, false, []Status{Delivered, Refunded}},
+		{Delivered, true, false, []Status{Refunded}},
+		{Cancelled, true, true, nil},
+		{Refunded, true, true, nil},
+		{Status("LOST"), false, true, nil},
+	}
+	for _, tt := range tests {
+		if got := tt.s.Valid(); got != tt.valid {
+			t.Errorf("%s.Valid() = %v, want %v", tt.s, got, tt.valid)
+		}
+		if got := tt.s.Final(); got != tt.final {
+			t.Errorf("%s.Final() = %v, want %v", tt.s, got, tt.final)
+		}
+		next := tt.s.Next()
+		if len(next) != len(tt.next) {
+			t.Errorf("%s.Next() = %v, want %v", tt.s, next, tt.next)
+			continue
+		}
+		for i := range next {
+			if next[i] != tt.next[i] {
+				t.Errorf("%s.Next() = %v, want %v", tt.s, next, tt.next)
+				break
+			}
+		}
+	}
+}
+
+// Next hands out a copy, so callers can't change the lifecycle.
+func TestNextCopies(t *testing.T) {
+	Pending.Next()[0] = Delivered
+	if err := Check(Pending, Paid); err != nil {
+		t.Errorf("changing Next() changed the lifecycle: %v", err)
+	}
+}
+
+func TestReturnsStock(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		want     bool
+	}{
+		{Pending, Cancelled, true},
+		{Paid, Refunded, true},
+		{Fulfilling, Refunded, true},
+		{Shipped, Refunded, false},
+		{Delivered, Refunded, false},
+		{Pending, Paid, false},
+		{Paid, Fulfilling, false},
+		{Shipped, Delivered, false},
+	}
+	for _, tt := range tests {
+		if got := ReturnsStock(tt.from, tt.to); got != tt.want {
+			t.Errorf("ReturnsStock(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
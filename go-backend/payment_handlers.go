@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"go-backend/payments"
+	"go-backend/store"
+)
+
+// paymentWebhookHandler receives payment provider notifications, such as
+// captures that complete after checkout returned. The provider retries
+// deliveries that don't succeed, so only transient failures get a 5xx.
+func paymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	event, err := payProvider.VerifyWebhook(r)
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = payProcessor.HandleEvent(r.Context(), event)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "unknown payment", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("payment webhook %s for %s: %v", event.Type, event.Reference, err)
+		http.Error(w, "failed to process event", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,159 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"go-backend/pricing"
+
+	"github.com/google/uuid"
+)
+
+// Payment method tokens understood by the fake provider. Any other token is
+// accepted like FakeCardOK.
+const (
+	FakeCardOK                = "tok_visa"
+	FakeCardDeclined          = "tok_declined"
+	FakeCardInsufficientFunds = "tok_insufficient_funds"
+)
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of a fake webhook's body.
+const FakeSignatureHeader = "Fake-Signature"
+
+// Fake is an in-process PaymentProvider for development and tests. It moves
+// no money and its outcome depends only on the payment method token. Its
+// references are random, so they stay unique across restarts like a real
+// provider's, though the holds behind them are forgotten.
+type Fake struct {
+	secret []byte
+
+	mu    sync.Mutex
+	holds map[string]*fakeHold
+}
+
+type fakeHold struct {
+	authorized, captured, refunded pricing.Cents
+	voided                         bool
+}
+
+// NewFake returns a fake provider whose webhooks are signed with secret.
+func NewFake(secret []byte) *Fake {
+	return &Fake{secret: secret, holds: make(map[string]*fakeHold)}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Authorize(ctx context.Context, req AuthorizeRequest) (*Authorization, error) {
+	switch req.PaymentMethod {
+	case FakeCardDeclined:
+		return nil, &DeclinedError{Reason: "card declined"}
+	case FakeCardInsufficientFunds:
+		return nil, &DeclinedError{Reason: "insufficient funds"}
+	}
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive, got %s", req.Amount)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ref := "fake_auth_" + uuid.New().String()
+	f.holds[ref] = &fakeHold{authorized: req.Amount}
+	return &Authorization{Reference: ref, Amount: req.Amount}, nil
+}
+
+// hold returns the hold for reference. Callers must hold mu.
+func (f *Fake) hold(reference string) (*fakeHold, error) {
+	h, ok := f.holds[reference]
+	if !ok {
+		return nil, ErrUnknownReference
+	}
+	return h, nil
+}
+
+func (f *Fake) Capture(ctx context.Context, reference string, amount pricing.Cents) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, err := f.hold(reference)
+	if err != nil {
+		return err
+	}
+	switch {
+	case h.voided:
+		return fmt.Errorf("authorization %s was voided", reference)
+	case h.captured > 0:
+		return fmt.Errorf("authorization %s was already captured", reference)
+	case amount <= 0 || amount > h.authorized:
+		return fmt.Errorf("can't capture %s of %s authorized", amount, h.authorized)
+	}
+	h.captured = amount
+	return nil
+}
+
+func (f *Fake) Refund(ctx context.Context, reference string, amount pricing.Cents) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, err := f.hold(reference)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || h.refunded+amount > h.captured {
+		return fmt.Errorf("can't refund %s of %s captured, %s already refunded", amount, h.captured, h.refunded)
+	}
+	h.refunded += amount
+	return nil
+}
+
+func (f *Fake) Void(ctx context.Context, reference string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, err := f.hold(reference)
+	if err != nil {
+		return err
+	}
+	if h.captured > 0 {
+		return fmt.Errorf("authorization %s was already captured", reference)
+	}
+	h.voided = true
+	return nil
+}
+
+func (f *Fake) VerifyWebhook(r *http.Request) (*Event, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	got, err := hex.DecodeString(r.Header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(got, f.sign(body)) {
+		return nil, ErrInvalidSignature
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("decoding webhook: %w", err)
+	}
+	return &event, nil
+}
+
+// SignWebhook returns the FakeSignatureHeader value for body, for sending
+// fake webhooks in development and tests.
+func (f *Fake) SignWebhook(body []byte) string {
+	return hex.EncodeToString(f.sign(body))
+}
+
+func (f *Fake) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
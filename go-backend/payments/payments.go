@@ -0,0 +1,83 @@
+// Package payments abstracts the payment gateway. Orders are paid in two
+// steps: an authorization holds the amount on the buyer's payment method and
+// a capture collects it. Captured amounts can be refunded; an authorization
+// that won't be captured is voided to release the hold.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go-backend/pricing"
+)
+
+// PaymentProvider is a payment gateway. References are the provider's IDs
+// for authorizations; every later call names one.
+type PaymentProvider interface {
+	// Name identifies the provider in stored payment records.
+	Name() string
+	// Authorize holds req.Amount on req.PaymentMethod. It returns a
+	// *DeclinedError if the payment method was refused.
+	Authorize(ctx context.Context, req AuthorizeRequest) (*Authorization, error)
+	// Capture collects an authorized amount, at most the amount authorized.
+	Capture(ctx context.Context, reference string, amount pricing.Cents) error
+	// Refund returns part or all of a captured amount.
+	Refund(ctx context.Context, reference string, amount pricing.Cents) error
+	// Void cancels an authorization that hasn't been captured.
+	Void(ctx context.Context, reference string) error
+	// VerifyWebhook checks that a webhook request was sent by the provider
+	// and decodes it. It returns ErrInvalidSignature if not.
+	VerifyWebhook(r *http.Request) (*Event, error)
+}
+
+// AuthorizeRequest asks for an amount to be held for an order.
+type AuthorizeRequest struct {
+	OrderID int32
+	Amount  pricing.Cents
+	// PaymentMethod is the token the client obtained from the provider for
+	// the buyer's card or wallet.
+	PaymentMethod string
+}
+
+// Authorization is a successful hold.
+type Authorization struct {
+	Reference string
+	Amount    pricing.Cents
+}
+
+// EventType names what a webhook reports.
+type EventType string
+
+const (
+	EventCaptured EventType = "payment.captured"
+	EventFailed   EventType = "payment.failed"
+	EventRefunded EventType = "payment.refunded"
+	EventVoided   EventType = "payment.voided"
+)
+
+// Event is a verified webhook notification about an authorization.
+type Event struct {
+	Type      EventType `json:"type"`
+	Reference string    `json:"reference"`
+	// Amount is the amount captured, or for refunds the total refunded so
+	// far, in cents.
+	Amount pricing.Cents `json:"amount"`
+}
+
+// ErrInvalidSignature is returned for webhook requests that fail
+// verification.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// ErrUnknownReference is returned for a reference the provider didn't
+// issue.
+var ErrUnknownReference = errors.New("unknown payment reference")
+
+// DeclinedError is returned when the provider refuses a payment method.
+type DeclinedError struct {
+	Reason string
+}
+
+func (e *DeclinedError) Error() string {
+	return "payment declined: " + e.Reason
+}
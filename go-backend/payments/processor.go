@@ -0,0 +1,357 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go-backend/models"
+	"go-backend/orderstatus"
+	"go-backend/pricing"
+	"go-backend/store"
+)
+
+// Payment statuses, stored in payment_intents.status.
+const (
+	StatusPending    = "PENDING"
+	StatusAuthorized = "AUTHORIZED"
+	StatusCaptured   = "CAPTURED"
+	StatusFailed     = "FAILED"
+	StatusVoided     = "VOIDED"
+	StatusRefunded   = "REFUNDED"
+)
+
+// ErrNothingToRefund is returned when refunding an order without a captured
+// payment, or one that has been refunded in full already.
+var ErrNothingToRefund = errors.New("order has no captured payment left to refund")
+
+// RefundAmountError is returned for a refund larger than what is left of
+// the payment.
+type RefundAmountError struct {
+	Requested, Refundable pricing.Cents
+}
+
+func (e *RefundAmountError) Error() string {
+	return fmt.Sprintf("can't refund %s, only %s is refundable", e.Requested, e.Refundable)
+}
+
+// Processor runs the payment side of the order lifecycle: it collects
+// order totals through a PaymentProvider, records every attempt as a
+// payment intent, and moves orders on accordingly.
+type Processor struct {
+	provider PaymentProvider
+	orders   store.OrderStore
+	payments store.PaymentStore
+}
+
+func NewProcessor(provider PaymentProvider, orders store.OrderStore, payments store.PaymentStore) *Processor {
+	return &Processor{provider: provider, orders: orders, payments: payments}
+}
+
+// Pay collects the total of a pending order with paymentMethod and marks
+// the order PAID. If the payment fails at any step the order is cancelled,
+// returning its items to stock, and the error is returned; it is a
+// *DeclinedError when the provider refused the payment method. Money the
+// provider captured before a later step failed is refunded.
+func (p *Processor) Pay(ctx context.Context, o *models.Order, paymentMethod string) (*models.Order, error) {
+	amount := pricing.FromFloat(o.TotalAmount)
+	payment, err := p.payments.Create(ctx, o.ID, p.provider.Name(), amount)
+	if err != nil {
+		return nil, p.fail(ctx, o, 0, err)
+	}
+
+	auth, err := p.provider.Authorize(ctx, AuthorizeRequest{OrderID: o.ID, Amount: amount, PaymentMethod: paymentMethod})
+	if err != nil {
+		return nil, p.fail(ctx, o, payment.ID, err)
+	}
+	if _, err := p.payments.Update(ctx, payment.ID, store.PaymentUpdate{Status: StatusAuthorized, Reference: auth.Reference}); err != nil {
+		p.void(ctx, auth.Reference)
+		return nil, p.fail(ctx, o, payment.ID, err)
+	}
+	if err := p.provider.Capture(ctx, auth.Reference, amount); err != nil {
+		p.void(ctx, auth.Reference)
+		return nil, p.fail(ctx, o, payment.ID, err)
+	}
+	if _, err := p.payments.Update(ctx, payment.ID, store.PaymentUpdate{Status: StatusCaptured}); err != nil {
+		p.giveBack(ctx, payment.ID, auth.Reference, amount)
+		return nil, p.fail(ctx, o, 0, err)
+	}
+
+	paid, err := p.orders.SetStatus(ctx, o.ID, store.StatusChange{
+		From: string(orderstatus.Pending),
+		To:   string(orderstatus.Paid),
+		Note: fmt.Sprintf("payment %d captured", payment.ID),
+	})
+	if err != nil {
+		// The order moved on while it was being paid for, e.g. it was
+		// cancelled, or couldn't be marked paid; don't keep the money.
+		p.giveBack(ctx, payment.ID, auth.Reference, amount)
+		return nil, p.fail(ctx, o, 0, err)
+	}
+	return paid, nil
+}
+
+// fail records that payment paymentID failed, unless paymentID is zero,
+// and cancels its order, returning cause. Failing to record the failure or
+// to cancel the order is logged; an order that has moved on already is
+// left alone.
+func (p *Processor) fail(ctx context.Context, o *models.Order, paymentID int32, cause error) error {
+	if paymentID != 0 {
+		if _, err := p.payments.Update(ctx, paymentID, store.PaymentUpdate{Status: StatusFailed, FailureReason: cause.Error()}); err != nil {
+			log.Printf("Failed to record failure of payment %d: %v", paymentID, err)
+		}
+	}
+	_, err := p.orders.SetStatus(ctx, o.ID, store.StatusChange{
+		From:    string(orderstatus.Pending),
+		To:      string(orderstatus.Cancelled),
+		Note:    cause.Error(),
+		Restock: true,
+	})
+	if err != nil && !errors.Is(err, store.ErrStatusChanged) {
+		log.Printf("Failed to cancel order %d after its payment failed: %v", o.ID, err)
+	}
+	return cause
+}
+
+// giveBack refunds a captured payment whose order won't be paid and
+// records the refund. A payment that can't be refunded is logged and left
+// as it is, captured, for someone to refund by hand.
+func (p *Processor) giveBack(ctx context.Context, paymentID int32, reference string, amount pricing.Cents) {
+	if err := p.provider.Refund(ctx, reference, amount); err != nil {
+		log.Printf("Failed to refund payment %d: %v", paymentID, err)
+		return
+	}
+	if _, err := p.payments.Update(ctx, paymentID, store.PaymentUpdate{Status: StatusRefunded, Refunded: amount}); err != nil {
+		log.Printf("Failed to record refund of payment %d: %v", paymentID, err)
+	}
+}
+
+// void releases an authorization that won't be captured. Failing to do so
+// only delays the release until the hold lapses, so it is just logged.
+func (p *Processor) void(ctx context.Context, reference string) {
+	if err := p.provider.Void(ctx, reference); err != nil {
+		log.Printf("Failed to void payment authorization %s: %v", reference, err)
+	}
+}
+
+// Refund returns amount, or if nil all that is left, of the order's
+// captured payment. A full refund moves the order to REFUNDED on behalf of
+// actorID, so it must be allowed by the order lifecycle.
+//
+// The refund is reserved on the payment before the provider is asked, so
+// of concurrent refunds only those the payment covers reach the provider;
+// the others fail with a *RefundAmountError.
+func (p *Processor) Refund(ctx context.Context, o *models.Order, amount *pricing.Cents, actorID int32, reason string) (*models.Order, error) {
+	payment, err := p.refundable(ctx, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	refundable := pricing.FromFloat(payment.Amount) - pricing.FromFloat(payment.AmountRefunded)
+	if refundable <= 0 {
+		// All of it is reserved by refunds under way
+		return nil, ErrNothingToRefund
+	}
+	refund := refundable
+	if amount != nil {
+		if *amount <= 0 || *amount > refundable {
+			return nil, &RefundAmountError{Requested: *amount, Refundable: refundable}
+		}
+		refund = *amount
+	}
+
+	reserved, err := p.payments.ReserveRefund(ctx, payment.ID, refund)
+	if errors.Is(err, store.ErrRefundExceeded) {
+		// Another refund got there first
+		left := pricing.Cents(0)
+		if current, err := p.payments.Get(ctx, payment.ID); err == nil && current.Status == StatusCaptured {
+			left = pricing.FromFloat(current.Amount) - pricing.FromFloat(current.AmountRefunded)
+		}
+		return nil, &RefundAmountError{Requested: refund, Refundable: left}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The refund taking the last of the payment refunds the order
+	from := orderstatus.Status(o.Status)
+	full := pricing.FromFloat(reserved.AmountRefunded) == pricing.FromFloat(reserved.Amount)
+	if full {
+		if err := orderstatus.Check(from, orderstatus.Refunded); err != nil {
+			p.release(ctx, payment.ID, refund)
+			return nil, err
+		}
+	}
+
+	if err := p.provider.Refund(ctx, *payment.Reference, refund); err != nil {
+		p.release(ctx, payment.ID, refund)
+		return nil, err
+	}
+	if !full {
+		return o, nil
+	}
+	if _, err := p.payments.Update(ctx, payment.ID, store.PaymentUpdate{Status: StatusRefunded}); err != nil {
+		return nil, err
+	}
+	return p.orders.SetStatus(ctx, o.ID, store.StatusChange{
+		From:    o.Status,
+		To:      string(orderstatus.Refunded),
+		ActorID: actorID,
+		Note:    reason,
+		Restock: orderstatus.ReturnsStock(from, orderstatus.Refunded),
+	})
+}
+
+// release gives back a refund reserved on a payment that the provider
+// didn't make. Failing to is logged: the payment then shows more refunded
+// than it was, which errs towards refunding too little rather than twice.
+func (p *Processor) release(ctx context.Context, paymentID int32, refund pricing.Cents) {
+	if _, err := p.payments.Update(ctx, paymentID, store.PaymentUpdate{Refunded: -refund}); err != nil {
+		log.Printf("Failed to release refund of %s reserved on payment %d: %v", refund, paymentID, err)
+	}
+}
+
+// refundable returns the order's captured payment.
+func (p *Processor) refundable(ctx context.Context, orderID int32) (*models.Payment, error) {
+	payments, err := p.payments.ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(payments) - 1; i >= 0; i-- {
+		if payments[i].Status == StatusCaptured && payments[i].Reference != nil {
+			return payments[i], nil
+		}
+	}
+	return nil, ErrNothingToRefund
+}
+
+// CancelUnpaid cancels the orders placed before t that are still waiting
+// for their payment, returning their items to stock, and reports how many
+// it cancelled. Authorizations held for them are voided first; an order
+// whose authorization can't be voided is left for a later run, as the
+// provider could still capture it.
+func (p *Processor) CancelUnpaid(ctx context.Context, t time.Time) (int64, error) {
+	orders, err := p.orders.PendingBefore(ctx, t)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, o := range orders {
+		if err := p.voidAuthorized(ctx, o.ID); err != nil {
+			log.Printf("Failed to void the payment of unpaid order %d: %v", o.ID, err)
+			continue
+		}
+		_, err := p.orders.SetStatus(ctx, o.ID, store.StatusChange{
+			From:    string(orderstatus.Pending),
+			To:      string(orderstatus.Cancelled),
+			Note:    "not paid in time",
+			Restock: true,
+		})
+		switch {
+		case errors.Is(err, store.ErrStatusChanged):
+			// Paid or cancelled in the meantime
+		case err != nil:
+			return n, err
+		default:
+			n++
+		}
+	}
+	return n, nil
+}
+
+// voidAuthorized voids the order's authorized payments and records them as
+// voided.
+func (p *Processor) voidAuthorized(ctx context.Context, orderID int32) error {
+	payments, err := p.payments.ListByOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	for _, payment := range payments {
+		if payment.Status != StatusAuthorized || payment.Reference == nil {
+			continue
+		}
+		if err := p.provider.Void(ctx, *payment.Reference); err != nil {
+			return err
+		}
+		if _, err := p.payments.Update(ctx, payment.ID, store.PaymentUpdate{Status: StatusVoided, FailureReason: "order not paid in time"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleEvent applies a verified webhook event to the payment it names and
+// its order. Events are applied idempotently, as providers may deliver them
+// more than once, and events of unknown types are ignored.
+func (p *Processor) HandleEvent(ctx context.Context, e *Event) error {
+	payment, err := p.payments.GetByReference(ctx, p.provider.Name(), e.Reference)
+	if err != nil {
+		return err
+	}
+	o, err := p.orders.Get(ctx, payment.OrderID)
+	if err != nil {
+		return err
+	}
+	note := fmt.Sprintf("%s webhook for payment %d", e.Type, payment.ID)
+
+	switch e.Type {
+	case EventCaptured:
+		if payment.Status != StatusAuthorized {
+			return nil
+		}
+		if _, err := p.payments.Update(ctx, payment.ID, store.PaymentUpdate{Status: StatusCaptured}); err != nil {
+			return err
+		}
+		return p.advance(ctx, o, orderstatus.Paid, note)
+
+	case EventFailed, EventVoided:
+		if payment.Status != StatusPending && payment.Status != StatusAuthorized {
+			return nil
+		}
+		status := StatusFailed
+		if e.Type == EventVoided {
+			status = StatusVoided
+		}
+		if _, err := p.payments.Update(ctx, payment.ID, store.PaymentUpdate{Status: status, FailureReason: string(e.Type)}); err != nil {
+			return err
+		}
+		return p.advance(ctx, o, orderstatus.Cancelled, note)
+
+	case EventRefunded:
+		delta := e.Amount - pricing.FromFloat(payment.AmountRefunded)
+		if delta <= 0 {
+			return nil
+		}
+		update := store.PaymentUpdate{Refunded: delta}
+		full := e.Amount >= pricing.FromFloat(payment.Amount)
+		if full {
+			update.Status = StatusRefunded
+		}
+		if _, err := p.payments.Update(ctx, payment.ID, update); err != nil {
+			return err
+		}
+		if full {
+			return p.advance(ctx, o, orderstatus.Refunded, note)
+		}
+	}
+	return nil
+}
+
+// advance moves an order to status to if the lifecycle allows it, and
+// otherwise leaves it alone.
+func (p *Processor) advance(ctx context.Context, o *models.Order, to orderstatus.Status, note string) error {
+	from := orderstatus.Status(o.Status)
+	if orderstatus.Check(from, to) != nil {
+		return nil
+	}
+	_, err := p.orders.SetStatus(ctx, o.ID, store.StatusChange{
+		From:    o.Status,
+		To:      string(to),
+		Note:    note,
+		Restock: orderstatus.ReturnsStock(from, to),
+	})
+	if errors.Is(err, store.ErrStatusChanged) {
+		return nil
+	}
+	return err
+}
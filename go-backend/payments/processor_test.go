@@ -0,0 +1,350 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-backend/models"
+	"go-backend/pricing"
+	"go-backend/store"
+)
+
+// shop is a memory store with one product in stock and a pending order
+// for two of it.
+type shop struct {
+	st      *store.Store
+	product *models.Product
+}
+
+const stock = 10
+
+func newShop(t *testing.T) *shop {
+	t.Helper()
+	ctx := context.Background()
+	st := store.NewMemory()
+	cat, err := st.Categories.Create(ctx, "Mugs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := st.Products.Create(ctx, models.ProductInput{Name: "Mug", Price: 12.99, StockQuantity: stock, CategoryID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &shop{st: st, product: p}
+}
+
+func (s *shop) order(t *testing.T) *models.Order {
+	t.Helper()
+	o, err := s.st.Orders.Create(context.Background(), 1, store.NewOrder{
+		Subtotal: 25.98,
+		Total:    25.98,
+		Items:    []store.NewOrderItem{{ProductID: s.product.ID, Quantity: 2, UnitPrice: 12.99, LineTotal: 25.98}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func (s *shop) stock(t *testing.T) int32 {
+	t.Helper()
+	p, err := s.st.Products.Get(context.Background(), s.product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.StockQuantity
+}
+
+var errInjected = errors.New("injected failure")
+
+// flakyPayments fails creating payments, or setting them to the statuses
+// in failStatus.
+type flakyPayments struct {
+	store.PaymentStore
+	failCreate bool
+	failStatus string
+}
+
+func (s *flakyPayments) Create(ctx context.Context, orderID int32, provider string, amount pricing.Cents) (*models.Payment, error) {
+	if s.failCreate {
+		return nil, errInjected
+	}
+	return s.PaymentStore.Create(ctx, orderID, provider, amount)
+}
+
+func (s *flakyPayments) Update(ctx context.Context, id int32, update store.PaymentUpdate) (*models.Payment, error) {
+	if update.Status != "" && update.Status == s.failStatus {
+		return nil, errInjected
+	}
+	return s.PaymentStore.Update(ctx, id, update)
+}
+
+// flakyOrders fails marking orders paid.
+type flakyOrders struct {
+	store.OrderStore
+	failPaid bool
+}
+
+func (s *flakyOrders) SetStatus(ctx context.Context, id int32, change store.StatusChange) (*models.Order, error) {
+	if s.failPaid && change.To == "PAID" {
+		return nil, errInjected
+	}
+	return s.OrderStore.SetStatus(ctx, id, change)
+}
+
+func TestPay(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		payments     flakyPayments
+		orders       flakyOrders
+		wantErr      error
+		wantOrder    string
+		wantStock    int32
+		wantPayment  string // "" if no payment is recorded
+		wantRefunded float64
+		wantDeclined bool
+	}{
+		{name: "paid", method: FakeCardOK, wantOrder: "PAID", wantStock: stock - 2, wantPayment: StatusCaptured},
+		{name: "declined", method: FakeCardDeclined, wantDeclined: true, wantOrder: "CANCELLED", wantStock: stock, wantPayment: StatusFailed},
+		{name: "payment not recorded", method: FakeCardOK, payments: flakyPayments{failCreate: true}, wantErr: errInjected, wantOrder: "CANCELLED", wantStock: stock},
+		{name: "authorization not recorded", method: FakeCardOK, payments: flakyPayments{failStatus: StatusAuthorized}, wantErr: errInjected, wantOrder: "CANCELLED", wantStock: stock, wantPayment: StatusFailed},
+		{name: "capture not recorded", method: FakeCardOK, payments: flakyPayments{failStatus: StatusCaptured}, wantErr: errInjected, wantOrder: "CANCELLED", wantStock: stock, wantPayment: StatusRefunded, wantRefunded: 25.98},
+		{name: "order not marked paid", method: FakeCardOK, orders: flakyOrders{failPaid: true}, wantErr: errInjected, wantOrder: "CANCELLED", wantStock: stock, wantPayment: StatusRefunded, wantRefunded: 25.98},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newShop(t)
+			o := s.order(t)
+			payments, orders := tt.payments, tt.orders
+			payments.PaymentStore, orders.OrderStore = s.st.Payments, s.st.Orders
+			p := NewProcessor(NewFake([]byte("secret")), &orders, &payments)
+
+			paid, err := p.Pay(ctx, o, tt.method)
+			var declined *DeclinedError
+			switch {
+			case tt.wantDeclined && !errors.As(err, &declined):
+				t.Fatalf("Pay() error = %v, want a *DeclinedError", err)
+			case !tt.wantDeclined && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Pay() error = %v, want %v", err, tt.wantErr)
+			case err == nil && paid.Status != "PAID":
+				t.Errorf("Pay() returned a %s order", paid.Status)
+			}
+
+			got, err := s.st.Orders.Get(ctx, o.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantOrder {
+				t.Errorf("order is %s, want %s", got.Status, tt.wantOrder)
+			}
+			if n := s.stock(t); n != tt.wantStock {
+				t.Errorf("stock is %d, want %d", n, tt.wantStock)
+			}
+
+			intents, err := s.st.Payments.ListByOrder(ctx, o.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantPayment == "" {
+				if len(intents) != 0 {
+					t.Errorf("recorded %d payments, want none", len(intents))
+				}
+				return
+			}
+			if len(intents) != 1 {
+				t.Fatalf("recorded %d payments, want 1", len(intents))
+			}
+			if pay := intents[0]; pay.Status != tt.wantPayment || pay.Amount != 25.98 || pay.AmountRefunded != tt.wantRefunded {
+				t.Errorf("payment is %s of %v with %v refunded, want %s of 25.98 with %v refunded",
+					pay.Status, pay.Amount, pay.AmountRefunded, tt.wantPayment, tt.wantRefunded)
+			}
+		})
+	}
+}
+
+// A restarted provider must not hand out references that are already
+// recorded.
+func TestPayAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	s := newShop(t)
+	refs := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		p := NewProcessor(NewFake([]byte("secret")), s.st.Orders, s.st.Payments)
+		o := s.order(t)
+		paid, err := p.Pay(ctx, o, FakeCardOK)
+		if err != nil {
+			t.Fatalf("payment %d: %v", i+1, err)
+		}
+		if paid.Status != "PAID" {
+			t.Fatalf("payment %d left the order %s", i+1, paid.Status)
+		}
+		intents, err := s.st.Payments.ListByOrder(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs[*intents[0].Reference] = true
+	}
+	if len(refs) != 2 {
+		t.Errorf("references %v aren't unique", refs)
+	}
+}
+
+// Orders left pending past the deadline are cancelled and restocked, and
+// the authorizations held for them released; paid orders are left alone.
+func TestCancelUnpaid(t *testing.T) {
+	ctx := context.Background()
+	s := newShop(t)
+	fake := NewFake([]byte("secret"))
+	p := NewProcessor(fake, s.st.Orders, s.st.Payments)
+
+	paid := s.order(t)
+	if _, err := p.Pay(ctx, paid, FakeCardOK); err != nil {
+		t.Fatal(err)
+	}
+	unpaid := s.order(t)
+	authorized := s.order(t)
+	payment, err := s.st.Payments.Create(ctx, authorized.ID, fake.Name(), pricing.FromFloat(authorized.TotalAmount))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, err := fake.Authorize(ctx, AuthorizeRequest{OrderID: authorized.ID, Amount: pricing.FromFloat(authorized.TotalAmount), PaymentMethod: FakeCardOK})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.st.Payments.Update(ctx, payment.ID, store.PaymentUpdate{Status: StatusAuthorized, Reference: auth.Reference}); err != nil {
+		t.Fatal(err)
+	}
+
+	// None were placed an hour ago.
+	if n, err := p.CancelUnpaid(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("CancelUnpaid(an hour ago) = %d, %v, want 0", n, err)
+	}
+	if n, err := p.CancelUnpaid(ctx, time.Now().Add(time.Second)); err != nil || n != 2 {
+		t.Fatalf("CancelUnpaid(now) = %d, %v, want 2", n, err)
+	}
+
+	for o, want := range map[*models.Order]string{paid: "PAID", unpaid: "CANCELLED", authorized: "CANCELLED"} {
+		got, err := s.st.Orders.Get(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != want {
+			t.Errorf("order %d is %s, want %s", o.ID, got.Status, want)
+		}
+	}
+	if n := s.stock(t); n != stock-2 {
+		t.Errorf("stock is %d, want %d", n, stock-2)
+	}
+	got, err := s.st.Payments.Get(ctx, payment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusVoided {
+		t.Errorf("authorized payment is %s, want %s", got.Status, StatusVoided)
+	}
+}
+
+// countingProvider tallies the refunds the provider made. Refunds take a
+// while, as they would over the network, so that concurrent ones overlap.
+type countingProvider struct {
+	PaymentProvider
+	mu       sync.Mutex
+	refunded pricing.Cents
+}
+
+func (p *countingProvider) Refund(ctx context.Context, reference string, amount pricing.Cents) error {
+	time.Sleep(10 * time.Millisecond)
+	if err := p.PaymentProvider.Refund(ctx, reference, amount); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refunded += amount
+	return nil
+}
+
+// Concurrent refunds of one payment reach the provider only as far as the
+// payment covers them; the rest fail before any money moves.
+func TestRefundConcurrently(t *testing.T) {
+	ten := pricing.Cents(1000)
+	tests := []struct {
+		name        string
+		amounts     []*pricing.Cents // nil refunds what is left
+		wantOK      int
+		wantRefund  pricing.Cents
+		wantOrder   string
+		wantStock   int32
+		wantPayment string
+	}{
+		{name: "partial refunds", amounts: []*pricing.Cents{&ten, &ten, &ten, &ten}, wantOK: 2, wantRefund: 2000, wantOrder: "PAID", wantStock: stock - 2, wantPayment: StatusCaptured},
+		{name: "full refunds", amounts: []*pricing.Cents{nil, nil, nil}, wantOK: 1, wantRefund: 2598, wantOrder: "REFUNDED", wantStock: stock, wantPayment: StatusRefunded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newShop(t)
+			provider := &countingProvider{PaymentProvider: NewFake([]byte("secret"))}
+			p := NewProcessor(provider, s.st.Orders, s.st.Payments)
+			o, err := p.Pay(ctx, s.order(t), FakeCardOK)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := make(chan struct{})
+			errs := make(chan error, len(tt.amounts))
+			var wg sync.WaitGroup
+			for _, amount := range tt.amounts {
+				wg.Add(1)
+				go func(amount *pricing.Cents) {
+					defer wg.Done()
+					<-start
+					_, err := p.Refund(ctx, o, amount, 0, "")
+					errs <- err
+				}(amount)
+			}
+			close(start)
+			wg.Wait()
+			close(errs)
+
+			ok := 0
+			for err := range errs {
+				var amountErr *RefundAmountError
+				switch {
+				case err == nil:
+					ok++
+				case !errors.As(err, &amountErr) && !errors.Is(err, ErrNothingToRefund):
+					t.Errorf("Refund() error = %v, want a *RefundAmountError or ErrNothingToRefund", err)
+				}
+			}
+			if ok != tt.wantOK {
+				t.Errorf("%d refunds succeeded, want %d", ok, tt.wantOK)
+			}
+			if provider.refunded != tt.wantRefund {
+				t.Errorf("provider refunded %s, want %s", provider.refunded, tt.wantRefund)
+			}
+
+			intents, err := s.st.Payments.ListByOrder(ctx, o.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pay := intents[0]; pay.Status != tt.wantPayment || pricing.FromFloat(pay.AmountRefunded) != tt.wantRefund {
+				t.Errorf("payment is %s with %v refunded, want %s with %s", pay.Status, pay.AmountRefunded, tt.wantPayment, tt.wantRefund)
+			}
+			got, err := s.st.Orders.Get(ctx, o.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantOrder {
+				t.Errorf("order is %s, want %s", got.Status, tt.wantOrder)
+			}
+			if n := s.stock(t); n != tt.wantStock {
+				t.Errorf("stock is %d, want %d", n, tt.wantStock)
+			}
+		})
+	}
+}
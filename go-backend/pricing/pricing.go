@@ -0,0 +1,133 @@
+// Package pricing computes order totals. All arithmetic is done in whole
+// cents so totals add up exactly; amounts are converted from and to the
+// float prices used elsewhere only at the edges.
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Cents is an amount of money in the smallest currency unit.
+type Cents int64
+
+// FromFloat converts a price such as 12.99 to cents, rounding to the
+// nearest cent.
+func FromFloat(f float64) Cents {
+	return Cents(math.Round(f * 100))
+}
+
+// Float converts c back to the float form used by the API.
+func (c Cents) Float() float64 {
+	return float64(c) / 100
+}
+
+func (c Cents) String() string {
+	sign := ""
+	if c < 0 {
+		sign, c = "-", -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// VolumeDiscount takes PercentOff off a line's subtotal when at least
+// MinQuantity units are ordered.
+type VolumeDiscount struct {
+	MinQuantity int32
+	PercentOff  int64
+}
+
+// Rules are the pricing policies applied to every order.
+type Rules struct {
+	// TaxBasisPoints is the sales tax rate in hundredths of a percent, e.g.
+	// 825 for 8.25%. Tax is charged on each line after discounts.
+	TaxBasisPoints int64
+	// Shipping is the flat shipping charge per order.
+	Shipping Cents
+	// FreeShippingOver waives shipping when the discounted subtotal reaches
+	// it. Zero never waives it.
+	FreeShippingOver Cents
+	// VolumeDiscounts apply per line; the largest one reached wins.
+	VolumeDiscounts []VolumeDiscount
+}
+
+// DefaultRules are the pricing rules used unless configured otherwise: no
+// tax or discounts, and flat-rate shipping that is free on larger orders.
+func DefaultRules() Rules {
+	return Rules{
+		Shipping:         599,
+		FreeShippingOver: 5000,
+	}
+}
+
+// Line is a product and quantity to be priced at UnitPrice.
+type Line struct {
+	ProductID int32
+	Quantity  int32
+	UnitPrice Cents
+}
+
+// LineQuote is the priced form of a Line.
+type LineQuote struct {
+	Line
+	Subtotal Cents // UnitPrice × Quantity
+	Discount Cents
+	Tax      Cents
+	Total    Cents // Subtotal − Discount + Tax
+}
+
+// Quote is a fully priced order.
+type Quote struct {
+	Lines    []LineQuote
+	Subtotal Cents
+	Discount Cents
+	Tax      Cents
+	Shipping Cents
+	Total    Cents
+}
+
+// Price applies rules to lines. Quantities must be positive.
+func (rules Rules) Price(lines []Line) (*Quote, error) {
+	discounts := append([]VolumeDiscount(nil), rules.VolumeDiscounts...)
+	sort.Slice(discounts, func(i, j int) bool { return discounts[i].MinQuantity > discounts[j].MinQuantity })
+
+	q := &Quote{Lines: make([]LineQuote, len(lines))}
+	for i, l := range lines {
+		if l.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity of product %d must be positive", l.ProductID)
+		}
+		if l.UnitPrice < 0 {
+			return nil, fmt.Errorf("price of product %d must not be negative", l.ProductID)
+		}
+
+		lq := LineQuote{Line: l, Subtotal: l.UnitPrice * Cents(l.Quantity)}
+		for _, d := range discounts {
+			if l.Quantity >= d.MinQuantity {
+				lq.Discount = percentOf(lq.Subtotal, d.PercentOff*100)
+				break
+			}
+		}
+		lq.Tax = percentOf(lq.Subtotal-lq.Discount, rules.TaxBasisPoints)
+		lq.Total = lq.Subtotal - lq.Discount + lq.Tax
+		q.Lines[i] = lq
+
+		q.Subtotal += lq.Subtotal
+		q.Discount += lq.Discount
+		q.Tax += lq.Tax
+	}
+
+	if len(lines) > 0 {
+		q.Shipping = rules.Shipping
+		if rules.FreeShippingOver > 0 && q.Subtotal-q.Discount >= rules.FreeShippingOver {
+			q.Shipping = 0
+		}
+	}
+	q.Total = q.Subtotal - q.Discount + q.Tax + q.Shipping
+	return q, nil
+}
+
+// percentOf returns basisPoints/10000 of c, rounded half up.
+func percentOf(c Cents, basisPoints int64) Cents {
+	return Cents((int64(c)*basisPoints + 5000) / 10000)
+}
@@ -0,0 +1,147 @@
+package pricing
+
+import "testing"
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want Cents
+	}{
+		{12.99, 1299},
+		{0.1 + 0.2, 30},
+		{0.125, 13},
+		{0.124, 12},
+		{19.999, 2000},
+		{-2.5, -250},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := FromFloat(tt.in); got != tt.want {
+			t.Errorf("FromFloat(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCentsString(t *testing.T) {
+	tests := []struct {
+		in   Cents
+		want string
+	}{
+		{1299, "12.99"},
+		{5, "0.05"},
+		{100, "1.00"},
+		{0, "0.00"},
+		{-250, "-2.50"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Cents(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+// totals are the order-level amounts of a Quote.
+type totals struct {
+	Subtotal, Discount, Tax, Shipping, Total Cents
+}
+
+func TestPrice(t *testing.T) {
+	volume := []VolumeDiscount{{MinQuantity: 5, PercentOff: 10}, {MinQuantity: 10, PercentOff: 20}}
+	tests := []struct {
+		name  string
+		rules Rules
+		lines []Line
+		want  totals
+	}{
+		{
+			name:  "empty order ships nothing",
+			rules: DefaultRules(),
+			want:  totals{},
+		},
+		{
+			name:  "flat shipping",
+			rules: DefaultRules(),
+			lines: []Line{{ProductID: 1, Quantity: 2, UnitPrice: 1299}},
+			want:  totals{Subtotal: 2598, Shipping: 599, Total: 3197},
+		},
+		{
+			name:  "free shipping from the threshold",
+			rules: DefaultRules(),
+			lines: []Line{{ProductID: 1, Quantity: 2, UnitPrice: 1299}, {ProductID: 2, Quantity: 1, UnitPrice: 2402}},
+			want:  totals{Subtotal: 5000, Total: 5000},
+		},
+		{
+			name:  "tax rounds down below half a cent",
+			rules: Rules{TaxBasisPoints: 825},
+			lines: []Line{{ProductID: 1, Quantity: 1, UnitPrice: 199}},
+			want:  totals{Subtotal: 199, Tax: 16, Total: 215},
+		},
+		{
+			name:  "tax rounds half a cent up",
+			rules: Rules{TaxBasisPoints: 25},
+			lines: []Line{{ProductID: 1, Quantity: 1, UnitPrice: 200}},
+			want:  totals{Subtotal: 200, Tax: 1, Total: 201},
+		},
+		{
+			name:  "tax is rounded per line",
+			rules: Rules{TaxBasisPoints: 25},
+			lines: []Line{{ProductID: 1, Quantity: 1, UnitPrice: 200}, {ProductID: 2, Quantity: 1, UnitPrice: 200}},
+			want:  totals{Subtotal: 400, Tax: 2, Total: 402},
+		},
+		{
+			name:  "smaller volume discount",
+			rules: Rules{VolumeDiscounts: volume},
+			lines: []Line{{ProductID: 1, Quantity: 7, UnitPrice: 100}},
+			want:  totals{Subtotal: 700, Discount: 70, Total: 630},
+		},
+		{
+			name:  "largest volume discount reached wins",
+			rules: Rules{VolumeDiscounts: volume},
+			lines: []Line{{ProductID: 1, Quantity: 10, UnitPrice: 100}},
+			want:  totals{Subtotal: 1000, Discount: 200, Total: 800},
+		},
+		{
+			name:  "tax is charged after discounts",
+			rules: Rules{TaxBasisPoints: 1000, VolumeDiscounts: volume},
+			lines: []Line{{ProductID: 1, Quantity: 10, UnitPrice: 100}},
+			want:  totals{Subtotal: 1000, Discount: 200, Tax: 80, Total: 880},
+		},
+		{
+			name:  "free shipping counts the discounted subtotal",
+			rules: Rules{Shipping: 599, FreeShippingOver: 1000, VolumeDiscounts: volume},
+			lines: []Line{{ProductID: 1, Quantity: 10, UnitPrice: 100}},
+			want:  totals{Subtotal: 1000, Discount: 200, Shipping: 599, Total: 1399},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := tt.rules.Price(tt.lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(q.Lines) != len(tt.lines) {
+				t.Errorf("priced %d lines, want %d", len(q.Lines), len(tt.lines))
+			}
+			got := totals{q.Subtotal, q.Discount, q.Tax, q.Shipping, q.Total}
+			if got != tt.want {
+				t.Errorf("Price() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		line Line
+	}{
+		{"zero quantity", Line{ProductID: 1, Quantity: 0, UnitPrice: 100}},
+		{"negative quantity", Line{ProductID: 1, Quantity: -1, UnitPrice: 100}},
+		{"negative price", Line{ProductID: 1, Quantity: 1, UnitPrice: -1}},
+	}
+	for _, tt := range tests {
+		if _, err := DefaultRules().Price([]Line{tt.line}); err == nil {
+			t.Errorf("%s: Price() succeeded", tt.name)
+		}
+	}
+}
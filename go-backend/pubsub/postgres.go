@@ -0,0 +1,99 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// notifyChannel is the NOTIFY channel every topic shares. Each notification
+// carries the topic and the message, separated by a newline.
+const notifyChannel = "pubsub"
+
+// maxNotifyPayload is the largest payload NOTIFY accepts, in bytes.
+const maxNotifyPayload = 7999
+
+// listenerPingInterval is how long the listener waits without
+// notifications before checking its connection is still alive.
+const listenerPingInterval = 90 * time.Second
+
+// Postgres is a Broker shared by every instance of the server connected to
+// the same database: messages are published with NOTIFY and each instance
+// LISTENs and hands them to its own subscribers. Messages must be valid
+// UTF-8 text without NUL bytes, as JSON is, and fit in a NOTIFY payload
+// along with their topic. Messages published while an instance is
+// reconnecting to the database are lost to it.
+type Postgres struct {
+	db       *sql.DB
+	listener *pq.Listener
+	local    *Memory
+	done     chan struct{}
+}
+
+// NewPostgres returns a Postgres broker publishing through db and listening
+// on a connection of its own to connStr.
+func NewPostgres(db *sql.DB, connStr string) (*Postgres, error) {
+	listener := pq.NewListener(connStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("pubsub: listener: %v", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("pubsub: listening for notifications: %w", err)
+	}
+	p := &Postgres{db: db, listener: listener, local: NewMemory(), done: make(chan struct{})}
+	go p.run()
+	return p, nil
+}
+
+// Publish sends the message to every instance, this one included, when the
+// surrounding transaction, if any, commits.
+func (p *Postgres) Publish(ctx context.Context, topic string, msg []byte) error {
+	payload := topic + "\n" + string(msg)
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("pubsub: message on %s is %d bytes, over the NOTIFY limit", topic, len(payload))
+	}
+	_, err := p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", notifyChannel, payload)
+	return err
+}
+
+func (p *Postgres) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	return p.local.Subscribe(ctx, topic)
+}
+
+// Close stops listening. Subscribers receive no more messages but their
+// channels stay open until their contexts are done.
+func (p *Postgres) Close() error {
+	err := p.listener.Close()
+	<-p.done
+	return err
+}
+
+func (p *Postgres) run() {
+	defer close(p.done)
+	for {
+		select {
+		case n, ok := <-p.listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification means the connection was re-established.
+			if n == nil {
+				continue
+			}
+			topic, msg, ok := strings.Cut(n.Extra, "\n")
+			if !ok {
+				continue
+			}
+			p.local.Publish(context.Background(), topic, []byte(msg))
+		case <-time.After(listenerPingInterval):
+			go p.listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package pubsub carries events, such as an upload arriving, from the code
+// that causes them to the GraphQL subscriptions waiting for them.
+// Messages are small, opaque payloads, typically a JSON object naming the
+// rows that changed, so that a Broker can be backed by Postgres
+// LISTEN/NOTIFY, whose payloads are limited to 8000 bytes, as well as by
+// memory.
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Broker delivers messages published on a topic to everyone subscribed to
+// it at the time. Delivery is best effort: subscribers that fall behind
+// miss messages rather than hold up the publisher.
+type Broker interface {
+	Publish(ctx context.Context, topic string, msg []byte) error
+	// Subscribe returns the messages published on topic from now on. The
+	// channel is closed once ctx is done.
+	Subscribe(ctx context.Context, topic string) (<-chan []byte, error)
+}
+
+// subscriberBuffer is how many messages a subscriber may fall behind by
+// before it misses some.
+const subscriberBuffer = 16
+
+// Memory is a Broker within a single process.
+type Memory struct {
+	mu   sync.Mutex
+	subs map[string]map[chan []byte]struct{}
+}
+
+// NewMemory returns an empty Memory broker.
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[string]map[chan []byte]struct{})}
+}
+
+func (m *Memory) Publish(ctx context.Context, topic string, msg []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for ch := range m.subs[topic] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	ch := make(chan []byte, subscriberBuffer)
+
+	m.mu.Lock()
+	if m.subs[topic] == nil {
+		m.subs[topic] = make(map[chan []byte]struct{})
+	}
+	m.subs[topic][ch] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		// Closing under the lock keeps Publish from sending on a closed
+		// channel.
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subs[topic], ch)
+		if len(m.subs[topic]) == 0 {
+			delete(m.subs, topic)
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
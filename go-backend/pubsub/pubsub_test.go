@@ -0,0 +1,53 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewMemory()
+	a, err := m.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.Subscribe(context.Background(), "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A subscriber that falls behind misses messages rather than holding
+	// up the publisher.
+	for i := 0; i < subscriberBuffer+5; i++ {
+		if err := m.Publish(ctx, "a", []byte(fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < subscriberBuffer; i++ {
+		if got := string(<-a); got != fmt.Sprint(i) {
+			t.Fatalf("message %d is %s", i, got)
+		}
+	}
+	select {
+	case msg := <-b:
+		t.Errorf("b received %s, published on a", msg)
+	default:
+	}
+
+	cancel()
+	select {
+	case _, ok := <-a:
+		if ok {
+			t.Error("received a dropped message")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed once the context was done")
+	}
+	// Publishing to a topic nobody listens to any more is fine.
+	if err := m.Publish(context.Background(), "a", []byte("late")); err != nil {
+		t.Fatal(err)
+	}
+}
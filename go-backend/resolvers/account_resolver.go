@@ -0,0 +1,103 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/store"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Creates an account and logs it in
+func (r *Resolver) Register(ctx context.Context, args struct{ Input models.RegisterInput }) (*UserResolver, error) {
+	reg := auth.Registration{Email: args.Input.Email, Password: args.Input.Password}
+	if args.Input.FirstName != nil {
+		reg.FirstName = *args.Input.FirstName
+	}
+	if args.Input.LastName != nil {
+		reg.LastName = *args.Input.LastName
+	}
+	u, err := r.accounts.Register(ctx, reg)
+	if err != nil {
+		return nil, userError(err)
+	}
+	if err := auth.StartSession(ctx, u.ID); err != nil {
+		return nil, err
+	}
+	return &UserResolver{r, *u, true}, nil
+}
+
+// Logs in with an email address and password
+func (r *Resolver) Login(ctx context.Context, args struct {
+	Email     string
+	Password  string
+	CartToken *string
+}) (*UserResolver, error) {
+	u, err := r.accounts.Login(ctx, args.Email, args.Password)
+	if err != nil {
+		return nil, userError(err)
+	}
+	if err := auth.StartSession(ctx, u.ID); err != nil {
+		return nil, err
+	}
+	// The session is issued already, so a cart that can't be merged doesn't
+	// fail the login; the anonymous cart stays behind its token
+	if args.CartToken != nil && *args.CartToken != "" {
+		if _, err := r.store.Carts.Merge(ctx, *args.CartToken, u.ID, time.Now().Add(CartTTL)); err != nil {
+			log.Printf("login: merging cart: %v", err)
+		}
+	}
+	return &UserResolver{r, *u, true}, nil
+}
+
+// Ends the current session
+func (r *Resolver) Logout(ctx context.Context) (bool, error) {
+	if err := auth.EndSession(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Resolves the logged-in user, or null
+func (r *Resolver) Me(ctx context.Context) (*UserResolver, error) {
+	p := auth.FromContext(ctx)
+	if p == nil {
+		return nil, nil
+	}
+	u, err := r.store.Users.Get(ctx, p.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &UserResolver{r, *u, false}, nil
+}
+
+// Changes a user's role; admins only
+func (r *Resolver) SetUserRole(ctx context.Context, args struct {
+	UserID graphql.ID
+	Role   string
+}) (*UserResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.UserID)
+	if err != nil {
+		return nil, err
+	}
+	role := auth.Role(strings.ToLower(args.Role))
+	if !role.Valid() {
+		return nil, newError(codeBadUserInput, "unknown role %q", args.Role)
+	}
+	u, err := r.store.Users.SetRole(ctx, id, string(role))
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &UserResolver{r, *u, false}, nil
+}
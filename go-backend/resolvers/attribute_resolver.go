@@ -0,0 +1,332 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-backend/attrdef"
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/store"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// AttributeDefinitionResolver resolves the AttributeDefinition type
+type AttributeDefinitionResolver struct {
+	root *Resolver
+	d    models.AttributeDefinition
+}
+
+func (r *AttributeDefinitionResolver) ID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.d.ID))
+}
+
+func (r *AttributeDefinitionResolver) Category(ctx context.Context) (*CategoryResolver, error) {
+	c, err := r.root.loaders(ctx).CategoryByID.Load(ctx, r.d.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("category %d not found", r.d.CategoryID)
+	}
+	return &CategoryResolver{r.root, *c}, nil
+}
+
+func (r *AttributeDefinitionResolver) Name() string {
+	return r.d.Name
+}
+
+func (r *AttributeDefinitionResolver) Type() string {
+	return r.d.Type
+}
+
+func (r *AttributeDefinitionResolver) AllowedValues() []string {
+	if r.d.AllowedValues == nil {
+		return []string{}
+	}
+	return r.d.AllowedValues
+}
+
+func (r *AttributeDefinitionResolver) Required() bool {
+	return r.d.Required
+}
+
+// definitionsFor returns the attribute definitions applying to products in
+// categoryID: its own and those inherited from its ancestors, top level
+// first, with nearer definitions replacing inherited ones of the same name.
+func (r *Resolver) definitionsFor(ctx context.Context, categoryID int32) ([]*models.AttributeDefinition, error) {
+	ancestors, err := r.store.Categories.Ancestors(ctx, categoryID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newError(codeNotFound, "category %d not found", categoryID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := r.loaders(ctx).DefinitionsByCategoryID
+	for _, c := range ancestors {
+		l.Expect(c.ID)
+	}
+
+	var defs []*models.AttributeDefinition
+	index := make(map[string]int)
+	for _, c := range append(ancestors, &models.Category{ID: categoryID}) {
+		own, err := l.Load(ctx, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range own {
+			key := strings.ToLower(d.Name)
+			if i, ok := index[key]; ok {
+				defs[i] = d
+				continue
+			}
+			index[key] = len(defs)
+			defs = append(defs, d)
+		}
+	}
+	return defs, nil
+}
+
+// definitionSet is the attrdef form of the definitions returned by
+// definitionsFor.
+func (r *Resolver) definitionSet(ctx context.Context, categoryID int32) (attrdef.Set, error) {
+	defs, err := r.definitionsFor(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	set := make([]attrdef.Definition, len(defs))
+	for i, d := range defs {
+		set[i] = definition(d)
+	}
+	return attrdef.Inherit(set), nil
+}
+
+func definition(d *models.AttributeDefinition) attrdef.Definition {
+	return attrdef.Definition{
+		Name:          d.Name,
+		Type:          attrdef.Type(d.Type),
+		AllowedValues: d.AllowedValues,
+		Required:      d.Required,
+	}
+}
+
+func attributeValues(input []models.ProductAttributeInput) []attrdef.Value {
+	values := make([]attrdef.Value, len(input))
+	for i, a := range input {
+		values[i] = attrdef.Value{Name: a.Name, Value: a.Value}
+	}
+	return values
+}
+
+// checkProductAttributes validates all of a product's attributes against
+// the definitions of its category, including that required ones are there.
+func (r *Resolver) checkProductAttributes(ctx context.Context, categoryID int32, attributes []models.ProductAttributeInput) error {
+	defs, err := r.definitionSet(ctx, categoryID)
+	if err != nil {
+		return err
+	}
+	return userError(defs.Validate(attributeValues(attributes), true))
+}
+
+// checkAttributeChange validates replacing the product attribute old with
+// input (or removing it when input is nil): the new value must be valid and
+// a required attribute must not disappear.
+func (r *Resolver) checkAttributeChange(ctx context.Context, p *models.Product, old *models.ProductAttribute, input *models.ProductAttributeInput) error {
+	defs, err := r.definitionSet(ctx, p.CategoryID)
+	if err != nil {
+		return err
+	}
+	if input != nil {
+		if err := defs.Validate(attributeValues([]models.ProductAttributeInput{*input}), false); err != nil {
+			return userError(err)
+		}
+	}
+	if old == nil || (input != nil && strings.EqualFold(input.Name, old.Name)) {
+		return nil
+	}
+	if d, ok := defs.Lookup(old.Name); !ok || !d.Required {
+		return nil
+	}
+	attributes, err := r.store.Products.Attributes(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	for _, a := range attributes {
+		if a.ID != old.ID && strings.EqualFold(a.Name, old.Name) {
+			return nil
+		}
+	}
+	return invalidAttributes(&attrdef.ValidationError{Problems: []attrdef.Problem{{Name: old.Name, Message: "is required"}}})
+}
+
+// newDefinition checks the input of an attribute definition mutation.
+func newDefinition(categoryID int32, input models.AttributeDefinitionInput) (models.AttributeDefinition, error) {
+	d := models.AttributeDefinition{
+		CategoryID: categoryID,
+		Name:       strings.TrimSpace(input.Name),
+		Type:       input.Type,
+	}
+	if input.AllowedValues != nil {
+		d.AllowedValues = *input.AllowedValues
+	}
+	if input.Required != nil {
+		d.Required = *input.Required
+	}
+	if err := definition(&d).Check(); err != nil {
+		return d, newError(codeBadUserInput, "%v", err)
+	}
+	return d, nil
+}
+
+// Defines an attribute for the products of a category and its
+// subcategories
+func (r *Resolver) CreateAttributeDefinition(ctx context.Context, args struct {
+	CategoryID graphql.ID
+	Input      models.AttributeDefinitionInput
+}) (*AttributeDefinitionResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
+	categoryID, err := parseID(args.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	def, err := newDefinition(categoryID, args.Input)
+	if err != nil {
+		return nil, err
+	}
+	d, err := r.store.Categories.CreateDefinition(ctx, def)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newError(codeNotFound, "category %d not found", categoryID)
+	}
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &AttributeDefinitionResolver{r, *d}, nil
+}
+
+func (r *Resolver) UpdateAttributeDefinition(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input models.AttributeDefinitionInput
+}) (*AttributeDefinitionResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	def, err := newDefinition(0, args.Input)
+	if err != nil {
+		return nil, err
+	}
+	d, err := r.store.Categories.UpdateDefinition(ctx, id, def)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &AttributeDefinitionResolver{r, *d}, nil
+}
+
+func (r *Resolver) DeleteAttributeDefinition(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	return r.store.Categories.DeleteDefinition(ctx, id)
+}
+
+// existingProduct loads the product a mutation works on.
+func (r *Resolver) existingProduct(ctx context.Context, id int32) (*models.Product, error) {
+	p, err := r.store.Products.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newError(codeNotFound, "product %d not found", id)
+	}
+	return p, err
+}
+
+// Adds an attribute to a product
+func (r *Resolver) AddProductAttribute(ctx context.Context, args struct {
+	ProductID graphql.ID
+	Input     models.ProductAttributeInput
+}) (*ProductAttributeResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
+	productID, err := parseID(args.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.existingProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkAttributeChange(ctx, p, nil, &args.Input); err != nil {
+		return nil, err
+	}
+	a, err := r.store.Products.AddAttribute(ctx, productID, args.Input)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &ProductAttributeResolver{*a}, nil
+}
+
+func (r *Resolver) UpdateProductAttribute(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input models.ProductAttributeInput
+}) (*ProductAttributeResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	old, err := r.store.Products.GetAttribute(ctx, id)
+	if err != nil {
+		return nil, userError(err)
+	}
+	p, err := r.existingProduct(ctx, old.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkAttributeChange(ctx, p, old, &args.Input); err != nil {
+		return nil, err
+	}
+	a, err := r.store.Products.UpdateAttribute(ctx, id, args.Input)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &ProductAttributeResolver{*a}, nil
+}
+
+// Removes an attribute from a product. Attributes the product's category
+// requires can't be removed.
+func (r *Resolver) DeleteProductAttribute(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	old, err := r.store.Products.GetAttribute(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	p, err := r.existingProduct(ctx, old.ProductID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.checkAttributeChange(ctx, p, old, nil); err != nil {
+		return false, err
+	}
+	return r.store.Products.DeleteAttribute(ctx, id)
+}
@@ -0,0 +1,632 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/pricing"
+	"go-backend/store"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// CartTTL is how long a cart is kept after its last change.
+const CartTTL = 30 * 24 * time.Hour
+
+// ReservationTTL is how long stock stays reserved for a cart in checkout.
+const ReservationTTL = 15 * time.Minute
+
+// maxCartQuantity caps the quantity of a single cart line.
+const maxCartQuantity = 999
+
+// cartFor returns the caller's cart for a cart mutation. A logged-in caller
+// gets their own cart, with the anonymous cart named by token merged into
+// it; an anonymous caller
+// gets the cart named by token. With open, a missing cart is created (for an
+// anonymous caller without a token, under a fresh token); otherwise nil is
+// returned for it.
+func (r *Resolver) cartFor(ctx context.Context, token *string, open bool) (*models.Cart, error) {
+	expiresAt := time.Now().Add(CartTTL)
+	if p := auth.FromContext(ctx); p != nil {
+		if token != nil && *token != "" {
+			return r.store.Carts.Merge(ctx, *token, p.UserID, expiresAt)
+		}
+		if open {
+			return r.store.Carts.Open(ctx, store.CartOwner{UserID: p.UserID}, expiresAt)
+		}
+		return r.findCart(ctx, store.CartOwner{UserID: p.UserID})
+	}
+
+	if token == nil || *token == "" {
+		if !open {
+			return nil, nil
+		}
+		return r.store.Carts.Open(ctx, store.CartOwner{Token: uuid.New().String()}, expiresAt)
+	}
+	if open {
+		return r.store.Carts.Open(ctx, store.CartOwner{Token: *token}, expiresAt)
+	}
+	return r.findCart(ctx, store.CartOwner{Token: *token})
+}
+
+func (r *Resolver) findCart(ctx context.Context, owner store.CartOwner) (*models.Cart, error) {
+	c, err := r.store.Carts.Get(ctx, owner)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return c, err
+}
+
+func (r *Resolver) cartResolver(ctx context.Context, c *models.Cart) (*CartResolver, error) {
+	items, err := r.store.Carts.Items(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int32, len(items))
+	var variantIDs []int32
+	for i, it := range items {
+		ids[i] = it.ProductID
+		if it.VariantID != nil {
+			variantIDs = append(variantIDs, *it.VariantID)
+		}
+	}
+	r.loaders(ctx).ProductByID.Expect(ids...)
+	r.loaders(ctx).VariantByID.Expect(variantIDs...)
+	c.Items = items
+
+	wanted := make(map[store.StockKey]int32)
+	keys := make([]store.StockKey, 0, len(items))
+	for _, it := range items {
+		key := lineKey(it)
+		if _, ok := wanted[key]; !ok {
+			keys = append(keys, key)
+		}
+		wanted[key] += it.Quantity
+	}
+	available, err := r.store.Inventory.Available(ctx, keys, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &CartResolver{r, *c, available, wanted}, nil
+}
+
+// stockItem resolves what a cart or order line buys: the product and, for
+// a product sold in variants, the chosen variant, which is then required.
+func (r *Resolver) stockItem(ctx context.Context, productID int32, variantID *int32) (*models.Product, *models.ProductVariant, error) {
+	p, err := r.store.Products.Get(ctx, productID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil, newError(codeNotFound, "product %d not found", productID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if variantID == nil {
+		variants, err := r.loaders(ctx).VariantsByProductID.Load(ctx, productID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(variants) > 0 {
+			return nil, nil, newError(codeBadUserInput, "%q is sold in variants; choose one", p.Name)
+		}
+		return p, nil, nil
+	}
+	v, err := r.store.Products.GetVariant(ctx, *variantID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && v.ProductID != productID) {
+		return nil, nil, newError(codeNotFound, "variant %d of product %d not found", *variantID, productID)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, v, nil
+}
+
+// stockKey is the inventory key of a product or one of its variants.
+func stockKey(p *models.Product, v *models.ProductVariant) store.StockKey {
+	if v == nil {
+		return store.StockKey{ProductID: p.ID}
+	}
+	return store.StockKey{ProductID: p.ID, VariantID: v.ID}
+}
+
+// lineKey is the inventory key of a cart line.
+func lineKey(it *models.CartItem) store.StockKey {
+	key := store.StockKey{ProductID: it.ProductID}
+	if it.VariantID != nil {
+		key.VariantID = *it.VariantID
+	}
+	return key
+}
+
+// itemName names a product or one of its variants in error messages.
+func itemName(p *models.Product, v *models.ProductVariant) string {
+	if v == nil {
+		return p.Name
+	}
+	return p.Name + " (" + v.SKU + ")"
+}
+
+// checkQuantity validates the total quantity of a product or variant
+// wanted in a cart against what isn't reserved by other carts.
+func (r *Resolver) checkQuantity(ctx context.Context, cartID int32, p *models.Product, v *models.ProductVariant, quantity int32) error {
+	if quantity > maxCartQuantity {
+		return newError(codeBadUserInput, "at most %d of an item can be added to the cart", maxCartQuantity)
+	}
+	key := stockKey(p, v)
+	available, err := r.store.Inventory.Available(ctx, []store.StockKey{key}, cartID)
+	if err != nil {
+		return err
+	}
+	if n := max(available[key], 0); quantity > n {
+		return newError(codeBadUserInput, "only %d of %q in stock", n, itemName(p, v))
+	}
+	return nil
+}
+
+// Resolves the caller's cart, or null if they don't have one yet. Reading
+// it never merges carts: a logged-in caller's anonymous cart is merged at
+// login or by their next cart mutation.
+func (r *Resolver) Cart(ctx context.Context, args struct{ CartToken *string }) (*CartResolver, error) {
+	var owner store.CartOwner
+	if p := auth.FromContext(ctx); p != nil {
+		owner.UserID = p.UserID
+	} else if args.CartToken != nil && *args.CartToken != "" {
+		owner.Token = *args.CartToken
+	} else {
+		return nil, nil
+	}
+	c, err := r.findCart(ctx, owner)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	return r.cartResolver(ctx, c)
+}
+
+func (r *Resolver) AddToCart(ctx context.Context, args struct {
+	ProductID      graphql.ID
+	VariantID      *graphql.ID
+	Customizations *[]models.CustomizationInput
+	Quantity       int32
+	CartToken      *string
+}) (*CartResolver, error) {
+	productID, err := parseID(args.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	variantID, err := parseOptionalID(args.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	if args.Quantity <= 0 {
+		return nil, newError(codeBadUserInput, "quantity must be positive")
+	}
+	p, v, err := r.stockItem(ctx, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	key := stockKey(p, v)
+	customizations, extra, err := r.customize(ctx, productID, args.Customizations)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := r.cartFor(ctx, args.CartToken, true)
+	if err != nil {
+		return nil, err
+	}
+	items, err := r.store.Carts.Items(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	quantity := args.Quantity
+	for _, it := range items {
+		if lineKey(it) == key {
+			quantity += it.Quantity
+		}
+	}
+	if err := r.checkQuantity(ctx, c.ID, p, v, quantity); err != nil {
+		return nil, err
+	}
+
+	price := p.Price
+	if v != nil {
+		price = v.Price
+	}
+	line := store.NewCartItem{
+		StockKey:       key,
+		Customizations: customizations,
+		Quantity:       args.Quantity,
+		Price:          (pricing.FromFloat(price) + extra).Float(),
+	}
+	if _, err := r.store.Carts.AddItem(ctx, c.ID, line, time.Now().Add(CartTTL)); err != nil {
+		return nil, err
+	}
+	return r.cartResolver(ctx, c)
+}
+
+// Sets the quantity of a cart line; zero removes it
+func (r *Resolver) UpdateCartItem(ctx context.Context, args struct {
+	ItemID    graphql.ID
+	Quantity  int32
+	CartToken *string
+}) (*CartResolver, error) {
+	itemID, err := parseID(args.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if args.Quantity < 0 {
+		return nil, newError(codeBadUserInput, "quantity must not be negative")
+	}
+	c, err := r.cartFor(ctx, args.CartToken, false)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, newError(codeNotFound, "cart not found")
+	}
+	if args.Quantity == 0 {
+		return r.removeCartItem(ctx, c, itemID)
+	}
+
+	items, err := r.store.Carts.Items(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	var line *models.CartItem
+	for _, it := range items {
+		if it.ID == itemID {
+			line = it
+		}
+	}
+	if line == nil {
+		return nil, newError(codeNotFound, "cart item %d not found", itemID)
+	}
+	// Lines differing only in customizations draw on the same stock.
+	quantity := args.Quantity
+	for _, it := range items {
+		if it.ID != itemID && lineKey(it) == lineKey(line) {
+			quantity += it.Quantity
+		}
+	}
+	p, err := r.store.Products.Get(ctx, line.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	var v *models.ProductVariant
+	if line.VariantID != nil {
+		if v, err = r.store.Products.GetVariant(ctx, *line.VariantID); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.checkQuantity(ctx, c.ID, p, v, quantity); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.store.Carts.UpdateItem(ctx, c.ID, itemID, args.Quantity, time.Now().Add(CartTTL)); err != nil {
+		return nil, userError(err)
+	}
+	return r.cartResolver(ctx, c)
+}
+
+func (r *Resolver) RemoveFromCart(ctx context.Context, args struct {
+	ItemID    graphql.ID
+	CartToken *string
+}) (*CartResolver, error) {
+	itemID, err := parseID(args.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.cartFor(ctx, args.CartToken, false)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, newError(codeNotFound, "cart not found")
+	}
+	return r.removeCartItem(ctx, c, itemID)
+}
+
+func (r *Resolver) removeCartItem(ctx context.Context, c *models.Cart, itemID int32) (*CartResolver, error) {
+	ok, err := r.store.Carts.RemoveItem(ctx, c.ID, itemID, time.Now().Add(CartTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, newError(codeNotFound, "cart item %d not found", itemID)
+	}
+	return r.cartResolver(ctx, c)
+}
+
+func (r *Resolver) ClearCart(ctx context.Context, args struct{ CartToken *string }) (*CartResolver, error) {
+	c, err := r.cartFor(ctx, args.CartToken, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.store.Carts.Clear(ctx, c.ID, time.Now().Add(CartTTL)); err != nil {
+		return nil, err
+	}
+	return r.cartResolver(ctx, c)
+}
+
+// Reserves stock for the cart's lines while the caller checks out,
+// replacing any earlier reservation
+func (r *Resolver) ReserveCart(ctx context.Context, args struct{ CartToken *string }) (*CartResolver, error) {
+	c, err := r.cartFor(ctx, args.CartToken, false)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, newError(codeNotFound, "cart not found")
+	}
+	items, err := r.store.Carts.Items(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, newError(codeBadUserInput, "cart is empty")
+	}
+	if _, err := r.store.Inventory.Reserve(ctx, c.ID, time.Now().Add(ReservationTTL)); err != nil {
+		return nil, userError(err)
+	}
+	return r.cartResolver(ctx, c)
+}
+
+// Gives up the cart's reservation, e.g. when the caller leaves checkout
+func (r *Resolver) ReleaseCart(ctx context.Context, args struct{ CartToken *string }) (*CartResolver, error) {
+	c, err := r.cartFor(ctx, args.CartToken, false)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, newError(codeNotFound, "cart not found")
+	}
+	if err := r.store.Inventory.Release(ctx, c.ID); err != nil {
+		return nil, err
+	}
+	return r.cartResolver(ctx, c)
+}
+
+// CartResolver resolves the Cart type. Prices and availability are read
+// from the current product rows on every request, so the cart always
+// reflects what checkout would charge.
+type CartResolver struct {
+	root *Resolver
+	c    models.Cart
+	// available is how much of each product or variant in the cart can be
+	// ordered through it, and wanted how much its lines add up to.
+	available map[store.StockKey]int32
+	wanted    map[store.StockKey]int32
+}
+
+func (r *CartResolver) ID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.c.ID))
+}
+
+func (r *CartResolver) Token() *string {
+	if r.c.Token == "" {
+		return nil
+	}
+	return &r.c.Token
+}
+
+func (r *CartResolver) Items() []*CartItemResolver {
+	items := make([]*CartItemResolver, len(r.c.Items))
+	for i, it := range r.c.Items {
+		key := lineKey(it)
+		items[i] = &CartItemResolver{r.root, *it, r.available[key], r.wanted[key]}
+	}
+	return items
+}
+
+func (r *CartResolver) TotalQuantity() int32 {
+	var n int32
+	for _, it := range r.c.Items {
+		n += it.Quantity
+	}
+	return n
+}
+
+func (r *CartResolver) Subtotal(ctx context.Context) (float64, error) {
+	var total pricing.Cents
+	for _, it := range r.Items() {
+		line, err := it.lineTotal(ctx)
+		if err != nil {
+			return 0, err
+		}
+		total += line
+	}
+	return total.Float(), nil
+}
+
+// Valid is false when any line changed price or can't be supplied.
+func (r *CartResolver) Valid(ctx context.Context) (bool, error) {
+	for _, it := range r.Items() {
+		changed, err := it.PriceChanged(ctx)
+		if err != nil {
+			return false, err
+		}
+		if changed || !it.InStock() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ReservedUntil is when the cart's stock reservation lapses, or null
+// without one.
+func (r *CartResolver) ReservedUntil(ctx context.Context) (*string, error) {
+	reservations, err := r.root.store.Inventory.Reservations(ctx, r.c.ID)
+	if err != nil || len(reservations) == 0 {
+		return nil, err
+	}
+	until := reservations[0].ExpiresAt
+	for _, res := range reservations[1:] {
+		if res.ExpiresAt.Before(until) {
+			until = res.ExpiresAt
+		}
+	}
+	s := until.UTC().Format(time.RFC3339)
+	return &s, nil
+}
+
+func (r *CartResolver) UpdatedAt() string {
+	return r.c.UpdatedAt
+}
+
+func (r *CartResolver) ExpiresAt() string {
+	return r.c.ExpiresAt
+}
+
+// CartItemResolver resolves the CartItem type
+type CartItemResolver struct {
+	root *Resolver
+	it   models.CartItem
+	// available is how much of the line's product or variant the cart can
+	// order, and wanted how much of it the cart's lines hold together.
+	available int32
+	wanted    int32
+}
+
+func (r *CartItemResolver) product(ctx context.Context) (*models.Product, error) {
+	p, err := r.root.loaders(ctx).ProductByID.Load(ctx, r.it.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("product %d not found", r.it.ProductID)
+	}
+	return p, nil
+}
+
+func (r *CartItemResolver) ID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.it.ID))
+}
+
+func (r *CartItemResolver) Product(ctx context.Context) (*ProductResolver, error) {
+	p, err := r.product(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ProductResolver{r.root, *p}, nil
+}
+
+func (r *CartItemResolver) Quantity() int32 {
+	return r.it.Quantity
+}
+
+func (r *CartItemResolver) Variant(ctx context.Context) (*ProductVariantResolver, error) {
+	v, err := r.variant(ctx)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return &ProductVariantResolver{r.root, *v}, nil
+}
+
+// variant returns the line's variant, or nil for a product without
+// variants.
+func (r *CartItemResolver) variant(ctx context.Context) (*models.ProductVariant, error) {
+	if r.it.VariantID == nil {
+		return nil, nil
+	}
+	v, err := r.root.loaders(ctx).VariantByID.Load(ctx, *r.it.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("variant %d not found", *r.it.VariantID)
+	}
+	return v, nil
+}
+
+func (r *CartItemResolver) Customizations() []*CustomizationResolver {
+	return customizationResolvers(r.it.Customizations)
+}
+
+// price returns the current unit price of what the line buys. The
+// customizations are repriced at the options' current price modifiers;
+// those whose option is gone keep their price until checkout refuses them.
+func (r *CartItemResolver) price(ctx context.Context) (pricing.Cents, error) {
+	v, err := r.variant(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var base pricing.Cents
+	if v != nil {
+		base = pricing.FromFloat(v.Price)
+	} else {
+		p, err := r.product(ctx)
+		if err != nil {
+			return 0, err
+		}
+		base = pricing.FromFloat(p.Price)
+	}
+	if len(r.it.Customizations) == 0 {
+		return base, nil
+	}
+
+	options, err := r.root.loaders(ctx).OptionsByProductID.Load(ctx, r.it.ProductID)
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[int32]*models.CustomizationOption, len(options))
+	for _, o := range options {
+		byID[o.ID] = o
+	}
+	for _, c := range r.it.Customizations {
+		if o, ok := byID[c.OptionID]; ok {
+			base += customizationOption(o).PriceOf(customizationChoice(c))
+		} else {
+			base += pricing.FromFloat(c.Price)
+		}
+	}
+	return base, nil
+}
+
+func (r *CartItemResolver) UnitPrice(ctx context.Context) (float64, error) {
+	price, err := r.price(ctx)
+	return price.Float(), err
+}
+
+func (r *CartItemResolver) PriceAtAdd() float64 {
+	return r.it.PriceAtAdd
+}
+
+func (r *CartItemResolver) PriceChanged(ctx context.Context) (bool, error) {
+	price, err := r.price(ctx)
+	if err != nil {
+		return false, err
+	}
+	return price != pricing.FromFloat(r.it.PriceAtAdd), nil
+}
+
+// AvailableQuantity is the stock the cart can order: what isn't held by
+// other carts' reservations or already taken by orders.
+func (r *CartItemResolver) AvailableQuantity() int32 {
+	return max(r.available, 0)
+}
+
+// InStock is whether the stock the cart can order covers all its lines of
+// the product or variant, including those with other customizations.
+func (r *CartItemResolver) InStock() bool {
+	return r.available >= r.wanted
+}
+
+func (r *CartItemResolver) LineTotal(ctx context.Context) (float64, error) {
+	total, err := r.lineTotal(ctx)
+	return total.Float(), err
+}
+
+func (r *CartItemResolver) lineTotal(ctx context.Context) (pricing.Cents, error) {
+	price, err := r.price(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return price * pricing.Cents(r.it.Quantity), nil
+}
+
+func (r *CartItemResolver) AddedAt() string {
+	return r.it.AddedAt
+}
@@ -0,0 +1,99 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-backend/models"
+	"go-backend/payments"
+	"go-backend/pubsub"
+	"go-backend/store"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+func newTestResolver(t *testing.T) (*Resolver, *store.Store) {
+	t.Helper()
+	st := store.NewMemory()
+	return NewResolver(st, payments.NewProcessor(payments.NewFake([]byte("secret")), st.Orders, st.Payments), nil, pubsub.NewMemory()), st
+}
+
+type addToCartArgs = struct {
+	ProductID      graphql.ID
+	VariantID      *graphql.ID
+	Customizations *[]models.CustomizationInput
+	Quantity       int32
+	CartToken      *string
+}
+
+func TestCartAvailability(t *testing.T) {
+	tests := []struct {
+		name        string
+		stock       int32
+		price       float64
+		reserved    int32 // afterwards, by another cart in checkout
+		quantities  []int32
+		wantAvail   int32
+		wantInStock bool
+		wantTotal   float64
+	}{
+		{name: "in stock", stock: 5, price: 2.5, quantities: []int32{2}, wantAvail: 5, wantInStock: true, wantTotal: 5},
+		{name: "less another cart's reservation", stock: 5, price: 2.5, reserved: 3, quantities: []int32{2}, wantAvail: 2, wantInStock: true, wantTotal: 5},
+		{name: "reserved away", stock: 5, price: 2.5, reserved: 4, quantities: []int32{2}, wantAvail: 1, wantInStock: false, wantTotal: 5},
+		{name: "subtotal adds up in cents", stock: 10, price: 0.1, quantities: []int32{1, 2}, wantAvail: 10, wantInStock: true, wantTotal: 0.3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r, st := newTestResolver(t)
+			cat, err := st.Categories.Create(ctx, "Mugs", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := st.Products.Create(ctx, models.ProductInput{Name: "Mug", Price: tt.price, StockQuantity: tt.stock, CategoryID: cat.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := graphql.ID(fmt.Sprint(p.ID))
+
+			var token *string
+			for _, quantity := range tt.quantities {
+				c, err := r.AddToCart(ctx, addToCartArgs{ProductID: id, Quantity: quantity, CartToken: token})
+				if err != nil {
+					t.Fatal(err)
+				}
+				token = c.Token()
+			}
+			if tt.reserved > 0 {
+				other, err := r.AddToCart(ctx, addToCartArgs{ProductID: id, Quantity: tt.reserved})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := r.ReserveCart(ctx, struct{ CartToken *string }{other.Token()}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c, err := r.Cart(ctx, struct{ CartToken *string }{token})
+			if err != nil {
+				t.Fatal(err)
+			}
+			total, err := c.Subtotal(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("subtotal = %v, want %v", total, tt.wantTotal)
+			}
+			for _, it := range c.Items() {
+				if got := it.AvailableQuantity(); got != tt.wantAvail {
+					t.Errorf("availableQuantity = %d, want %d", got, tt.wantAvail)
+				}
+				if got := it.InStock(); got != tt.wantInStock {
+					t.Errorf("inStock = %v, want %v", got, tt.wantInStock)
+				}
+			}
+		})
+	}
+}
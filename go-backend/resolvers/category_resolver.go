@@ -3,14 +3,17 @@ package resolvers
 import (
 	"context"
 	"fmt"
-	"go-backend/db"
+	"go-backend/auth"
 	"go-backend/models"
+	"go-backend/store"
+	"strings"
 
 	"github.com/graph-gophers/graphql-go"
 )
 
 type CategoryResolver struct {
-	c models.Category
+	root *Resolver
+	c    models.Category
 }
 
 func (r *CategoryResolver) ID() graphql.ID {
@@ -25,24 +28,152 @@ func (r *CategoryResolver) ParentCategory(ctx context.Context) (*CategoryResolve
 	if r.c.ParentCategory == nil {
 		return nil, nil
 	}
-	return &CategoryResolver{*r.c.ParentCategory}, nil
+	parent, err := r.root.loaders(ctx).CategoryByID.Load(ctx, r.c.ParentCategory.ID)
+	if err != nil || parent == nil {
+		return nil, err
+	}
+	return &CategoryResolver{r.root, *parent}, nil
+}
+
+func (r *CategoryResolver) Children(ctx context.Context) ([]*CategoryResolver, error) {
+	children, err := r.root.loaders(ctx).ChildrenByCategoryID.Load(ctx, r.c.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.root.categoryResolvers(children), nil
+}
+
+func (r *CategoryResolver) Ancestors(ctx context.Context) ([]*CategoryResolver, error) {
+	ancestors, err := r.root.store.Categories.Ancestors(ctx, r.c.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.root.categoryResolvers(ancestors), nil
+}
+
+func (r *CategoryResolver) Descendants(ctx context.Context) ([]*CategoryResolver, error) {
+	descendants, err := r.root.store.Categories.Descendants(ctx, r.c.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.root.categoryResolvers(descendants), nil
+}
+
+func (r *CategoryResolver) ProductCount(ctx context.Context) (int32, error) {
+	return r.root.loaders(ctx).ProductCountByCategoryID.Load(ctx, r.c.ID)
+}
+
+// AttributeDefinitions are the definitions applying to the category's
+// products, including those inherited from its ancestors.
+func (r *CategoryResolver) AttributeDefinitions(ctx context.Context) ([]*AttributeDefinitionResolver, error) {
+	defs, err := r.root.definitionsFor(ctx, r.c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*AttributeDefinitionResolver, len(defs))
+	for i, d := range defs {
+		resolvers[i] = &AttributeDefinitionResolver{r.root, *d}
+	}
+	return resolvers, nil
 }
 
 func (r *CategoryResolver) Products(ctx context.Context) ([]*ProductResolver, error) {
-	rows, err := db.DB.Query("SELECT id, name, description, price, stock_quantity FROM products WHERE category_id = $1", r.c.ID)
+	products, err := r.root.store.Products.List(ctx, store.ProductFilter{CategoryID: &r.c.ID})
+	if err != nil {
+		return nil, err
+	}
+	return r.root.productResolvers(ctx, products), nil
+}
+
+func (r *CategoryResolver) ProductsConnection(ctx context.Context, args connectionArgs) (*ProductConnectionResolver, error) {
+	pageArgs, err := args.pageArgs()
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.root.store.Products.Page(ctx, store.ProductFilter{CategoryID: &r.c.ID}, pageArgs)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return newProductConnection(ctx, r.root, page), nil
+}
 
-	var products []*ProductResolver
-	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity); err != nil {
-			return nil, err
-		}
-		products = append(products, &ProductResolver{p})
+func (r *Resolver) categoryResolvers(categories []*models.Category) []*CategoryResolver {
+	resolvers := make([]*CategoryResolver, len(categories))
+	for i, c := range categories {
+		resolvers[i] = &CategoryResolver{r, *c}
 	}
+	return resolvers
+}
 
-	return products, nil
+// Renames a category
+func (r *Resolver) UpdateCategory(ctx context.Context, args struct {
+	ID   graphql.ID
+	Name string
+}) (*CategoryResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Name) == "" {
+		return nil, newError(codeBadUserInput, "name must not be empty")
+	}
+	c, err := r.store.Categories.Update(ctx, id, args.Name)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &CategoryResolver{r, *c}, nil
+}
+
+// Moves a category under another one, or to the top level when parentId is
+// null
+func (r *Resolver) MoveCategory(ctx context.Context, args struct {
+	ID       graphql.ID
+	ParentID *graphql.ID
+}) (*CategoryResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	parentID, err := parseOptionalID(args.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.store.Categories.Move(ctx, id, parentID)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &CategoryResolver{r, *c}, nil
+}
+
+// Deletes a category, moving its products to reassignProductsTo
+func (r *Resolver) DeleteCategory(ctx context.Context, args struct {
+	ID                 graphql.ID
+	ReassignProductsTo *graphql.ID
+}) (bool, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	reassignTo, err := parseOptionalID(args.ReassignProductsTo)
+	if err != nil {
+		return false, err
+	}
+	if reassignTo != nil && *reassignTo == id {
+		return false, newError(codeBadUserInput, "can't reassign products to the category being deleted")
+	}
+	deleted, err := r.store.Categories.Delete(ctx, id, reassignTo)
+	if err != nil {
+		return false, userError(err)
+	}
+	return deleted, nil
 }
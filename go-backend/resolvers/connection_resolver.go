@@ -0,0 +1,219 @@
+package resolvers
+
+import (
+	"context"
+	"go-backend/models"
+	"go-backend/store"
+)
+
+// connectionArgs are the Relay pagination arguments shared by every
+// connection field.
+type connectionArgs struct {
+	First  *int32
+	After  *string
+	Last   *int32
+	Before *string
+}
+
+func (a connectionArgs) pageArgs() (store.PageArgs, error) {
+	page := store.PageArgs{First: a.First, Last: a.Last}
+	if a.After != nil {
+		c, err := store.DecodeCursor(*a.After)
+		if err != nil {
+			return page, err
+		}
+		page.After = c
+	}
+	if a.Before != nil {
+		c, err := store.DecodeCursor(*a.Before)
+		if err != nil {
+			return page, err
+		}
+		page.Before = c
+	}
+	return page, nil
+}
+
+// PageInfoResolver resolves the PageInfo type
+type PageInfoResolver struct {
+	hasNext, hasPrev bool
+	start, end       *string
+}
+
+func newPageInfo[T any](page *store.Page[T]) *PageInfoResolver {
+	info := &PageInfoResolver{hasNext: page.HasNextPage, hasPrev: page.HasPreviousPage}
+	if n := len(page.Edges); n > 0 {
+		start, end := page.Edges[0].Cursor.Encode(), page.Edges[n-1].Cursor.Encode()
+		info.start, info.end = &start, &end
+	}
+	return info
+}
+
+func (r *PageInfoResolver) HasNextPage() bool {
+	return r.hasNext
+}
+
+func (r *PageInfoResolver) HasPreviousPage() bool {
+	return r.hasPrev
+}
+
+func (r *PageInfoResolver) StartCursor() *string {
+	return r.start
+}
+
+func (r *PageInfoResolver) EndCursor() *string {
+	return r.end
+}
+
+// ProductConnectionResolver resolves the ProductConnection type
+type ProductConnectionResolver struct {
+	root *Resolver
+	page *store.Page[models.Product]
+}
+
+func newProductConnection(ctx context.Context, root *Resolver, page *store.Page[models.Product]) *ProductConnectionResolver {
+	products := make([]*models.Product, len(page.Edges))
+	for i, e := range page.Edges {
+		products[i] = e.Node
+	}
+	root.loaders(ctx).ExpectProducts(products)
+	return &ProductConnectionResolver{root, page}
+}
+
+func (r *ProductConnectionResolver) Edges() []*ProductEdgeResolver {
+	edges := make([]*ProductEdgeResolver, len(r.page.Edges))
+	for i, e := range r.page.Edges {
+		edges[i] = &ProductEdgeResolver{e.Cursor.Encode(), &ProductResolver{r.root, *e.Node}}
+	}
+	return edges
+}
+
+func (r *ProductConnectionResolver) PageInfo() *PageInfoResolver {
+	return newPageInfo(r.page)
+}
+
+func (r *ProductConnectionResolver) TotalCount() int32 {
+	return int32(r.page.TotalCount)
+}
+
+// ProductEdgeResolver resolves the ProductEdge type
+type ProductEdgeResolver struct {
+	cursor string
+	node   *ProductResolver
+}
+
+func (r *ProductEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+func (r *ProductEdgeResolver) Node() *ProductResolver {
+	return r.node
+}
+
+// OrderConnectionResolver resolves the OrderConnection type
+type OrderConnectionResolver struct {
+	root *Resolver
+	page *store.Page[models.Order]
+}
+
+func (r *OrderConnectionResolver) Edges() []*OrderEdgeResolver {
+	edges := make([]*OrderEdgeResolver, len(r.page.Edges))
+	for i, e := range r.page.Edges {
+		edges[i] = &OrderEdgeResolver{e.Cursor.Encode(), &OrderResolver{r.root, *e.Node}}
+	}
+	return edges
+}
+
+func (r *OrderConnectionResolver) PageInfo() *PageInfoResolver {
+	return newPageInfo(r.page)
+}
+
+func (r *OrderConnectionResolver) TotalCount() int32 {
+	return int32(r.page.TotalCount)
+}
+
+// OrderEdgeResolver resolves the OrderEdge type
+type OrderEdgeResolver struct {
+	cursor string
+	node   *OrderResolver
+}
+
+func (r *OrderEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+func (r *OrderEdgeResolver) Node() *OrderResolver {
+	return r.node
+}
+
+// ReviewConnectionResolver resolves the ReviewConnection type
+type ReviewConnectionResolver struct {
+	root *Resolver
+	page *store.Page[models.Review]
+}
+
+func (r *ReviewConnectionResolver) Edges() []*ReviewEdgeResolver {
+	edges := make([]*ReviewEdgeResolver, len(r.page.Edges))
+	for i, e := range r.page.Edges {
+		edges[i] = &ReviewEdgeResolver{e.Cursor.Encode(), &ReviewResolver{r.root, *e.Node}}
+	}
+	return edges
+}
+
+func (r *ReviewConnectionResolver) PageInfo() *PageInfoResolver {
+	return newPageInfo(r.page)
+}
+
+func (r *ReviewConnectionResolver) TotalCount() int32 {
+	return int32(r.page.TotalCount)
+}
+
+// ReviewEdgeResolver resolves the ReviewEdge type
+type ReviewEdgeResolver struct {
+	cursor string
+	node   *ReviewResolver
+}
+
+func (r *ReviewEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+func (r *ReviewEdgeResolver) Node() *ReviewResolver {
+	return r.node
+}
+
+// ProductSearchConnectionResolver resolves the ProductSearchConnection type
+type ProductSearchConnectionResolver struct {
+	root *Resolver
+	page *store.Page[models.SearchHit]
+}
+
+func (r *ProductSearchConnectionResolver) Edges() []*ProductSearchEdgeResolver {
+	edges := make([]*ProductSearchEdgeResolver, len(r.page.Edges))
+	for i, e := range r.page.Edges {
+		edges[i] = &ProductSearchEdgeResolver{e.Cursor.Encode(), &ProductSearchHitResolver{r.root, *e.Node}}
+	}
+	return edges
+}
+
+func (r *ProductSearchConnectionResolver) PageInfo() *PageInfoResolver {
+	return newPageInfo(r.page)
+}
+
+func (r *ProductSearchConnectionResolver) TotalCount() int32 {
+	return int32(r.page.TotalCount)
+}
+
+// ProductSearchEdgeResolver resolves the ProductSearchEdge type
+type ProductSearchEdgeResolver struct {
+	cursor string
+	node   *ProductSearchHitResolver
+}
+
+func (r *ProductSearchEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+func (r *ProductSearchEdgeResolver) Node() *ProductSearchHitResolver {
+	return r.node
+}
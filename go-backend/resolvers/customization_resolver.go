@@ -0,0 +1,321 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-backend/auth"
+	"go-backend/customization"
+	"go-backend/models"
+	"go-backend/pricing"
+	"go-backend/store"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// CustomizationOptionResolver resolves the CustomizationOption type
+type CustomizationOptionResolver struct {
+	root *Resolver
+	o    models.CustomizationOption
+}
+
+func (r *CustomizationOptionResolver) ID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.o.ID))
+}
+
+func (r *CustomizationOptionResolver) Product(ctx context.Context) (*ProductResolver, error) {
+	p, err := r.root.loaders(ctx).ProductByID.Load(ctx, r.o.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("product %d not found", r.o.ProductID)
+	}
+	return &ProductResolver{r.root, *p}, nil
+}
+
+func (r *CustomizationOptionResolver) Name() string {
+	return r.o.Name
+}
+
+func (r *CustomizationOptionResolver) Kind() string {
+	return r.o.Kind
+}
+
+func (r *CustomizationOptionResolver) Required() bool {
+	return r.o.Required
+}
+
+func (r *CustomizationOptionResolver) MaxLength() *int32 {
+	if r.o.MaxLength == 0 {
+		return nil
+	}
+	return &r.o.MaxLength
+}
+
+func (r *CustomizationOptionResolver) AllowedValues() []string {
+	if r.o.AllowedValues == nil {
+		return []string{}
+	}
+	return r.o.AllowedValues
+}
+
+func (r *CustomizationOptionResolver) MinWidth() *float64 {
+	return nonZero(r.o.MinWidth)
+}
+
+func (r *CustomizationOptionResolver) MaxWidth() *float64 {
+	return nonZero(r.o.MaxWidth)
+}
+
+func (r *CustomizationOptionResolver) MinHeight() *float64 {
+	return nonZero(r.o.MinHeight)
+}
+
+func (r *CustomizationOptionResolver) MaxHeight() *float64 {
+	return nonZero(r.o.MaxHeight)
+}
+
+func (r *CustomizationOptionResolver) Price() float64 {
+	return r.o.Price
+}
+
+func (r *CustomizationOptionResolver) UnitPrice() float64 {
+	return r.o.UnitPrice
+}
+
+func nonZero(f float64) *float64 {
+	if f == 0 {
+		return nil
+	}
+	return &f
+}
+
+// CustomizationResolver resolves the Customization type
+type CustomizationResolver struct {
+	c models.Customization
+}
+
+func (r *CustomizationResolver) OptionID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.c.OptionID))
+}
+
+func (r *CustomizationResolver) Name() string {
+	return r.c.Name
+}
+
+func (r *CustomizationResolver) Kind() string {
+	return r.c.Kind
+}
+
+func (r *CustomizationResolver) Value() *string {
+	if r.c.Value == "" {
+		return nil
+	}
+	return &r.c.Value
+}
+
+func (r *CustomizationResolver) Width() *float64 {
+	return nonZero(r.c.Width)
+}
+
+func (r *CustomizationResolver) Height() *float64 {
+	return nonZero(r.c.Height)
+}
+
+func (r *CustomizationResolver) Price() float64 {
+	return r.c.Price
+}
+
+func customizationResolvers(cs []models.Customization) []*CustomizationResolver {
+	resolvers := make([]*CustomizationResolver, len(cs))
+	for i, c := range cs {
+		resolvers[i] = &CustomizationResolver{c}
+	}
+	return resolvers
+}
+
+func customizationOption(o *models.CustomizationOption) customization.Option {
+	return customization.Option{
+		ID:            o.ID,
+		Name:          o.Name,
+		Kind:          customization.Kind(o.Kind),
+		Required:      o.Required,
+		MaxLength:     o.MaxLength,
+		AllowedValues: o.AllowedValues,
+		MinWidth:      o.MinWidth,
+		MaxWidth:      o.MaxWidth,
+		MinHeight:     o.MinHeight,
+		MaxHeight:     o.MaxHeight,
+		Price:         pricing.FromFloat(o.Price),
+		UnitPrice:     pricing.FromFloat(o.UnitPrice),
+	}
+}
+
+func customizationChoice(c models.Customization) customization.Choice {
+	return customization.Choice{OptionID: c.OptionID, Value: c.Value, Width: c.Width, Height: c.Height}
+}
+
+// customize validates a buyer's choices against the options offered on a
+// product, returning them in the form kept on cart lines and order items
+// and what they add to the unit price.
+func (r *Resolver) customize(ctx context.Context, productID int32, input *[]models.CustomizationInput) ([]models.Customization, pricing.Cents, error) {
+	stored, err := r.loaders(ctx).OptionsByProductID.Load(ctx, productID)
+	if err != nil {
+		return nil, 0, err
+	}
+	options := make([]customization.Option, len(stored))
+	for i, o := range stored {
+		options[i] = customizationOption(o)
+	}
+
+	var choices []customization.Choice
+	if input != nil {
+		choices = make([]customization.Choice, len(*input))
+		for i, in := range *input {
+			c := customization.Choice{OptionID: in.OptionID}
+			if in.Value != nil {
+				c.Value = *in.Value
+			}
+			if in.Width != nil {
+				c.Width = *in.Width
+			}
+			if in.Height != nil {
+				c.Height = *in.Height
+			}
+			choices[i] = c
+		}
+	}
+
+	priced, err := customization.Apply(options, choices)
+	if err != nil {
+		return nil, 0, userError(err)
+	}
+	customizations := make([]models.Customization, len(priced))
+	for i, p := range priced {
+		customizations[i] = models.Customization{
+			OptionID: p.Option.ID,
+			Name:     p.Option.Name,
+			Kind:     string(p.Option.Kind),
+			Value:    p.Choice.Value,
+			Width:    p.Choice.Width,
+			Height:   p.Choice.Height,
+			Price:    p.Price.Float(),
+		}
+	}
+	return customizations, customization.Total(priced), nil
+}
+
+// customizationInputs turns the customizations of a cart line back into
+// input, so they are checked and priced afresh when the cart is ordered.
+func customizationInputs(cs []models.Customization) *[]models.CustomizationInput {
+	input := make([]models.CustomizationInput, len(cs))
+	for i, c := range cs {
+		input[i] = models.CustomizationInput{OptionID: c.OptionID}
+		if c.Value != "" {
+			input[i].Value = &c.Value
+		}
+		if c.Width != 0 || c.Height != 0 {
+			input[i].Width, input[i].Height = &c.Width, &c.Height
+		}
+	}
+	return &input
+}
+
+// newCustomizationOption checks the input of a customization option
+// mutation.
+func newCustomizationOption(productID int32, input models.CustomizationOptionInput) (models.CustomizationOption, error) {
+	o := models.CustomizationOption{
+		ProductID: productID,
+		Name:      strings.TrimSpace(input.Name),
+		Kind:      input.Kind,
+	}
+	if input.Required != nil {
+		o.Required = *input.Required
+	}
+	if input.MaxLength != nil {
+		o.MaxLength = *input.MaxLength
+	}
+	if input.AllowedValues != nil {
+		for _, v := range *input.AllowedValues {
+			c, _ := customization.NormalizeColour(v)
+			o.AllowedValues = append(o.AllowedValues, c)
+		}
+	}
+	for _, f := range []struct {
+		in  *float64
+		out *float64
+	}{
+		{input.MinWidth, &o.MinWidth}, {input.MaxWidth, &o.MaxWidth},
+		{input.MinHeight, &o.MinHeight}, {input.MaxHeight, &o.MaxHeight},
+		{input.Price, &o.Price}, {input.UnitPrice, &o.UnitPrice},
+	} {
+		if f.in != nil {
+			*f.out = *f.in
+		}
+	}
+	if err := customizationOption(&o).Check(); err != nil {
+		return o, newError(codeBadUserInput, "%v", err)
+	}
+	return o, nil
+}
+
+// Offers a customisation, such as an engraving, on a product
+func (r *Resolver) CreateCustomizationOption(ctx context.Context, args struct {
+	ProductID graphql.ID
+	Input     models.CustomizationOptionInput
+}) (*CustomizationOptionResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
+	productID, err := parseID(args.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	opt, err := newCustomizationOption(productID, args.Input)
+	if err != nil {
+		return nil, err
+	}
+	o, err := r.store.Products.CreateCustomizationOption(ctx, opt)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newError(codeNotFound, "product %d not found", productID)
+	}
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &CustomizationOptionResolver{r, *o}, nil
+}
+
+func (r *Resolver) UpdateCustomizationOption(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input models.CustomizationOptionInput
+}) (*CustomizationOptionResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	opt, err := newCustomizationOption(0, args.Input)
+	if err != nil {
+		return nil, err
+	}
+	o, err := r.store.Products.UpdateCustomizationOption(ctx, id, opt)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &CustomizationOptionResolver{r, *o}, nil
+}
+
+func (r *Resolver) DeleteCustomizationOption(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	return r.store.Products.DeleteCustomizationOption(ctx, id)
+}
@@ -0,0 +1,194 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-backend/attrdef"
+	"go-backend/auth"
+	"go-backend/customization"
+	"go-backend/orderstatus"
+	"go-backend/payments"
+	"go-backend/store"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Error codes reported in the extensions.code field of GraphQL errors.
+const (
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeForbidden       = "FORBIDDEN"
+	// codeBadUserInput errors for invalid product attributes list them in
+	// extensions.attributes as {name, message}, and those for invalid
+	// customizations in extensions.customizations as {option, message}.
+	codeBadUserInput  = "BAD_USER_INPUT"
+	codeNotFound      = "NOT_FOUND"
+	codeConflict      = "CONFLICT"
+	codeTotalMismatch = "TOTAL_MISMATCH"
+	// codeInsufficientStock errors list the short products in
+	// extensions.products as {productId, variantId, requested, available},
+	// variantId only for variants.
+	codeInsufficientStock = "INSUFFICIENT_STOCK"
+	// codePaymentDeclined errors name the cancelled order in
+	// extensions.orderId.
+	codePaymentDeclined = "PAYMENT_DECLINED"
+	// codeIdempotencyKeyReused errors report an idempotency key sent again
+	// with a different request.
+	codeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+)
+
+// Error is a resolver error carrying a machine-readable code, exposed to
+// clients as extensions.code. Details are added to the extensions alongside
+// it.
+type Error struct {
+	Code    string
+	Message string
+	Details map[string]interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	for k, v := range e.Details {
+		ext[k] = v
+	}
+	return ext
+}
+
+func newError(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+var (
+	errUnauthenticated = newError(codeUnauthenticated, "you must be logged in")
+	errForbidden       = newError(codeForbidden, "you are not allowed to do this")
+)
+
+// userError attaches codes to the domain errors that are the caller's fault,
+// passing anything else through unchanged.
+func userError(err error) error {
+	var pwErr *auth.PasswordError
+	var stockErr *store.InsufficientStockError
+	var transitionErr *orderstatus.TransitionError
+	var refundErr *payments.RefundAmountError
+	var inUseErr *store.CategoryInUseError
+	var attrErr *attrdef.ValidationError
+	var customErr *customization.ValidationError
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return newError(codeUnauthenticated, "%v", err)
+	case errors.Is(err, auth.ErrInvalidEmail), errors.As(err, &pwErr), errors.As(err, &refundErr),
+		errors.Is(err, store.ErrCategoryCycle):
+		return newError(codeBadUserInput, "%v", err)
+	case errors.Is(err, store.ErrEmailTaken), errors.Is(err, store.ErrStatusChanged), errors.As(err, &transitionErr),
+		errors.Is(err, payments.ErrNothingToRefund), errors.Is(err, store.ErrAttributeDefined), errors.Is(err, store.ErrSKUTaken),
+		errors.Is(err, store.ErrOptionDefined):
+		return newError(codeConflict, "%v", err)
+	case errors.As(err, &inUseErr):
+		e := newError(codeConflict, "%v", err)
+		e.Details = map[string]interface{}{"productCount": inUseErr.Products}
+		return e
+	case errors.As(err, &attrErr):
+		return invalidAttributes(attrErr)
+	case errors.As(err, &customErr):
+		return invalidCustomizations(customErr)
+	case errors.As(err, &stockErr):
+		return insufficientStock(stockErr)
+	case errors.Is(err, store.ErrNotFound):
+		return newError(codeNotFound, "%v", err)
+	}
+	return err
+}
+
+func insufficientStock(err *store.InsufficientStockError) *Error {
+	products := make([]map[string]interface{}, len(err.Shortages))
+	for i, s := range err.Shortages {
+		products[i] = map[string]interface{}{
+			"productId": fmt.Sprint(s.ProductID),
+			"requested": s.Requested,
+			"available": s.Available,
+		}
+		if s.VariantID != 0 {
+			products[i]["variantId"] = fmt.Sprint(s.VariantID)
+		}
+	}
+	e := newError(codeInsufficientStock, "%v", err)
+	e.Details = map[string]interface{}{"products": products}
+	return e
+}
+
+func invalidAttributes(err *attrdef.ValidationError) *Error {
+	attributes := make([]map[string]interface{}, len(err.Problems))
+	for i, p := range err.Problems {
+		attributes[i] = map[string]interface{}{"name": p.Name, "message": p.Message}
+	}
+	e := newError(codeBadUserInput, "%v", err)
+	e.Details = map[string]interface{}{"attributes": attributes}
+	return e
+}
+
+func invalidCustomizations(err *customization.ValidationError) *Error {
+	options := make([]map[string]interface{}, len(err.Problems))
+	for i, p := range err.Problems {
+		options[i] = map[string]interface{}{"option": p.Option, "message": p.Message}
+	}
+	e := newError(codeBadUserInput, "%v", err)
+	e.Details = map[string]interface{}{"customizations": options}
+	return e
+}
+
+// requireUser returns the caller, or an UNAUTHENTICATED error.
+func requireUser(ctx context.Context) (*auth.Principal, error) {
+	p := auth.FromContext(ctx)
+	if p == nil {
+		return nil, errUnauthenticated
+	}
+	return p, nil
+}
+
+// requireRole returns the caller if it holds one of roles (admins hold all).
+func requireRole(ctx context.Context, roles ...auth.Role) (*auth.Principal, error) {
+	p, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !p.HasRole(roles...) {
+		return nil, errForbidden
+	}
+	return p, nil
+}
+
+// requireSelf returns the caller if it is userID or an admin.
+func requireSelf(ctx context.Context, userID int32) (*auth.Principal, error) {
+	p, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != userID && p.Role != auth.RoleAdmin {
+		return nil, errForbidden
+	}
+	return p, nil
+}
+
+// actingUser picks the user a create mutation acts for: the caller, unless
+// an admin names someone else through the deprecated userId argument.
+func actingUser(ctx context.Context, userID *graphql.ID) (int32, error) {
+	p, err := requireUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if userID == nil {
+		return p.UserID, nil
+	}
+	id, err := parseID(*userID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := requireSelf(ctx, id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
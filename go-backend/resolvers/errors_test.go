@@ -0,0 +1,79 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"go-backend/auth"
+	"go-backend/models"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+func TestAuthorization(t *testing.T) {
+	anonymous := context.Background()
+	customer := auth.WithPrincipal(anonymous, &auth.Principal{UserID: 1, Role: auth.RoleCustomer})
+	seller := auth.WithPrincipal(anonymous, &auth.Principal{UserID: 2, Role: auth.RoleSeller})
+	admin := auth.WithPrincipal(anonymous, &auth.Principal{UserID: 3, Role: auth.RoleAdmin})
+	userID := func(id graphql.ID) *graphql.ID { return &id }
+
+	tests := []struct {
+		name  string
+		check func() error
+		want  error
+	}{
+		{name: "user, anonymous", check: func() error { _, err := requireUser(anonymous); return err }, want: errUnauthenticated},
+		{name: "user, customer", check: func() error { _, err := requireUser(customer); return err }},
+		{name: "role, anonymous", check: func() error { _, err := requireRole(anonymous, auth.RoleSeller); return err }, want: errUnauthenticated},
+		{name: "role, wrong role", check: func() error { _, err := requireRole(customer, auth.RoleSeller); return err }, want: errForbidden},
+		{name: "role, held", check: func() error { _, err := requireRole(seller, auth.RoleSeller); return err }},
+		{name: "role, admin holds all", check: func() error { _, err := requireRole(admin, auth.RoleSeller); return err }},
+		{name: "self, same user", check: func() error { _, err := requireSelf(customer, 1); return err }},
+		{name: "self, other user", check: func() error { _, err := requireSelf(seller, 1); return err }, want: errForbidden},
+		{name: "self, admin", check: func() error { _, err := requireSelf(admin, 1); return err }},
+		{name: "acting, for someone else", check: func() error { _, err := actingUser(customer, userID("2")); return err }, want: errForbidden},
+		{name: "acting, admin for someone else", check: func() error { _, err := actingUser(admin, userID("2")); return err }},
+	}
+	for _, tt := range tests {
+		if err := tt.check(); err != tt.want {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if id, err := actingUser(customer, nil); err != nil || id != 1 {
+		t.Errorf("actingUser(customer, nil) = %d, %v, want the caller", id, err)
+	}
+	if id, err := actingUser(admin, userID("2")); err != nil || id != 2 {
+		t.Errorf("actingUser(admin, 2) = %d, %v, want 2", id, err)
+	}
+}
+
+// Users are reachable by anyone through reviews, so only the user and admins
+// see their email address and role.
+func TestUserPrivateFields(t *testing.T) {
+	u := &UserResolver{u: models.User{ID: 1, Email: "ann@example.com", Role: string(auth.RoleCustomer)}}
+	tests := []struct {
+		name    string
+		caller  *auth.Principal
+		private bool
+	}{
+		{name: "anonymous"},
+		{name: "another customer", caller: &auth.Principal{UserID: 2, Role: auth.RoleCustomer}},
+		{name: "seller", caller: &auth.Principal{UserID: 2, Role: auth.RoleSeller}},
+		{name: "the user", caller: &auth.Principal{UserID: 1, Role: auth.RoleCustomer}, private: true},
+		{name: "admin", caller: &auth.Principal{UserID: 3, Role: auth.RoleAdmin}, private: true},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.caller != nil {
+			ctx = auth.WithPrincipal(ctx, tt.caller)
+		}
+		email, role := u.Email(ctx), u.Role(ctx)
+		if (email != nil) != tt.private || (role != nil) != tt.private {
+			t.Errorf("%s: email %v and role %v visible, want %t", tt.name, email != nil, role != nil, tt.private)
+		}
+		if tt.private && (*email != "ann@example.com" || *role != "CUSTOMER") {
+			t.Errorf("%s: got %s, %s", tt.name, *email, *role)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package resolvers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go-backend/models"
+	"go-backend/store"
+	"log"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader is the HTTP header carrying a request's idempotency
+// key. Mutations that honour keys also take one as an argument, which wins.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyTTL is how long a request's result is replayed to retries
+// with the same idempotency key.
+const IdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength caps the length of idempotency keys.
+const maxIdempotencyKeyLength = 255
+
+type idempotencyKeyContextKey struct{}
+
+// IdempotencyKeys is HTTP middleware passing the Idempotency-Key header on
+// to the resolvers.
+func IdempotencyKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+			r = r.WithContext(context.WithValue(r.Context(), idempotencyKeyContextKey{}, key))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// idempotencyKey returns the request's idempotency key: arg if given, else
+// the header's. Empty means the request has none.
+func idempotencyKey(ctx context.Context, arg *string) string {
+	if arg != nil {
+		return *arg
+	}
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key
+}
+
+// fingerprint identifies a request made with an idempotency key, so that
+// reusing the key for something else can be told apart from a retry.
+func fingerprint(operation string, request any) (string, error) {
+	b, err := json.Marshal(struct {
+		Operation string
+		Request   any
+	}{operation, request})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// orderResponse is what the order mutations keep for replays: the order as
+// the first request left it, so that a retry gets the same answer even if
+// the order has moved on since. Order.UserID isn't part of the order's JSON,
+// so it is kept alongside.
+type orderResponse struct {
+	Order  models.Order
+	UserID int32
+}
+
+func newOrderResponse(o *models.Order) orderResponse {
+	return orderResponse{Order: *o, UserID: o.UserID}
+}
+
+func (resp orderResponse) resolver(root *Resolver) *OrderResolver {
+	o := resp.Order
+	o.UserID = resp.UserID
+	return &OrderResolver{root, o}
+}
+
+// idempotent runs do at most once per idempotency key of userID. A retry
+// with the same key and request gets the first result back without do
+// running again; reusing the key for a different request, or while the
+// first is still running, is an error. Without a key do just runs. A failed
+// request releases its key so that it can be retried.
+func idempotent[T any](ctx context.Context, keys store.IdempotencyStore, userID int32, key, operation string, request any, do func() (T, error)) (T, error) {
+	var zero T
+	if key == "" {
+		return do()
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return zero, newError(codeBadUserInput, "idempotency key must be at most %d characters", maxIdempotencyKeyLength)
+	}
+	fp, err := fingerprint(operation, request)
+	if err != nil {
+		return zero, err
+	}
+
+	claimed, created, err := keys.Begin(ctx, store.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Operation:   operation,
+		Fingerprint: fp,
+	}, time.Now().Add(IdempotencyTTL))
+	if err != nil {
+		return zero, err
+	}
+	if !created {
+		if claimed.Operation != operation || claimed.Fingerprint != fp {
+			return zero, newError(codeIdempotencyKeyReused, "idempotency key %q was already used for a different request", key)
+		}
+		if claimed.Response == nil {
+			return zero, newError(codeConflict, "a request with idempotency key %q is still in progress", key)
+		}
+		var result T
+		if err := json.Unmarshal(claimed.Response, &result); err != nil {
+			return zero, err
+		}
+		return result, nil
+	}
+
+	result, err := do()
+	if err != nil {
+		if rerr := keys.Release(context.WithoutCancel(ctx), userID, key); rerr != nil {
+			log.Printf("Failed to release idempotency key %q: %v", key, rerr)
+		}
+		return zero, err
+	}
+	response, err := json.Marshal(result)
+	if err == nil {
+		err = keys.Complete(context.WithoutCancel(ctx), userID, key, response)
+	}
+	if err != nil {
+		// The work is done; retries will be told the key is still in use
+		// until it expires, which beats doing it twice.
+		log.Printf("Failed to store response for idempotency key %q: %v", key, err)
+	}
+	return result, nil
+}
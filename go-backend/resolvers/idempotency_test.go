@@ -0,0 +1,277 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/payments"
+	"go-backend/store"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+var errDo = errors.New("request failed")
+
+// call is one request made through idempotent. The request itself returns
+// how many requests have run so far, or errDo if it fails.
+type call struct {
+	userID    int32
+	key       string
+	operation string
+	request   string
+	fails     bool
+
+	want     int32
+	wantRan  bool
+	wantCode string // of the *Error returned, if any
+	wantErr  error
+}
+
+func TestIdempotent(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls []call
+	}{
+		{
+			name: "retry gets the first result",
+			calls: []call{
+				{userID: 1, key: "k", operation: "op", request: "a", want: 1, wantRan: true},
+				{userID: 1, key: "k", operation: "op", request: "a", want: 1},
+				{userID: 1, key: "k", operation: "op", request: "a", want: 1},
+			},
+		},
+		{
+			name: "without a key every request runs",
+			calls: []call{
+				{userID: 1, operation: "op", request: "a", want: 1, wantRan: true},
+				{userID: 1, operation: "op", request: "a", want: 2, wantRan: true},
+			},
+		},
+		{
+			name: "key reused for another request",
+			calls: []call{
+				{userID: 1, key: "k", operation: "op", request: "a", want: 1, wantRan: true},
+				{userID: 1, key: "k", operation: "op", request: "b", wantCode: codeIdempotencyKeyReused},
+			},
+		},
+		{
+			name: "key reused for another operation",
+			calls: []call{
+				{userID: 1, key: "k", operation: "op", request: "a", want: 1, wantRan: true},
+				{userID: 1, key: "k", operation: "other", request: "a", wantCode: codeIdempotencyKeyReused},
+			},
+		},
+		{
+			name: "keys belong to a user",
+			calls: []call{
+				{userID: 1, key: "k", operation: "op", request: "a", want: 1, wantRan: true},
+				{userID: 2, key: "k", operation: "op", request: "a", want: 2, wantRan: true},
+			},
+		},
+		{
+			name: "a failed request can be retried",
+			calls: []call{
+				{userID: 1, key: "k", operation: "op", request: "a", fails: true, wantRan: true, wantErr: errDo},
+				{userID: 1, key: "k", operation: "op", request: "a", want: 2, wantRan: true},
+				{userID: 1, key: "k", operation: "op", request: "a", want: 2},
+			},
+		},
+		{
+			name: "key too long",
+			calls: []call{
+				{userID: 1, key: strings.Repeat("k", maxIdempotencyKeyLength+1), operation: "op", request: "a", wantCode: codeBadUserInput},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			keys := store.NewMemory().Idempotency
+			var runs int32
+			for i, c := range tt.calls {
+				ran := false
+				got, err := idempotent(ctx, keys, c.userID, c.key, c.operation, c.request, func() (int32, error) {
+					ran = true
+					runs++
+					if c.fails {
+						return 0, errDo
+					}
+					return runs, nil
+				})
+
+				var e *Error
+				switch {
+				case c.wantCode != "":
+					if !errors.As(err, &e) || e.Code != c.wantCode {
+						t.Errorf("call %d: error = %v, want %s", i+1, err, c.wantCode)
+					}
+				case !errors.Is(err, c.wantErr):
+					t.Errorf("call %d: error = %v, want %v", i+1, err, c.wantErr)
+				case got != c.want:
+					t.Errorf("call %d = %d, want %d", i+1, got, c.want)
+				}
+				if ran != c.wantRan {
+					t.Errorf("call %d ran = %v, want %v", i+1, ran, c.wantRan)
+				}
+			}
+		})
+	}
+}
+
+// A retry arriving while the first request is still running must not run
+// it a second time.
+func TestIdempotentInProgress(t *testing.T) {
+	ctx := context.Background()
+	keys := store.NewMemory().Idempotency
+	var retried error
+	_, err := idempotent(ctx, keys, 1, "k", "op", "a", func() (int32, error) {
+		_, retried = idempotent(ctx, keys, 1, "k", "op", "a", func() (int32, error) {
+			t.Error("the retry ran")
+			return 2, nil
+		})
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e *Error
+	if !errors.As(retried, &e) || e.Code != codeConflict {
+		t.Errorf("retry error = %v, want %s", retried, codeConflict)
+	}
+}
+
+// Retrying createOrder with the key of an order placed already returns that
+// order without taking its stock again.
+func TestCreateOrderReplay(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: auth.RoleCustomer})
+	r, st := newTestResolver(t)
+	cat, err := st.Categories.Create(ctx, "Mugs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := st.Products.Create(ctx, models.ProductInput{Name: "Mug", Price: 12.99, StockQuantity: 5, CategoryID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "order-1"
+	order := func(quantity int32) (*OrderResolver, error) {
+		return r.CreateOrder(ctx, struct {
+			Input          models.OrderInput
+			IdempotencyKey *string
+		}{models.OrderInput{Items: []*models.OrderItemInput{{ProductID: p.ID, Quantity: quantity}}}, &key})
+	}
+	first, err := order(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := order(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry.o.ID != first.o.ID {
+		t.Errorf("retry placed order %d, want %d again", retry.o.ID, first.o.ID)
+	}
+	var e *Error
+	if _, err := order(3); !errors.As(err, &e) || e.Code != codeIdempotencyKeyReused {
+		t.Errorf("reusing the key for another order: error = %v, want %s", err, codeIdempotencyKeyReused)
+	}
+
+	got, err := st.Products.Get(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.StockQuantity != 3 {
+		t.Errorf("stock is %d, want 3", got.StockQuantity)
+	}
+}
+
+// A replayed checkout answers as the first request did, not with what has
+// become of the order since.
+func TestCheckoutReplay(t *testing.T) {
+	customer := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: auth.RoleCustomer})
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: auth.RoleAdmin})
+	r, st := newTestResolver(t)
+	cat, err := st.Categories.Create(admin, "Mugs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := st.Products.Create(admin, models.ProductInput{Name: "Mug", Price: 12.99, StockQuantity: 5, CategoryID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AddToCart(customer, addToCartArgs{ProductID: graphql.ID(fmt.Sprint(p.ID)), Quantity: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	key := "checkout-1"
+	checkout := func() (*OrderResolver, error) {
+		return r.Checkout(customer, struct {
+			Input          models.CheckoutInput
+			IdempotencyKey *string
+		}{models.CheckoutInput{PaymentMethod: payments.FakeCardOK}, &key})
+	}
+	first, err := checkout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status() != "PAID" {
+		t.Fatalf("checkout left the order %s", first.Status())
+	}
+	if _, err := r.RefundOrder(admin, struct {
+		ID             graphql.ID
+		Amount         *float64
+		Reason         *string
+		IdempotencyKey *string
+	}{ID: first.ID()}); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := checkout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.ID() != first.ID() || replay.Status() != "PAID" || replay.TotalAmount() != first.TotalAmount() {
+		t.Errorf("replay = order %s %s of %v, want order %s PAID of %v as first answered",
+			replay.ID(), replay.Status(), replay.TotalAmount(), first.ID(), first.TotalAmount())
+	}
+	if replay.o.UserID != 1 {
+		t.Errorf("replayed order was placed by user %d, want 1", replay.o.UserID)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	arg := "from-argument"
+	tests := []struct {
+		name   string
+		header string
+		arg    *string
+		want   string
+	}{
+		{name: "none"},
+		{name: "header", header: "from-header", want: "from-header"},
+		{name: "argument", arg: &arg, want: arg},
+		{name: "argument wins", header: "from-header", arg: &arg, want: arg},
+	}
+	for _, tt := range tests {
+		var got string
+		handler := IdempotencyKeys(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = idempotencyKey(r.Context(), tt.arg)
+		}))
+		r := httptest.NewRequest("POST", "/graphql", nil)
+		if tt.header != "" {
+			r.Header.Set(IdempotencyKeyHeader, tt.header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if got != tt.want {
+			t.Errorf("%s: key = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-backend/auth"
+	"go-backend/imaging"
+	"go-backend/models"
+	"go-backend/store"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// renditions returns the renditions of an uploaded photo, largest first,
+// and none for a photo added by URL.
+func (r *ProductImageResolver) renditions(ctx context.Context) ([]*models.ImageRendition, error) {
+	if r.i.ImageID == nil {
+		return nil, nil
+	}
+	return r.root.loaders(ctx).RenditionsByImageID.Load(ctx, *r.i.ImageID)
+}
+
+// rendition returns the rendition of an uploaded photo in size and format,
+// or nil for a photo added by URL.
+func (r *ProductImageResolver) rendition(ctx context.Context, size, format string) (*models.ImageRendition, error) {
+	renditions, err := r.renditions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return findRendition(renditions, size, format), nil
+}
+
+func findRendition(renditions []*models.ImageRendition, size, format string) *models.ImageRendition {
+	for _, rend := range renditions {
+		if rend.Size == size && rend.Format == format {
+			return rend
+		}
+	}
+	return nil
+}
+
+func (r *ProductImageResolver) URL(ctx context.Context, args struct {
+	Size   string
+	Format string
+}) (string, error) {
+	rend, err := r.rendition(ctx, args.Size, args.Format)
+	if err != nil || rend == nil {
+		return r.i.ImageUrl, err
+	}
+	return r.root.images.URL(rend.Key), nil
+}
+
+func (r *ProductImageResolver) Width(ctx context.Context, args struct{ Size string }) (*int32, error) {
+	rend, err := r.rendition(ctx, args.Size, string(imaging.JPEG))
+	if err != nil || rend == nil {
+		return nil, err
+	}
+	return &rend.Width, nil
+}
+
+func (r *ProductImageResolver) Height(ctx context.Context, args struct{ Size string }) (*int32, error) {
+	rend, err := r.rendition(ctx, args.Size, string(imaging.JPEG))
+	if err != nil || rend == nil {
+		return nil, err
+	}
+	return &rend.Height, nil
+}
+
+func (r *ProductImageResolver) Renditions(ctx context.Context) ([]*ImageRenditionResolver, error) {
+	renditions, err := r.renditions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return renditionResolvers(r.root, renditions), nil
+}
+
+func renditionResolvers(root *Resolver, renditions []*models.ImageRendition) []*ImageRenditionResolver {
+	resolvers := make([]*ImageRenditionResolver, len(renditions))
+	for i, rend := range renditions {
+		resolvers[i] = &ImageRenditionResolver{root, *rend}
+	}
+	return resolvers
+}
+
+// ImageResolver resolves the Image type, an image a user uploaded
+type ImageResolver struct {
+	root *Resolver
+	img  models.Image
+}
+
+func (r *ImageResolver) ID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.img.ID))
+}
+
+func (r *ImageResolver) Filename() string {
+	return r.img.Filename
+}
+
+func (r *ImageResolver) MimeType() string {
+	return r.img.MimeType
+}
+
+func (r *ImageResolver) FileSize() int32 {
+	return int32(r.img.Size)
+}
+
+func (r *ImageResolver) Checksum() string {
+	return r.img.Checksum
+}
+
+func (r *ImageResolver) Width() int32 {
+	return r.img.Width
+}
+
+func (r *ImageResolver) Height() int32 {
+	return r.img.Height
+}
+
+func (r *ImageResolver) URL(ctx context.Context, args struct {
+	Size   string
+	Format string
+}) (string, error) {
+	renditions, err := r.root.loaders(ctx).RenditionsByImageID.Load(ctx, r.img.ID)
+	if err != nil {
+		return "", err
+	}
+	if rend := findRendition(renditions, args.Size, args.Format); rend != nil {
+		return r.root.images.URL(rend.Key), nil
+	}
+	return r.root.images.URL(r.img.Key), nil
+}
+
+func (r *ImageResolver) Renditions(ctx context.Context) ([]*ImageRenditionResolver, error) {
+	renditions, err := r.root.loaders(ctx).RenditionsByImageID.Load(ctx, r.img.ID)
+	if err != nil {
+		return nil, err
+	}
+	return renditionResolvers(r.root, renditions), nil
+}
+
+func (r *ImageResolver) CreatedAt() string {
+	return r.img.CreatedAt
+}
+
+// ImageRenditionResolver resolves the ImageRendition type
+type ImageRenditionResolver struct {
+	root *Resolver
+	r    models.ImageRendition
+}
+
+func (r *ImageRenditionResolver) Size() string {
+	return r.r.Size
+}
+
+func (r *ImageRenditionResolver) Format() string {
+	return r.r.Format
+}
+
+func (r *ImageRenditionResolver) URL() string {
+	return r.root.images.URL(r.r.Key)
+}
+
+func (r *ImageRenditionResolver) Width() int32 {
+	return r.r.Width
+}
+
+func (r *ImageRenditionResolver) Height() int32 {
+	return r.r.Height
+}
+
+// checkImages validates the photos of a product or variant mutation. A
+// photo is added either by URL or by the ID of an image the seller
+// uploaded; for uploads ImageUrl is filled in with the URL of the LARGE
+// JPEG rendition.
+func (r *Resolver) checkImages(ctx context.Context, seller *auth.Principal, images []models.ProductImageInput) error {
+	for i := range images {
+		in := &images[i]
+		if in.ImageID == nil {
+			if in.ImageUrl == nil || strings.TrimSpace(*in.ImageUrl) == "" {
+				return newError(codeBadUserInput, "an image needs an imageUrl or an imageId")
+			}
+			continue
+		}
+		if in.ImageUrl != nil {
+			return newError(codeBadUserInput, "give an image either an imageUrl or an imageId, not both")
+		}
+		img, err := r.store.Images.Get(ctx, *in.ImageID)
+		// Other sellers' uploads are reported as missing, so IDs can't be
+		// probed.
+		if errors.Is(err, store.ErrNotFound) || (err == nil && img.OwnerID != seller.UserID && seller.Role != auth.RoleAdmin) {
+			return newError(codeNotFound, "image %d not found", *in.ImageID)
+		}
+		if err != nil {
+			return err
+		}
+		renditions, err := r.loaders(ctx).RenditionsByImageID.Load(ctx, img.ID)
+		if err != nil {
+			return err
+		}
+		key := img.Key
+		if rend := findRendition(renditions, string(imaging.Large), string(imaging.JPEG)); rend != nil {
+			key = rend.Key
+		}
+		url := r.images.URL(key)
+		in.ImageUrl = &url
+	}
+	return nil
+}
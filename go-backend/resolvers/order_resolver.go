@@ -3,14 +3,16 @@ package resolvers
 import (
 	"context"
 	"fmt"
-	"go-backend/db"
 	"go-backend/models"
+	"go-backend/orderstatus"
+	"go-backend/pricing"
 
 	"github.com/graph-gophers/graphql-go"
 )
 
 type OrderResolver struct {
-	o models.Order
+	root *Resolver
+	o    models.Order
 }
 
 // Resolve ID field
@@ -20,12 +22,34 @@ func (r *OrderResolver) ID() graphql.ID {
 
 // Resolve User field
 func (r *OrderResolver) User(ctx context.Context) (*UserResolver, error) {
-	var u models.User
-	err := db.DB.QueryRowContext(ctx, "SELECT id, email, first_name, last_name FROM users WHERE id = $1", r.o.UserID).Scan(&u.ID, &u.Email, &u.FirstName, &u.LastName)
+	u, err := r.root.loaders(ctx).UserByID.Load(ctx, r.o.UserID)
 	if err != nil {
 		return nil, err
 	}
-	return &UserResolver{u}, nil
+	if u == nil {
+		return nil, fmt.Errorf("user %d not found", r.o.UserID)
+	}
+	return &UserResolver{r.root, *u, false}, nil
+}
+
+// Resolve Subtotal field
+func (r *OrderResolver) Subtotal() float64 {
+	return r.o.Subtotal
+}
+
+// Resolve DiscountTotal field
+func (r *OrderResolver) DiscountTotal() float64 {
+	return r.o.DiscountTotal
+}
+
+// Resolve TaxTotal field
+func (r *OrderResolver) TaxTotal() float64 {
+	return r.o.TaxTotal
+}
+
+// Resolve ShippingTotal field
+func (r *OrderResolver) ShippingTotal() float64 {
+	return r.o.ShippingTotal
 }
 
 // Resolve TotalAmount field
@@ -40,22 +64,16 @@ func (r *OrderResolver) Status() string {
 
 // Resolve Items field
 func (r *OrderResolver) Items(ctx context.Context) ([]*OrderItemResolver, error) {
-	rows, err := db.DB.QueryContext(ctx, "SELECT id, product_id, quantity, price_at_time FROM order_items WHERE order_id = $1", r.o.ID)
+	items, err := r.root.store.Orders.Items(ctx, r.o.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var items []*OrderItemResolver
-	for rows.Next() {
-		var item models.OrderItem
-		if err := rows.Scan(&item.ID, &item.ProductID, &item.Quantity, &item.PriceAtTime); err != nil {
-			return nil, err
-		}
-		items = append(items, &OrderItemResolver{item})
+	resolvers := make([]*OrderItemResolver, len(items))
+	for i, item := range items {
+		resolvers[i] = &OrderItemResolver{r.root, *item}
 	}
-
-	return items, nil
+	return resolvers, nil
 }
 
 // Resolve CreatedAt field
@@ -63,8 +81,55 @@ func (r *OrderResolver) CreatedAt() string {
 	return r.o.CreatedAt
 }
 
+// Resolve StatusHistory field
+func (r *OrderResolver) StatusHistory(ctx context.Context) ([]*OrderStatusChangeResolver, error) {
+	history, err := r.root.store.Orders.StatusHistory(ctx, r.o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var actors []int32
+	for _, c := range history {
+		if c.ActorID != nil {
+			actors = append(actors, *c.ActorID)
+		}
+	}
+	r.root.loaders(ctx).UserByID.Expect(actors...)
+
+	resolvers := make([]*OrderStatusChangeResolver, len(history))
+	for i, c := range history {
+		resolvers[i] = &OrderStatusChangeResolver{r.root, *c}
+	}
+	return resolvers, nil
+}
+
+// Resolve Payments field
+func (r *OrderResolver) Payments(ctx context.Context) ([]*PaymentResolver, error) {
+	payments, err := r.root.store.Payments.ListByOrder(ctx, r.o.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*PaymentResolver, len(payments))
+	for i, p := range payments {
+		resolvers[i] = &PaymentResolver{*p}
+	}
+	return resolvers, nil
+}
+
+// Resolve NextStatuses field
+func (r *OrderResolver) NextStatuses() []string {
+	next := orderstatus.Status(r.o.Status).Next()
+	statuses := make([]string, len(next))
+	for i, s := range next {
+		statuses[i] = string(s)
+	}
+	return statuses
+}
+
 type OrderItemResolver struct {
-	oi models.OrderItem
+	root *Resolver
+	oi   models.OrderItem
 }
 
 // Resolve ID field
@@ -74,12 +139,37 @@ func (r *OrderItemResolver) ID() graphql.ID {
 
 // Resolve Product field
 func (r *OrderItemResolver) Product(ctx context.Context) (*ProductResolver, error) {
-	var p models.Product
-	err := db.DB.QueryRowContext(ctx, "SELECT id, name, description, price, stock_quantity, category_id FROM products WHERE id = $1", r.oi.ProductID).Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &p.CategoryID)
+	p, err := r.root.loaders(ctx).ProductByID.Load(ctx, r.oi.ProductID)
 	if err != nil {
 		return nil, err
 	}
-	return &ProductResolver{p}, nil
+	if p == nil {
+		return nil, fmt.Errorf("product %d not found", r.oi.ProductID)
+	}
+	return &ProductResolver{r.root, *p}, nil
+}
+
+// Resolve Variant field; null for products without variants, or once the
+// variant is deleted
+func (r *OrderItemResolver) Variant(ctx context.Context) (*ProductVariantResolver, error) {
+	if r.oi.VariantID == nil {
+		return nil, nil
+	}
+	v, err := r.root.loaders(ctx).VariantByID.Load(ctx, *r.oi.VariantID)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return &ProductVariantResolver{r.root, *v}, nil
+}
+
+// Resolve Sku field; the variant's SKU when the order was placed
+func (r *OrderItemResolver) Sku() *string {
+	return r.oi.SKU
+}
+
+// Resolve Customizations field
+func (r *OrderItemResolver) Customizations() []*CustomizationResolver {
+	return customizationResolvers(r.oi.Customizations)
 }
 
 // Resolve Quantity field
@@ -91,3 +181,28 @@ func (r *OrderItemResolver) Quantity() int32 {
 func (r *OrderItemResolver) PriceAtTime() float64 {
 	return r.oi.PriceAtTime
 }
+
+// Resolve UnitPrice field; the same snapshot as priceAtTime
+func (r *OrderItemResolver) UnitPrice() float64 {
+	return r.oi.PriceAtTime
+}
+
+// Resolve LineSubtotal field
+func (r *OrderItemResolver) LineSubtotal() float64 {
+	return (pricing.FromFloat(r.oi.PriceAtTime) * pricing.Cents(r.oi.Quantity)).Float()
+}
+
+// Resolve Discount field
+func (r *OrderItemResolver) Discount() float64 {
+	return r.oi.Discount
+}
+
+// Resolve Tax field
+func (r *OrderItemResolver) Tax() float64 {
+	return r.oi.Tax
+}
+
+// Resolve LineTotal field
+func (r *OrderItemResolver) LineTotal() float64 {
+	return r.oi.LineTotal
+}
@@ -0,0 +1,166 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/orderstatus"
+	"go-backend/store"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// orderAuthorizer decides whether the caller may change an order's status.
+type orderAuthorizer func(p *auth.Principal, o *models.Order) bool
+
+// byOwner lets the order's buyer, or an admin, change it.
+func byOwner(p *auth.Principal, o *models.Order) bool {
+	return p.UserID == o.UserID || p.Role == auth.RoleAdmin
+}
+
+// byRole lets holders of role (admins hold all) change any order.
+func byRole(role auth.Role) orderAuthorizer {
+	return func(p *auth.Principal, o *models.Order) bool {
+		return p.HasRole(role)
+	}
+}
+
+// transitionOrder moves an order to status to on behalf of the caller.
+// The move must be allowed by the order lifecycle and the caller by allow.
+func (r *Resolver) transitionOrder(ctx context.Context, orderID graphql.ID, to orderstatus.Status, note *string, allow orderAuthorizer) (*OrderResolver, error) {
+	id, err := parseID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	o, err := r.store.Orders.Get(ctx, id)
+	if err != nil {
+		return nil, userError(err)
+	}
+	if !allow(p, o) {
+		return nil, errForbidden
+	}
+
+	from := orderstatus.Status(o.Status)
+	if err := orderstatus.Check(from, to); err != nil {
+		return nil, userError(err)
+	}
+	change := store.StatusChange{
+		From:    o.Status,
+		To:      string(to),
+		ActorID: p.UserID,
+		Restock: orderstatus.ReturnsStock(from, to),
+	}
+	if note != nil {
+		change.Note = *note
+	}
+	o, err = r.store.Orders.SetStatus(ctx, id, change)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &OrderResolver{r, *o}, nil
+}
+
+// Cancels one of the caller's pending orders, returning its items to stock
+func (r *Resolver) CancelOrder(ctx context.Context, args struct {
+	ID     graphql.ID
+	Reason *string
+}) (*OrderResolver, error) {
+	return r.transitionOrder(ctx, args.ID, orderstatus.Cancelled, args.Reason, byOwner)
+}
+
+func (r *Resolver) MarkOrderPaid(ctx context.Context, args struct {
+	ID   graphql.ID
+	Note *string
+}) (*OrderResolver, error) {
+	return r.transitionOrder(ctx, args.ID, orderstatus.Paid, args.Note, byRole(auth.RoleAdmin))
+}
+
+func (r *Resolver) StartOrderFulfillment(ctx context.Context, args struct {
+	ID   graphql.ID
+	Note *string
+}) (*OrderResolver, error) {
+	return r.transitionOrder(ctx, args.ID, orderstatus.Fulfilling, args.Note, byRole(auth.RoleSeller))
+}
+
+func (r *Resolver) MarkOrderShipped(ctx context.Context, args struct {
+	ID             graphql.ID
+	TrackingNumber *string
+}) (*OrderResolver, error) {
+	var note *string
+	if args.TrackingNumber != nil {
+		n := "tracking number " + *args.TrackingNumber
+		note = &n
+	}
+	return r.transitionOrder(ctx, args.ID, orderstatus.Shipped, note, byRole(auth.RoleSeller))
+}
+
+func (r *Resolver) MarkOrderDelivered(ctx context.Context, args struct {
+	ID   graphql.ID
+	Note *string
+}) (*OrderResolver, error) {
+	return r.transitionOrder(ctx, args.ID, orderstatus.Delivered, args.Note, byRole(auth.RoleSeller))
+}
+
+// Moves an order to any status the lifecycle allows next, for corrections.
+// Payment statuses are left to the mutations that move the money: marking
+// an order REFUNDED here would restock it without refunding the payment,
+// and leave refundOrder nothing to refund.
+func (r *Resolver) UpdateOrderStatus(ctx context.Context, args struct {
+	ID     graphql.ID
+	Status string
+	Note   *string
+}) (*OrderResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	switch to := orderstatus.Status(args.Status); to {
+	case orderstatus.Paid:
+		return nil, newError(codeBadUserInput, "orders become %s by paying for them", to)
+	case orderstatus.Refunded:
+		return nil, newError(codeBadUserInput, "orders become %s through refundOrder", to)
+	}
+	return r.transitionOrder(ctx, args.ID, orderstatus.Status(args.Status), args.Note, byRole(auth.RoleAdmin))
+}
+
+// OrderStatusChangeResolver resolves the OrderStatusChange type
+type OrderStatusChangeResolver struct {
+	root *Resolver
+	c    models.OrderStatusChange
+}
+
+func (r *OrderStatusChangeResolver) ID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.c.ID))
+}
+
+func (r *OrderStatusChangeResolver) From() *string {
+	return r.c.FromStatus
+}
+
+func (r *OrderStatusChangeResolver) To() string {
+	return r.c.ToStatus
+}
+
+// Actor is null for changes made by the system or by a deleted user.
+func (r *OrderStatusChangeResolver) Actor(ctx context.Context) (*UserResolver, error) {
+	if r.c.ActorID == nil {
+		return nil, nil
+	}
+	u, err := r.root.loaders(ctx).UserByID.Load(ctx, *r.c.ActorID)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return &UserResolver{r.root, *u, false}, nil
+}
+
+func (r *OrderStatusChangeResolver) Note() *string {
+	return r.c.Note
+}
+
+func (r *OrderStatusChangeResolver) CreatedAt() string {
+	return r.c.CreatedAt
+}
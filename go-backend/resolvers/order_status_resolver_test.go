@@ -0,0 +1,107 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/orderstatus"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Corrections can't move an order into a payment status, which would change
+// its stock or its paid state without moving any money.
+func TestUpdateOrderStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		paid     bool // by markOrderPaid, before the correction
+		to       orderstatus.Status
+		wantCode string
+		want     orderstatus.Status
+		wantLeft int32 // in stock afterwards
+	}{
+		{name: "pending to paid", to: orderstatus.Paid, wantCode: codeBadUserInput, want: orderstatus.Pending, wantLeft: 3},
+		{name: "paid to refunded", paid: true, to: orderstatus.Refunded, wantCode: codeBadUserInput, want: orderstatus.Paid, wantLeft: 3},
+		{name: "pending to cancelled", to: orderstatus.Cancelled, want: orderstatus.Cancelled, wantLeft: 5},
+		{name: "paid to fulfilling", paid: true, to: orderstatus.Fulfilling, want: orderstatus.Fulfilling, wantLeft: 3},
+		{name: "not allowed next", to: orderstatus.Shipped, wantCode: codeConflict, want: orderstatus.Pending, wantLeft: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: auth.RoleCustomer})
+			admin := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 2, Role: auth.RoleAdmin})
+			r, st := newTestResolver(t)
+			cat, err := st.Categories.Create(admin, "Mugs", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := st.Products.Create(admin, models.ProductInput{Name: "Mug", Price: 12.99, StockQuantity: 5, CategoryID: cat.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			o, err := r.CreateOrder(customer, struct {
+				Input          models.OrderInput
+				IdempotencyKey *string
+			}{models.OrderInput{Items: []*models.OrderItemInput{{ProductID: p.ID, Quantity: 2}}}, nil})
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := graphql.ID(fmt.Sprint(o.o.ID))
+			if tt.paid {
+				if _, err := r.MarkOrderPaid(admin, struct {
+					ID   graphql.ID
+					Note *string
+				}{id, nil}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err = r.UpdateOrderStatus(admin, struct {
+				ID     graphql.ID
+				Status string
+				Note   *string
+			}{id, string(tt.to), nil})
+			var e *Error
+			switch {
+			case tt.wantCode == "" && err != nil:
+				t.Errorf("UpdateOrderStatus(%s) error = %v", tt.to, err)
+			case tt.wantCode != "" && (!errors.As(err, &e) || e.Code != tt.wantCode):
+				t.Errorf("UpdateOrderStatus(%s) error = %v, want %s", tt.to, err, tt.wantCode)
+			}
+
+			got, err := st.Orders.Get(admin, o.o.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if orderstatus.Status(got.Status) != tt.want {
+				t.Errorf("order is %s, want %s", got.Status, tt.want)
+			}
+			product, err := st.Products.Get(admin, p.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if product.StockQuantity != tt.wantLeft {
+				t.Errorf("stock is %d, want %d", product.StockQuantity, tt.wantLeft)
+			}
+		})
+	}
+}
+
+// A caller who isn't an admin is refused before the status is looked at.
+func TestUpdateOrderStatusForbidden(t *testing.T) {
+	r, _ := newTestResolver(t)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: auth.RoleSeller})
+	_, err := r.UpdateOrderStatus(ctx, struct {
+		ID     graphql.ID
+		Status string
+		Note   *string
+	}{"1", string(orderstatus.Refunded), nil})
+	var e *Error
+	if !errors.As(err, &e) || e.Code != codeForbidden {
+		t.Errorf("error = %v, want %s", err, codeForbidden)
+	}
+}
@@ -0,0 +1,177 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/payments"
+	"go-backend/pricing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Places an order for the caller's cart and pays for it. The cart is
+// emptied once the order is paid; if the payment fails the order is
+// cancelled and the cart kept for another attempt.
+func (r *Resolver) Checkout(ctx context.Context, args struct {
+	Input          models.CheckoutInput
+	IdempotencyKey *string
+}) (*OrderResolver, error) {
+	p, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := idempotent(ctx, r.store.Idempotency, p.UserID, idempotencyKey(ctx, args.IdempotencyKey), "checkout", args.Input,
+		func() (orderResponse, error) {
+			o, err := r.checkout(ctx, p.UserID, args.Input)
+			if err != nil {
+				return orderResponse{}, err
+			}
+			return newOrderResponse(o), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp.resolver(r), nil
+}
+
+// checkout places and pays for an order of the cart's lines, returning the
+// paid order.
+func (r *Resolver) checkout(ctx context.Context, userID int32, input models.CheckoutInput) (*models.Order, error) {
+	cart, err := r.cartFor(ctx, input.CartToken, false)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, newError(codeBadUserInput, "cart is empty")
+	}
+	items, err := r.store.Carts.Items(ctx, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, newError(codeBadUserInput, "cart is empty")
+	}
+
+	order := models.OrderInput{TotalAmount: input.TotalAmount}
+	for _, it := range items {
+		order.Items = append(order.Items, &models.OrderItemInput{
+			ProductID:      it.ProductID,
+			VariantID:      it.VariantID,
+			Customizations: customizationInputs(it.Customizations),
+			Quantity:       it.Quantity,
+		})
+	}
+	o, err := r.placeOrder(ctx, userID, order, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	paid, err := r.payments.Pay(ctx, o, input.PaymentMethod)
+	var declined *payments.DeclinedError
+	if errors.As(err, &declined) {
+		e := newError(codePaymentDeclined, "%v", err)
+		e.Details = map[string]interface{}{"orderId": fmt.Sprint(o.ID)}
+		return nil, e
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.store.Carts.Clear(ctx, cart.ID, time.Now().Add(CartTTL)); err != nil {
+		return nil, err
+	}
+	return paid, nil
+}
+
+// Refunds an order's payment. Without an amount the whole payment is
+// refunded and the order moves to REFUNDED.
+func (r *Resolver) RefundOrder(ctx context.Context, args struct {
+	ID             graphql.ID
+	Amount         *float64
+	Reason         *string
+	IdempotencyKey *string
+}) (*OrderResolver, error) {
+	p, err := requireRole(ctx, auth.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	request := struct {
+		ID     int32
+		Amount *float64
+		Reason *string
+	}{id, args.Amount, args.Reason}
+	resp, err := idempotent(ctx, r.store.Idempotency, p.UserID, idempotencyKey(ctx, args.IdempotencyKey), "refundOrder", request,
+		func() (orderResponse, error) {
+			o, err := r.store.Orders.Get(ctx, id)
+			if err != nil {
+				return orderResponse{}, userError(err)
+			}
+			var amount *pricing.Cents
+			if args.Amount != nil {
+				c := pricing.FromFloat(*args.Amount)
+				amount = &c
+			}
+			var reason string
+			if args.Reason != nil {
+				reason = *args.Reason
+			}
+			refunded, err := r.payments.Refund(ctx, o, amount, p.UserID, reason)
+			if err != nil {
+				return orderResponse{}, userError(err)
+			}
+			return newOrderResponse(refunded), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp.resolver(r), nil
+}
+
+// PaymentResolver resolves the Payment type
+type PaymentResolver struct {
+	p models.Payment
+}
+
+func (r *PaymentResolver) ID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.p.ID))
+}
+
+func (r *PaymentResolver) Provider() string {
+	return r.p.Provider
+}
+
+func (r *PaymentResolver) Reference() *string {
+	return r.p.Reference
+}
+
+func (r *PaymentResolver) Amount() float64 {
+	return r.p.Amount
+}
+
+func (r *PaymentResolver) AmountRefunded() float64 {
+	return r.p.AmountRefunded
+}
+
+func (r *PaymentResolver) Status() string {
+	return r.p.Status
+}
+
+func (r *PaymentResolver) FailureReason() *string {
+	return r.p.FailureReason
+}
+
+func (r *PaymentResolver) CreatedAt() string {
+	return r.p.CreatedAt
+}
+
+func (r *PaymentResolver) UpdatedAt() string {
+	return r.p.UpdatedAt
+}
@@ -2,16 +2,15 @@ package resolvers
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
-	"go-backend/db"
 	"go-backend/models"
 
 	"github.com/graph-gophers/graphql-go"
 )
 
 type ProductResolver struct {
-	p models.Product
+	root *Resolver
+	p    models.Product
 }
 
 func (r *ProductResolver) ID() graphql.ID {
@@ -35,82 +34,120 @@ func (r *ProductResolver) StockQuantity() int32 {
 }
 
 func (r *ProductResolver) Category(ctx context.Context) (*CategoryResolver, error) {
-
 	if r.p.CategoryID == 0 {
 		return nil, nil
 	}
 
-	var c models.Category
-	err := db.DB.QueryRow("SELECT id, name FROM categories WHERE id = $1", r.p.CategoryID).Scan(&c.ID, &c.Name)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
+	c, err := r.root.loaders(ctx).CategoryByID.Load(ctx, r.p.CategoryID)
+	if err != nil || c == nil {
 		return nil, err
 	}
-	return &CategoryResolver{c}, nil
+	return &CategoryResolver{r.root, *c}, nil
 }
 
 func (r *ProductResolver) Images(ctx context.Context) ([]*ProductImageResolver, error) {
-	rows, err := db.DB.Query("SELECT id, image_url, is_primary FROM product_images WHERE product_id = $1", r.p.ID)
+	images, err := r.root.loaders(ctx).ImagesByProductID.Load(ctx, r.p.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var images []*ProductImageResolver
-	for rows.Next() {
-		var img models.ProductImage
-		if err := rows.Scan(&img.ID, &img.ImageUrl, &img.IsPrimary); err != nil {
-			return nil, err
-		}
-		images = append(images, &ProductImageResolver{img})
-	}
 
-	return images, nil
+	resolvers := make([]*ProductImageResolver, len(images))
+	for i, img := range images {
+		resolvers[i] = &ProductImageResolver{r.root, *img}
+	}
+	return resolvers, nil
 }
 
 func (r *ProductResolver) Attributes(ctx context.Context) ([]*ProductAttributeResolver, error) {
-	rows, err := db.DB.Query("SELECT id, name, value FROM product_attributes WHERE product_id = $1", r.p.ID)
+	attributes, err := r.root.loaders(ctx).AttributesByProductID.Load(ctx, r.p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*ProductAttributeResolver, len(attributes))
+	for i, attr := range attributes {
+		resolvers[i] = &ProductAttributeResolver{*attr}
+	}
+	return resolvers, nil
+}
+
+func (r *ProductResolver) Variants(ctx context.Context) ([]*ProductVariantResolver, error) {
+	variants, err := r.root.loaders(ctx).VariantsByProductID.Load(ctx, r.p.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var attributes []*ProductAttributeResolver
-	for rows.Next() {
-		var attr models.ProductAttribute
-		if err := rows.Scan(&attr.ID, &attr.Name, &attr.Value); err != nil {
-			return nil, err
-		}
-		attributes = append(attributes, &ProductAttributeResolver{attr})
+
+	resolvers := make([]*ProductVariantResolver, len(variants))
+	for i, v := range variants {
+		resolvers[i] = &ProductVariantResolver{r.root, *v}
 	}
+	return resolvers, nil
+}
 
-	return attributes, nil
+func (r *ProductResolver) CustomizationOptions(ctx context.Context) ([]*CustomizationOptionResolver, error) {
+	options, err := r.root.loaders(ctx).OptionsByProductID.Load(ctx, r.p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*CustomizationOptionResolver, len(options))
+	for i, o := range options {
+		resolvers[i] = &CustomizationOptionResolver{r.root, *o}
+	}
+	return resolvers, nil
 }
 
 func (r *ProductResolver) Reviews(ctx context.Context) ([]*ReviewResolver, error) {
-	rows, err := db.DB.Query("SELECT id, rating, comment FROM reviews WHERE product_id = $1", r.p.ID)
+	reviews, err := r.root.loaders(ctx).ReviewsByProductID.Load(ctx, r.p.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var reviews []*ReviewResolver
-	for rows.Next() {
-		var rev models.Review
-		if err := rows.Scan(&rev.ID, &rev.Rating, &rev.Comment); err != nil {
-			return nil, err
-		}
-		reviews = append(reviews, &ReviewResolver{rev})
+
+	resolvers := make([]*ReviewResolver, len(reviews))
+	for i, rev := range reviews {
+		resolvers[i] = &ReviewResolver{r.root, *rev}
+	}
+	return resolvers, nil
+}
+
+func (r *ProductResolver) ReviewsConnection(ctx context.Context, args connectionArgs) (*ReviewConnectionResolver, error) {
+	pageArgs, err := args.pageArgs()
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.root.store.Reviews.PageByProduct(ctx, r.p.ID, pageArgs)
+	if err != nil {
+		return nil, err
 	}
+	return &ReviewConnectionResolver{r.root, page}, nil
+}
+
+// ProductSearchHitResolver resolves the ProductSearchHit type
+type ProductSearchHitResolver struct {
+	root *Resolver
+	h    models.SearchHit
+}
+
+func (r *ProductSearchHitResolver) Product() *ProductResolver {
+	return &ProductResolver{r.root, r.h.Product}
+}
+
+func (r *ProductSearchHitResolver) Score() float64 {
+	return r.h.Score
+}
+
+func (r *ProductSearchHitResolver) Snippet() string {
+	return r.h.Snippet
+}
 
-	return reviews, nil
+func (r *ProductSearchHitResolver) Fuzzy() bool {
+	return r.h.Fuzzy
 }
 
 // ProductImageResolver resolves the ProductImage type
 type ProductImageResolver struct {
-	i models.ProductImage
+	root *Resolver
+	i    models.ProductImage
 }
 
 func (r *ProductImageResolver) ID() graphql.ID {
@@ -2,450 +2,589 @@ package resolvers
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
-	"go-backend/db"
+	"go-backend/auth"
+	"go-backend/loaders"
 	"go-backend/models"
+	"go-backend/payments"
+	"go-backend/pricing"
+	"go-backend/pubsub"
+	"go-backend/storage"
+	"go-backend/store"
 	"strconv"
+	"strings"
 
 	"github.com/graph-gophers/graphql-go"
 )
 
+// Resolver is the root resolver for queries, mutations and subscriptions.
+// Type resolvers keep a pointer back to it so nested fields can reach the
+// store.
 type Resolver struct {
-	Query    *QueryResolver
-	Mutation *MutationResolver
-	ProductR *ProductResolver
-	Category *CategoryResolver
-	OrderR   *OrderResolver
-	Review   *ReviewResolver
-	User     *UserResolver
-	// Add other resolvers as needed
+	store    *store.Store
+	accounts *auth.Accounts
+	pricing  pricing.Rules
+	payments *payments.Processor
+	images   storage.Storage
+	broker   pubsub.Broker
 }
 
-func NewResolver() *Resolver {
+// NewResolver returns the root resolver over s, taking payments through
+// processor. Uploaded images are served from images. Subscriptions wait for
+// events on broker.
+func NewResolver(s *store.Store, processor *payments.Processor, images storage.Storage, broker pubsub.Broker) *Resolver {
 	return &Resolver{
-		Query:    &QueryResolver{},
-		Mutation: &MutationResolver{},
-		ProductR: &ProductResolver{},
-		Category: &CategoryResolver{},
-		OrderR:   &OrderResolver{},
-		Review:   &ReviewResolver{},
-		User:     &UserResolver{},
-		// Initialize other resolvers
+		store:    s,
+		images:   images,
+		broker:   broker,
+		accounts: auth.NewAccounts(s.Users),
+		pricing:  pricing.DefaultRules(),
+		payments: processor,
 	}
 }
 
+// loaders returns the request's loaders, or a throwaway set when the
+// resolver is called without the HTTP middleware (e.g. from tests).
+func (r *Resolver) loaders(ctx context.Context) *loaders.Loaders {
+	if l := loaders.FromContext(ctx); l != nil {
+		return l
+	}
+	return loaders.New(r.store)
+}
+
+// parseID converts a GraphQL ID into a database key.
+func parseID(id graphql.ID) (int32, error) {
+	n, err := strconv.ParseInt(string(id), 10, 32)
+	if err != nil {
+		return 0, newError(codeBadUserInput, "invalid ID %q", id)
+	}
+	return int32(n), nil
+}
+
+// parseOptionalID converts a nullable GraphQL ID argument, mapping null to
+// nil.
+func parseOptionalID(id *graphql.ID) (*int32, error) {
+	if id == nil {
+		return nil, nil
+	}
+	n, err := parseID(*id)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
 // Resolves a single product by ID
 func (r *Resolver) Product(ctx context.Context, args struct{ ID graphql.ID }) (*ProductResolver, error) {
-	var p models.Product
-	err := db.DB.QueryRow("SELECT id, name, description, price, stock_quantity, category_id FROM products WHERE id = $1", args.ID).Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &p.CategoryID)
+	id, err := parseID(args.ID)
 	if err != nil {
 		return nil, err
 	}
-	return &ProductResolver{p}, nil
+	p, err := r.store.Products.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ProductResolver{r, *p}, nil
 }
 
-// Resolves a list of products optionally filtered by category or search string
-func (r *Resolver) Products(ctx context.Context, args struct{ Category, Search *string }) ([]*ProductResolver, error) {
-	var rows *sql.Rows
-	var err error
+// Resolves a list of products, optionally filtered and sorted
+func (r *Resolver) Products(ctx context.Context, args productsArgs) ([]*ProductResolver, error) {
+	filter, err := args.storeFilter()
+	if err != nil {
+		return nil, err
+	}
+	products, err := r.store.Products.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return r.productResolvers(ctx, products), nil
+}
 
-	if args.Category != nil {
-		rows, err = db.DB.Query("SELECT id, name, description, price, stock_quantity, category_id FROM products WHERE category_id = $1", *args.Category)
-	} else if args.Search != nil {
-		searchPattern := "%" + *args.Search + "%"
-		rows, err = db.DB.Query("SELECT id, name, description, price, stock_quantity, category_id FROM products WHERE name ILIKE $1", searchPattern)
-	} else {
-		rows, err = db.DB.Query("SELECT id, name, description, price, stock_quantity, category_id FROM products")
+// Paginated form of Products
+func (r *Resolver) ProductsConnection(ctx context.Context, args struct {
+	productsArgs
+	connectionArgs
+}) (*ProductConnectionResolver, error) {
+	filter, err := args.storeFilter()
+	if err != nil {
+		return nil, err
+	}
+	pageArgs, err := args.pageArgs()
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.store.Products.Page(ctx, filter, pageArgs)
+	if err != nil {
+		return nil, err
 	}
+	return newProductConnection(ctx, r, page), nil
+}
 
+// Ranked full-text search over products
+func (r *Resolver) SearchProducts(ctx context.Context, args struct {
+	Query string
+	First *int32
+	After *string
+}) (*ProductSearchConnectionResolver, error) {
+	if strings.TrimSpace(args.Query) == "" {
+		return nil, newError(codeBadUserInput, "query must not be empty")
+	}
+	pageArgs, err := connectionArgs{First: args.First, After: args.After}.pageArgs()
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.store.Products.Search(ctx, args.Query, pageArgs)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var products []*ProductResolver
-	for rows.Next() {
-		var p models.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &p.CategoryID); err != nil {
-			return nil, err
-		}
-		products = append(products, &ProductResolver{p})
+	products := make([]*models.Product, len(page.Edges))
+	for i, e := range page.Edges {
+		products[i] = &e.Node.Product
 	}
+	r.loaders(ctx).ExpectProducts(products)
+	return &ProductSearchConnectionResolver{r, page}, nil
+}
 
-	return products, nil
+// productsArgs are the arguments of Query.products. The legacy category and
+// search arguments are combined with the filter input.
+type productsArgs struct {
+	Category *graphql.ID
+	Search   *string
+	Filter   *models.ProductFilterInput
+	Sort     *string
 }
 
-// Resolves a list of orders
-func (r *Resolver) Orders(ctx context.Context) ([]*OrderResolver, error) {
-	rows, err := db.DB.Query("SELECT id, user_id, total_amount, status, created_at FROM orders")
-	if err != nil {
-		return nil, err
+func (a productsArgs) storeFilter() (store.ProductFilter, error) {
+	filter := store.ProductFilter{Search: a.Search}
+	if a.Category != nil {
+		id, err := parseID(*a.Category)
+		if err != nil {
+			return filter, err
+		}
+		filter.CategoryID = &id
+	}
+	if a.Sort != nil {
+		filter.Sort = store.ProductSort(*a.Sort)
 	}
-	defer rows.Close()
 
-	var orders []*OrderResolver
-	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.UserID, &o.TotalAmount, &o.Status, &o.CreatedAt); err != nil {
-			return nil, err
+	in := a.Filter
+	if in == nil {
+		return filter, nil
+	}
+	if in.CategoryID != nil {
+		if filter.CategoryID != nil {
+			return filter, fmt.Errorf("category and filter.categoryId cannot both be set")
+		}
+		id, err := parseID(*in.CategoryID)
+		if err != nil {
+			return filter, err
+		}
+		filter.CategoryID = &id
+		filter.IncludeSubcategories = in.IncludeSubcategories == nil || *in.IncludeSubcategories
+	}
+	if in.Search != nil {
+		if filter.Search != nil {
+			return filter, fmt.Errorf("search and filter.search cannot both be set")
+		}
+		filter.Search = in.Search
+	}
+	if in.MinPrice != nil && in.MaxPrice != nil && *in.MinPrice > *in.MaxPrice {
+		return filter, fmt.Errorf("minPrice must not exceed maxPrice")
+	}
+	filter.MinPrice = in.MinPrice
+	filter.MaxPrice = in.MaxPrice
+	filter.InStock = in.InStock != nil && *in.InStock
+	if in.Attributes != nil {
+		for _, attr := range *in.Attributes {
+			filter.Attributes = append(filter.Attributes, store.AttributeFilter{Name: attr.Name, Value: attr.Value})
 		}
-		orders = append(orders, &OrderResolver{o})
 	}
+	filter.MinRating = in.MinRating
+	return filter, nil
+}
 
-	return orders, nil
+// productResolvers wraps a product list and primes the loaders for the
+// nested fields that are likely to be requested on it.
+func (r *Resolver) productResolvers(ctx context.Context, products []*models.Product) []*ProductResolver {
+	r.loaders(ctx).ExpectProducts(products)
+	resolvers := make([]*ProductResolver, len(products))
+	for i, p := range products {
+		resolvers[i] = &ProductResolver{r, *p}
+	}
+	return resolvers
 }
 
-func (r *Resolver) Order(ctx context.Context, args struct{ ID graphql.ID }) (*OrderResolver, error) {
-	id, err := strconv.Atoi(string(args.ID))
-	if err != nil {
-		return nil, fmt.Errorf("invalid ID: %v", err)
+// Resolves a list of orders
+func (r *Resolver) Orders(ctx context.Context) ([]*OrderResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleAdmin); err != nil {
+		return nil, err
 	}
-	var o models.Order
-	err = db.DB.QueryRow("SELECT id, user_id, total_amount, status, created_at FROM orders WHERE id = $1", id).Scan(&o.ID, &o.UserID, &o.TotalAmount, &o.Status, &o.CreatedAt)
+	orders, err := r.store.Orders.List(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &OrderResolver{o}, nil
+	return r.orderResolvers(orders), nil
 }
 
-func (r *Resolver) CreateProduct(ctx context.Context, args struct{ Input models.ProductInput }) (*ProductResolver, error) {
-	tx, err := db.DB.BeginTx(ctx, nil)
+func (r *Resolver) orderResolvers(orders []*models.Order) []*OrderResolver {
+	resolvers := make([]*OrderResolver, len(orders))
+	for i, o := range orders {
+		resolvers[i] = &OrderResolver{r, *o}
+	}
+	return resolvers
+}
+
+func (r *Resolver) Order(ctx context.Context, args struct{ ID graphql.ID }) (*OrderResolver, error) {
+	id, err := parseID(args.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback()
-
-	stockQuantity := int(args.Input.StockQuantity)
-	categoryID := int(args.Input.CategoryID)
-
-	var categoryExists bool
-	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1)", categoryID).Scan(&categoryExists)
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	o, err := r.store.Orders.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	if !categoryExists {
-		return nil, fmt.Errorf("category with ID %d does not exist", categoryID)
+	if _, err := requireSelf(ctx, o.UserID); err != nil {
+		return nil, err
 	}
+	return &OrderResolver{r, *o}, nil
+}
 
-	var productID int32
-	err = tx.QueryRow(`
-        INSERT INTO products (name, description, price, stock_quantity, category_id)
-        VALUES ($1, $2, $3, $4, $5)
-        RETURNING id
-    `, args.Input.Name, args.Input.Description, args.Input.Price, stockQuantity, categoryID).Scan(&productID)
+func (r *Resolver) CreateProduct(ctx context.Context, args struct{ Input models.ProductInput }) (*ProductResolver, error) {
+	seller, err := requireRole(ctx, auth.RoleSeller)
 	if err != nil {
 		return nil, err
 	}
-
-	if len(args.Input.Images) > 0 {
-		for _, imgInput := range args.Input.Images {
-			_, err = tx.Exec(`
-				INSERT INTO product_images (product_id, image_url, is_primary)
-				VALUES ($1, $2, $3)
-			`, productID, imgInput.ImageUrl, imgInput.IsPrimary)
-			if err != nil {
-				return nil, err
-			}
-		}
+	if err := r.checkImages(ctx, seller, args.Input.Images); err != nil {
+		return nil, err
 	}
-
-	if err = tx.Commit(); err != nil {
+	var attributes []models.ProductAttributeInput
+	if args.Input.Attributes != nil {
+		attributes = *args.Input.Attributes
+	}
+	if err := r.checkProductAttributes(ctx, args.Input.CategoryID, attributes); err != nil {
 		return nil, err
 	}
-
-	var p models.Product
-	err = db.DB.QueryRow(`
-        SELECT id, name, description, price, stock_quantity, category_id
-        FROM products WHERE id = $1
-    `, productID).Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &p.CategoryID)
+	p, err := r.store.Products.Create(ctx, args.Input)
 	if err != nil {
 		return nil, err
 	}
-
-	p.Category = &models.Category{ID: p.CategoryID}
-
-	return &ProductResolver{p: p}, nil
+	return &ProductResolver{r, *p}, nil
 }
 
 func (r *Resolver) AddProductImage(ctx context.Context, args struct {
 	ProductID graphql.ID
 	Input     models.ProductImageInput
 }) (*ProductImageResolver, error) {
-	productID, err := strconv.Atoi(string(args.ProductID))
+	seller, err := requireRole(ctx, auth.RoleSeller)
 	if err != nil {
-		return nil, fmt.Errorf("invalid product ID: %v", err)
+		return nil, err
 	}
-
-	var imageID int32
-	err = db.DB.QueryRow(`
-        INSERT INTO product_images (product_id, image_url, is_primary)
-        VALUES ($1, $2, $3)
-        RETURNING id
-    `, productID, args.Input.ImageUrl, args.Input.IsPrimary).Scan(&imageID)
+	productID, err := parseID(args.ProductID)
 	if err != nil {
 		return nil, err
 	}
-
-	img := models.ProductImage{
-		ID:        imageID,
-		ImageUrl:  args.Input.ImageUrl,
-		IsPrimary: args.Input.IsPrimary,
+	input := []models.ProductImageInput{args.Input}
+	if err := r.checkImages(ctx, seller, input); err != nil {
+		return nil, err
 	}
-
-	return &ProductImageResolver{i: img}, nil
+	img, err := r.store.Products.AddImage(ctx, productID, input[0])
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newError(codeNotFound, "product %d not found", productID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ProductImageResolver{r, *img}, nil
 }
 
 func (r *Resolver) UpdateProduct(ctx context.Context, args struct {
 	ID    graphql.ID
 	Input models.ProductInput
 }) (*ProductResolver, error) {
-	id, err := strconv.Atoi(string(args.ID))
+	seller, err := requireRole(ctx, auth.RoleSeller)
 	if err != nil {
-		return nil, fmt.Errorf("invalid ID: %v", err)
+		return nil, err
 	}
-	// Start a transaction
-	tx, err := db.DB.BeginTx(ctx, nil)
+	id, err := parseID(args.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback()
-	stockQuantity := int(args.Input.StockQuantity)
-
-	// Update the product
-	_, err = tx.Exec(`
-        UPDATE products
-        SET name = $1, description = $2, price = $3, stock_quantity = $4, category_id = $5
-        WHERE id = $6
-    `, args.Input.Name, args.Input.Description, args.Input.Price, stockQuantity, args.Input.CategoryID, id)
-	if err != nil {
+	if err := r.checkImages(ctx, seller, args.Input.Images); err != nil {
 		return nil, err
 	}
-
-	_, err = tx.Exec("DELETE FROM images WHERE product_id = $1", id)
-	if err != nil {
+	if err := r.checkUpdatedAttributes(ctx, id, args.Input); err != nil {
 		return nil, err
 	}
-
-	for _, imgInput := range args.Input.Images {
-		_, err = tx.Exec(`
-            INSERT INTO images (product_id, url, is_primary)
-            VALUES ($1, $2, $3)
-        `, id, imgInput.ImageUrl, imgInput.IsPrimary)
-		if err != nil {
-			return nil, err
-		}
+	p, err := r.store.Products.Update(ctx, id, args.Input)
+	if err != nil {
+		return nil, userError(err)
 	}
+	return &ProductResolver{r, *p}, nil
+}
 
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		return nil, err
+// checkUpdatedAttributes validates the attributes a product will have after
+// an update: the new ones, or its current ones when it moves category.
+func (r *Resolver) checkUpdatedAttributes(ctx context.Context, id int32, input models.ProductInput) error {
+	if input.Attributes != nil {
+		return r.checkProductAttributes(ctx, input.CategoryID, *input.Attributes)
 	}
-
-	// Fetch the updated product
-	var p models.Product
-	err = db.DB.QueryRow(`
-        SELECT id, name, description, price, stock_quantity, category_id
-        FROM products WHERE id = $1
-    `, id).Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &p.CategoryID)
+	p, err := r.existingProduct(ctx, id)
+	if err != nil || p.CategoryID == input.CategoryID {
+		return err
+	}
+	current, err := r.store.Products.Attributes(ctx, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return &ProductResolver{p}, nil
+	attributes := make([]models.ProductAttributeInput, len(current))
+	for i, a := range current {
+		attributes[i] = models.ProductAttributeInput{Name: a.Name, Value: a.Value}
+	}
+	return r.checkProductAttributes(ctx, input.CategoryID, attributes)
 }
 
 func (r *Resolver) DeleteProduct(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
-	id, err := strconv.Atoi(string(args.ID))
-	if err != nil {
-		return false, fmt.Errorf("invalid ID: %v", err)
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return false, err
 	}
-
-	result, err := db.DB.Exec("DELETE FROM products WHERE id = $1", id)
+	id, err := parseID(args.ID)
 	if err != nil {
 		return false, err
 	}
+	return r.store.Products.Delete(ctx, id)
+}
 
-	rowsAffected, err := result.RowsAffected()
+// Places an order for the caller
+func (r *Resolver) CreateOrder(ctx context.Context, args struct {
+	Input          models.OrderInput
+	IdempotencyKey *string
+}) (*OrderResolver, error) {
+	userID, err := actingUser(ctx, args.Input.UserID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-
-	return rowsAffected > 0, nil
+	p := auth.FromContext(ctx)
+	resp, err := idempotent(ctx, r.store.Idempotency, p.UserID, idempotencyKey(ctx, args.IdempotencyKey), "createOrder", args.Input,
+		func() (orderResponse, error) {
+			// Stock the buyer reserved in checkout is theirs to order.
+			cart, err := r.findCart(ctx, store.CartOwner{UserID: userID})
+			if err != nil {
+				return orderResponse{}, err
+			}
+			var cartID int32
+			if cart != nil {
+				cartID = cart.ID
+			}
+			o, err := r.placeOrder(ctx, userID, args.Input, cartID)
+			if err != nil {
+				return orderResponse{}, err
+			}
+			return newOrderResponse(o), nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return resp.resolver(r), nil
 }
 
-func (r *Resolver) CreateOrder(ctx context.Context, args struct{ Input models.OrderInput }) (*OrderResolver, error) {
-	// Start a transaction
-	tx, err := db.DB.BeginTx(ctx, nil)
+// placeOrder prices and stores an order for userID, counting the stock
+// reserved for cartID (if not zero) towards it.
+func (r *Resolver) placeOrder(ctx context.Context, userID int32, input models.OrderInput, cartID int32) (*models.Order, error) {
+	order, err := r.priceOrder(ctx, input)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback()
+	order.CartID = cartID
+	o, err := r.store.Orders.Create(ctx, userID, *order)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return o, nil
+}
 
-	// Insert the new order
-	var orderID int
-	err = tx.QueryRow(`
-        INSERT INTO orders (user_id, total_amount, status)
-        VALUES ($1, $2, $3)
-        RETURNING id
-    `, args.Input.UserID, args.Input.TotalAmount, "PENDING").Scan(&orderID)
+// priceOrder prices input at the current prices of the products or
+// variants, plus the price modifiers of the chosen customizations. A
+// totalAmount sent by the client is only checked against the computed
+// total, so a client showing stale prices finds out before the order is
+// placed.
+func (r *Resolver) priceOrder(ctx context.Context, input models.OrderInput) (*store.NewOrder, error) {
+	if len(input.Items) == 0 {
+		return nil, newError(codeBadUserInput, "an order needs at least one item")
+	}
+	ids := make([]int32, len(input.Items))
+	var variantIDs []int32
+	for i, item := range input.Items {
+		ids[i] = item.ProductID
+		if item.VariantID != nil {
+			variantIDs = append(variantIDs, *item.VariantID)
+		}
+	}
+	products, err := r.store.Products.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := r.store.Products.GetVariants(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	// Products sold in variants can only be ordered as one of them.
+	sold, err := r.store.Products.VariantsByProducts(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
 
-	// Insert order items
-	for _, item := range args.Input.Items {
-		_, err = tx.Exec(`
-            INSERT INTO order_items (order_id, product_id, quantity, price_at_time)
-            VALUES ($1, $2, $3, (SELECT price FROM products WHERE id = $2))
-        `, orderID, item.ProductID, item.Quantity)
+	lines := make([]pricing.Line, len(input.Items))
+	chosen := make([]*models.ProductVariant, len(input.Items))
+	customizations := make([][]models.Customization, len(input.Items))
+	for i, item := range input.Items {
+		p, ok := products[item.ProductID]
+		if !ok {
+			return nil, newError(codeNotFound, "product %d not found", item.ProductID)
+		}
+		if item.Quantity <= 0 {
+			return nil, newError(codeBadUserInput, "quantity of product %d must be positive", item.ProductID)
+		}
+		price := p.Price
+		if item.VariantID != nil {
+			v, ok := variants[*item.VariantID]
+			if !ok || v.ProductID != p.ID {
+				return nil, newError(codeNotFound, "variant %d of product %d not found", *item.VariantID, p.ID)
+			}
+			chosen[i], price = v, v.Price
+		} else if len(sold[p.ID]) > 0 {
+			return nil, newError(codeBadUserInput, "%q is sold in variants; choose one", p.Name)
+		}
+		var extra pricing.Cents
+		customizations[i], extra, err = r.customize(ctx, p.ID, item.Customizations)
 		if err != nil {
 			return nil, err
 		}
+		lines[i] = pricing.Line{ProductID: p.ID, Quantity: item.Quantity, UnitPrice: pricing.FromFloat(price) + extra}
 	}
-
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		return nil, err
-	}
-
-	// Fetch the newly created order
-	var o models.Order
-	err = db.DB.QueryRow(`
-        SELECT id, user_id, total_amount, status, created_at
-        FROM orders WHERE id = $1
-    `, orderID).Scan(&o.ID, &o.UserID, &o.TotalAmount, &o.Status, &o.CreatedAt)
+	quote, err := r.pricing.Price(lines)
 	if err != nil {
 		return nil, err
 	}
 
-	return &OrderResolver{o}, nil
+	if input.TotalAmount != nil && pricing.FromFloat(*input.TotalAmount) != quote.Total {
+		e := newError(codeTotalMismatch, "order total is %s, not %s", quote.Total, pricing.FromFloat(*input.TotalAmount))
+		e.Details = map[string]interface{}{"expectedTotal": quote.Total.Float()}
+		return nil, e
+	}
+
+	order := &store.NewOrder{
+		Subtotal:      quote.Subtotal.Float(),
+		DiscountTotal: quote.Discount.Float(),
+		TaxTotal:      quote.Tax.Float(),
+		ShippingTotal: quote.Shipping.Float(),
+		Total:         quote.Total.Float(),
+		Items:         make([]store.NewOrderItem, len(quote.Lines)),
+	}
+	for i, l := range quote.Lines {
+		order.Items[i] = store.NewOrderItem{
+			ProductID: l.ProductID,
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice.Float(),
+			Discount:  l.Discount.Float(),
+			Tax:       l.Tax.Float(),
+			LineTotal: l.Total.Float(),
+		}
+		if v := chosen[i]; v != nil {
+			order.Items[i].VariantID = v.ID
+			order.Items[i].SKU = v.SKU
+		}
+		order.Items[i].Customizations = customizations[i]
+	}
+	return order, nil
 }
 
+// Records a review by the caller
 func (r *Resolver) CreateReview(ctx context.Context, args struct{ Input models.ReviewInput }) (*ReviewResolver, error) {
-	// Insert the new review
-	var reviewID int
-	err := db.DB.QueryRow(`
-        INSERT INTO reviews (product_id, user_id, rating, comment)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id
-    `, args.Input.ProductID, args.Input.UserID, args.Input.Rating, args.Input.Comment).Scan(&reviewID)
+	userID, err := actingUser(ctx, args.Input.UserID)
 	if err != nil {
 		return nil, err
 	}
-
-	// Fetch the newly created review
-	var rev models.Review
-	err = db.DB.QueryRow(`
-        SELECT id, product_id, user_id, rating, comment, created_at
-        FROM reviews WHERE id = $1
-    `, reviewID).Scan(&rev.ID, &rev.ProductID, &rev.UserID, &rev.Rating, &rev.Comment, &rev.CreatedAt)
+	rev, err := r.store.Reviews.Create(ctx, userID, args.Input)
 	if err != nil {
 		return nil, err
 	}
-
-	return &ReviewResolver{rev}, nil
+	return &ReviewResolver{r, *rev}, nil
 }
 
 func (r *Resolver) UserOrders(ctx context.Context, args struct{ UserID graphql.ID }) ([]*OrderResolver, error) {
-	userID, err := strconv.Atoi(string(args.UserID))
+	userID, err := parseID(args.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid user ID: %v", err)
+		return nil, err
 	}
-
-	rows, err := db.DB.Query("SELECT id, user_id, total_amount, status, created_at FROM orders WHERE user_id = $1", userID)
+	if _, err := requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	orders, err := r.store.Orders.ListByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return r.orderResolvers(orders), nil
+}
 
-	var orders []*OrderResolver
-	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.UserID, &o.TotalAmount, &o.Status, &o.CreatedAt); err != nil {
-			return nil, err
-		}
-		orders = append(orders, &OrderResolver{o})
+// Paginated form of UserOrders, newest first
+func (r *Resolver) UserOrdersConnection(ctx context.Context, args struct {
+	UserID graphql.ID
+	connectionArgs
+}) (*OrderConnectionResolver, error) {
+	userID, err := parseID(args.UserID)
+	if err != nil {
+		return nil, err
 	}
-
-	return orders, nil
+	if _, err := requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	pageArgs, err := args.pageArgs()
+	if err != nil {
+		return nil, err
+	}
+	page, err := r.store.Orders.PageByUser(ctx, userID, pageArgs)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderConnectionResolver{r, page}, nil
 }
 
 // Resolves a list of all categories
 func (r *Resolver) Categories(ctx context.Context) ([]*CategoryResolver, error) {
-	rows, err := db.DB.Query("SELECT id, name, parent_id FROM categories")
+	categories, err := r.store.Categories.List(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var categories []*CategoryResolver
-	for rows.Next() {
-		var c models.Category
-		var parentID sql.NullInt32
-		if err := rows.Scan(&c.ID, &c.Name, &parentID); err != nil {
-			return nil, err
-		}
-
-		if parentID.Valid {
-			c.ParentCategory = &models.Category{ID: parentID.Int32}
-		}
-
-		categories = append(categories, &CategoryResolver{c})
-	}
-
-	return categories, nil
+	return r.categoryResolvers(categories), nil
 }
 
 func (r *Resolver) CreateCategory(ctx context.Context, args struct{ Input models.CategoryInput }) (*CategoryResolver, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return nil, err
+	}
 	var parentID *int32
 	if args.Input.ParentID != nil {
-		id, err := strconv.ParseInt(*args.Input.ParentID, 10, 32)
+		id, err := parseID(graphql.ID(*args.Input.ParentID))
 		if err != nil {
 			return nil, fmt.Errorf("invalid parent ID: %v", err)
 		}
-		parentIDInt32 := int32(id)
-		parentID = &parentIDInt32
-	}
-
-	// Start a transaction
-	tx, err := db.DB.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
-
-	// Insert the new category
-	var categoryID int32
-	err = tx.QueryRow(`
-        INSERT INTO categories (name, parent_id)
-        VALUES ($1, $2)
-        RETURNING id
-    `, args.Input.Name, parentID).Scan(&categoryID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		return nil, err
+		parentID = &id
 	}
 
-	// Fetch the newly created category
-	var c models.Category
-	err = db.DB.QueryRow(`
-        SELECT id, name, parent_id
-        FROM categories WHERE id = $1
-    `, categoryID).Scan(&c.ID, &c.Name, &c.ParentCategory)
+	c, err := r.store.Categories.Create(ctx, args.Input.Name, parentID)
 	if err != nil {
 		return nil, err
 	}
-
-	return &CategoryResolver{c}, nil
+	return &CategoryResolver{r, *c}, nil
 }
-
-// ProductResolver resolves the Product type
-
-// ReviewResolver resolves the Review type
-
-// CategoryResolver resolves the Category type
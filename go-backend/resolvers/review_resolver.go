@@ -3,14 +3,14 @@ package resolvers
 import (
 	"context"
 	"fmt"
-	"go-backend/db"
 	"go-backend/models"
 
 	"github.com/graph-gophers/graphql-go"
 )
 
 type ReviewResolver struct {
-	r models.Review
+	root *Resolver
+	r    models.Review
 }
 
 func (r *ReviewResolver) ID() graphql.ID {
@@ -29,19 +29,23 @@ func (r *ReviewResolver) CreatedAt() string {
 }
 
 func (r *ReviewResolver) Product(ctx context.Context) (*ProductResolver, error) {
-	var p models.Product
-	err := db.DB.QueryRow("SELECT id, name, description, price, stock_quantity, category_id FROM products WHERE id = $1", r.r.ProductID).Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &p.CategoryID)
+	p, err := r.root.loaders(ctx).ProductByID.Load(ctx, r.r.ProductID)
 	if err != nil {
 		return nil, err
 	}
-	return &ProductResolver{p}, nil
+	if p == nil {
+		return nil, fmt.Errorf("product %d not found", r.r.ProductID)
+	}
+	return &ProductResolver{r.root, *p}, nil
 }
 
 func (r *ReviewResolver) User(ctx context.Context) (*UserResolver, error) {
-	var u models.User
-	err := db.DB.QueryRow("SELECT id, email, first_name, last_name FROM users WHERE id = $1", r.r.UserID).Scan(&u.ID, &u.Email, &u.FirstName, &u.LastName)
+	u, err := r.root.loaders(ctx).UserByID.Load(ctx, r.r.UserID)
 	if err != nil {
 		return nil, err
 	}
-	return &UserResolver{u}, nil
+	if u == nil {
+		return nil, fmt.Errorf("user %d not found", r.r.UserID)
+	}
+	return &UserResolver{r.root, *u, false}, nil
 }
@@ -0,0 +1,162 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-backend/auth"
+	"go-backend/events"
+	"go-backend/orderstatus"
+	"go-backend/store"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// subscribe returns the messages published on topic, and a function to
+// stop them that must be called once the subscription is over.
+func (r *Resolver) subscribe(ctx context.Context, topic string) (context.Context, <-chan []byte, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	msgs, err := r.broker.Subscribe(ctx, topic)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	return ctx, msgs, cancel, nil
+}
+
+// relay sends what next makes of each message on the returned channel
+// until ctx is done or next says to stop, then calls cancel and closes the
+// channel, which completes the subscription. next returns nil for
+// messages to skip.
+func relay[T any](ctx context.Context, msgs <-chan []byte, cancel context.CancelFunc, next func(msg []byte) (v *T, stop bool)) <-chan *T {
+	out := make(chan *T)
+	go func() {
+		defer cancel()
+		defer close(out)
+		for msg := range msgs {
+			v, stop := next(msg)
+			if v != nil {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if stop {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// closed returns a channel that completes a subscription straight away.
+func closed[T any]() <-chan *T {
+	out := make(chan *T)
+	close(out)
+	return out
+}
+
+// mayWatch reports whether p may still follow an order of ownerID. It is
+// asked for every event, so a subscription ends once its user is deleted
+// or an admin loses the role.
+func (r *Resolver) mayWatch(ctx context.Context, p *auth.Principal, ownerID int32) bool {
+	u, err := r.store.Users.Get(ctx, p.UserID)
+	if err != nil {
+		return false
+	}
+	return u.ID == ownerID || auth.Role(u.Role) == auth.RoleAdmin
+}
+
+// Streams an order each time it changes, completing once it reaches a
+// final status
+func (r *Resolver) OrderUpdated(ctx context.Context, args struct{ OrderID graphql.ID }) (<-chan *OrderResolver, error) {
+	id, err := parseID(args.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Subscribe before looking at the order, so no change slips in
+	// between.
+	ctx, msgs, cancel, err := r.subscribe(ctx, events.OrderTopic(id))
+	if err != nil {
+		return nil, err
+	}
+	o, err := r.store.Orders.Get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		err = newError(codeNotFound, "order %d not found", id)
+	} else if err == nil {
+		_, err = requireSelf(ctx, o.UserID)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if orderstatus.Status(o.Status).Final() {
+		cancel()
+		return closed[OrderResolver](), nil
+	}
+
+	return relay(ctx, msgs, cancel, func(msg []byte) (*OrderResolver, bool) {
+		o, err := r.store.Orders.Get(ctx, id)
+		if err != nil || !r.mayWatch(ctx, p, o.UserID) {
+			return nil, true
+		}
+		return &OrderResolver{r, *o}, orderstatus.Status(o.Status).Final()
+	}), nil
+}
+
+// Streams the caller's orders as they are placed and change
+func (r *Resolver) MyOrdersUpdated(ctx context.Context) (<-chan *OrderResolver, error) {
+	p, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, msgs, cancel, err := r.subscribe(ctx, events.UserOrdersTopic(p.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	return relay(ctx, msgs, cancel, func(msg []byte) (*OrderResolver, bool) {
+		var e events.OrderUpdated
+		if err := json.Unmarshal(msg, &e); err != nil {
+			return nil, false
+		}
+		o, err := r.store.Orders.Get(ctx, e.OrderID)
+		if err != nil || !r.mayWatch(ctx, p, o.UserID) {
+			return nil, true
+		}
+		return &OrderResolver{r, *o}, false
+	}), nil
+}
+
+// Streams a product each time its stock, or that of one of its variants,
+// changes, completing if it is deleted
+func (r *Resolver) ProductStockChanged(ctx context.Context, args struct{ ProductID graphql.ID }) (<-chan *ProductResolver, error) {
+	id, err := parseID(args.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, msgs, cancel, err := r.subscribe(ctx, events.StockTopic(id))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.store.Products.Get(ctx, id); err != nil {
+		cancel()
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, newError(codeNotFound, "product %d not found", id)
+		}
+		return nil, err
+	}
+
+	return relay(ctx, msgs, cancel, func(msg []byte) (*ProductResolver, bool) {
+		p, err := r.store.Products.Get(ctx, id)
+		if err != nil {
+			return nil, true
+		}
+		return &ProductResolver{r, *p}, false
+	}), nil
+}
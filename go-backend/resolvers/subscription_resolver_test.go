@@ -0,0 +1,156 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-backend/auth"
+	"go-backend/events"
+	"go-backend/models"
+	"go-backend/orderstatus"
+	"go-backend/payments"
+	"go-backend/pubsub"
+	"go-backend/store"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// newSubscriptionResolver returns a Resolver whose store announces its
+// changes, as main sets it up.
+func newSubscriptionResolver(t *testing.T) (*Resolver, *store.Store) {
+	t.Helper()
+	broker := pubsub.NewMemory()
+	st := events.Notify(store.NewMemory(), broker)
+	return NewResolver(st, payments.NewProcessor(payments.NewFake([]byte("secret")), st.Orders, st.Payments), nil, broker), st
+}
+
+// next returns the next value sent on ch, or nil once ch is closed.
+func next[T any](t *testing.T, ch <-chan *T) *T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the subscription")
+		return nil
+	}
+}
+
+func TestOrderUpdated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, st := newSubscriptionResolver(t)
+	var users []context.Context
+	for _, u := range []struct {
+		email string
+		role  auth.Role
+	}{{"ann@example.com", auth.RoleCustomer}, {"bob@example.com", auth.RoleCustomer}, {"eve@example.com", auth.RoleAdmin}} {
+		created, err := st.Users.Create(ctx, store.NewUser{Email: u.email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Users.SetRole(ctx, created.ID, string(u.role)); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, auth.WithPrincipal(ctx, &auth.Principal{UserID: created.ID, Role: u.role}))
+	}
+	owner, other, admin := users[0], users[1], users[2]
+
+	cat, err := st.Categories.Create(ctx, "Mugs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := st.Products.Create(ctx, models.ProductInput{Name: "Mug", Price: 12.99, StockQuantity: 5, CategoryID: cat.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := r.CreateOrder(owner, struct {
+		Input          models.OrderInput
+		IdempotencyKey *string
+	}{models.OrderInput{Items: []*models.OrderItemInput{{ProductID: p.ID, Quantity: 1}}}, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := struct{ OrderID graphql.ID }{graphql.ID(fmt.Sprint(o.o.ID))}
+
+	var e *Error
+	if _, err := r.OrderUpdated(other, args); !errors.As(err, &e) || e.Code != codeForbidden {
+		t.Errorf("following another user's order: error = %v, want %s", err, codeForbidden)
+	}
+
+	updates, err := r.OrderUpdated(owner, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setStatus := func(status orderstatus.Status) {
+		t.Helper()
+		if _, err := r.UpdateOrderStatus(admin, struct {
+			ID     graphql.ID
+			Status string
+			Note   *string
+		}{args.OrderID, string(status), nil}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The subscription follows the order to a final status, then completes.
+	setStatus(orderstatus.Cancelled)
+	if got := next(t, updates); got == nil || got.o.Status != string(orderstatus.Cancelled) {
+		t.Fatalf("got %v, want the cancelled order", got)
+	}
+	if got := next(t, updates); got != nil {
+		t.Errorf("got %s after the final status, want the subscription completed", got.o.Status)
+	}
+
+	// Following an order that is already final completes straight away.
+	updates, err = r.OrderUpdated(owner, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := next(t, updates); got != nil {
+		t.Errorf("got %s, want the subscription completed", got.o.Status)
+	}
+}
+
+func TestProductStockChanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, st := newSubscriptionResolver(t)
+	cat, err := st.Categories.Create(ctx, "Mugs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := models.ProductInput{Name: "Mug", Price: 12.99, StockQuantity: 5, CategoryID: cat.ID}
+	p, err := st.Products.Create(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := r.ProductStockChanged(ctx, struct{ ProductID graphql.ID }{graphql.ID(fmt.Sprint(p.ID))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input.StockQuantity = 8
+	if _, err := st.Products.Update(ctx, p.ID, input); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(t, changes); got == nil || got.p.StockQuantity != 8 {
+		t.Fatalf("got %v, want the restocked product", got)
+	}
+
+	// Deleting the product completes the subscription.
+	if _, err := st.Products.Delete(ctx, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(t, changes); got != nil {
+		t.Errorf("got stock %d after deleting the product, want the subscription completed", got.p.StockQuantity)
+	}
+
+	var e *Error
+	if _, err := r.ProductStockChanged(ctx, struct{ ProductID graphql.ID }{graphql.ID(fmt.Sprint(p.ID))}); !errors.As(err, &e) || e.Code != codeNotFound {
+		t.Errorf("following a deleted product: error = %v, want %s", err, codeNotFound)
+	}
+}
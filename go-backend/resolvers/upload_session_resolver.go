@@ -0,0 +1,177 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-backend/auth"
+	"go-backend/events"
+	"go-backend/models"
+	"go-backend/store"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// QrUploadSessionResolver resolves the QrUploadSession type
+type QrUploadSessionResolver struct {
+	root *Resolver
+	s    models.UploadSession
+}
+
+func (r *QrUploadSessionResolver) ID() graphql.ID {
+	return graphql.ID(r.s.ID)
+}
+
+func (r *QrUploadSessionResolver) Status() string {
+	return r.s.Status
+}
+
+func (r *QrUploadSessionResolver) Product(ctx context.Context) (*ProductResolver, error) {
+	if r.s.ProductID == nil {
+		return nil, nil
+	}
+	p, err := r.root.loaders(ctx).ProductByID.Load(ctx, *r.s.ProductID)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return &ProductResolver{r.root, *p}, nil
+}
+
+func (r *QrUploadSessionResolver) MaxUploads() int32 {
+	return r.s.MaxUploads
+}
+
+func (r *QrUploadSessionResolver) UploadCount() int32 {
+	return r.s.UploadCount
+}
+
+func (r *QrUploadSessionResolver) ExpiresAt() string {
+	return r.s.ExpiresAt
+}
+
+func (r *QrUploadSessionResolver) RevokedAt() *string {
+	return r.s.RevokedAt
+}
+
+func (r *QrUploadSessionResolver) CreatedAt() string {
+	return r.s.CreatedAt
+}
+
+func (r *QrUploadSessionResolver) Files(ctx context.Context) ([]*ImageResolver, error) {
+	images, err := r.root.store.Images.ListBySession(ctx, r.s.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int32, len(images))
+	for i, img := range images {
+		ids[i] = img.ID
+	}
+	r.root.loaders(ctx).RenditionsByImageID.Expect(ids...)
+
+	resolvers := make([]*ImageResolver, len(images))
+	for i, img := range images {
+		resolvers[i] = &ImageResolver{r.root, *img}
+	}
+	return resolvers, nil
+}
+
+// ownSession returns the upload session id if it belongs to the caller or
+// the caller is an admin, and nil otherwise, so other users' sessions look
+// the same as missing ones.
+func (r *Resolver) ownSession(ctx context.Context, id graphql.ID) (*models.UploadSession, error) {
+	p, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.store.Sessions.Get(ctx, string(id))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.UserID != p.UserID && p.Role != auth.RoleAdmin {
+		return nil, nil
+	}
+	return s, nil
+}
+
+func (r *Resolver) QrUploadSession(ctx context.Context, args struct{ ID graphql.ID }) (*QrUploadSessionResolver, error) {
+	s, err := r.ownSession(ctx, args.ID)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	return &QrUploadSessionResolver{r, *s}, nil
+}
+
+// Closes a QR upload session to further uploads
+func (r *Resolver) RevokeQrUploadSession(ctx context.Context, args struct{ ID graphql.ID }) (*QrUploadSessionResolver, error) {
+	s, err := r.ownSession(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, newError(codeNotFound, "upload session %s not found", args.ID)
+	}
+	s, err = r.store.Sessions.Revoke(ctx, s.ID)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &QrUploadSessionResolver{r, *s}, nil
+}
+
+// ImageUploadedEventResolver resolves the ImageUploadedEvent type
+type ImageUploadedEventResolver struct {
+	image   *ImageResolver
+	session *QrUploadSessionResolver
+}
+
+func (r *ImageUploadedEventResolver) Image() *ImageResolver {
+	return r.image
+}
+
+func (r *ImageUploadedEventResolver) Session() *QrUploadSessionResolver {
+	return r.session
+}
+
+// Streams the files uploaded to one of the caller's QR upload sessions,
+// completing after the upload that closes the session
+func (r *Resolver) ImageUploaded(ctx context.Context, args struct{ SessionID graphql.ID }) (<-chan *ImageUploadedEventResolver, error) {
+	// Subscribe before looking at the session, so no upload slips in
+	// between.
+	ctx, msgs, cancel, err := r.subscribe(ctx, events.ImageUploadedTopic(string(args.SessionID)))
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.ownSession(ctx, args.SessionID)
+	if err == nil && s == nil {
+		err = newError(codeNotFound, "upload session %s not found", args.SessionID)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if s.Status != "ACTIVE" {
+		cancel()
+		return closed[ImageUploadedEventResolver](), nil
+	}
+
+	return relay(ctx, msgs, cancel, func(msg []byte) (*ImageUploadedEventResolver, bool) {
+		var e events.ImageUploaded
+		if err := json.Unmarshal(msg, &e); err != nil {
+			return nil, false
+		}
+		img, err := r.store.Images.Get(ctx, e.ImageID)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, false
+		}
+		if err != nil {
+			return nil, true
+		}
+		session, err := r.store.Sessions.Get(ctx, s.ID)
+		if err != nil {
+			return nil, true
+		}
+		return &ImageUploadedEventResolver{&ImageResolver{r, *img}, &QrUploadSessionResolver{r, *session}}, session.Status != "ACTIVE"
+	}), nil
+}
@@ -0,0 +1,52 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-backend/auth"
+	"go-backend/store"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Another user's session looks the same as a missing one.
+func TestRevokeQrUploadSession(t *testing.T) {
+	ctx := context.Background()
+	r, st := newTestResolver(t)
+	var users []int32
+	for _, email := range []string{"ann@example.com", "bob@example.com"} {
+		u, err := st.Users.Create(ctx, store.NewUser{Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u.ID)
+	}
+	if _, err := st.Sessions.Create(ctx, store.NewUploadSession{ID: "qr", UserID: users[0], MaxUploads: 5, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	owner := auth.WithPrincipal(ctx, &auth.Principal{UserID: users[0], Role: auth.RoleCustomer})
+	other := auth.WithPrincipal(ctx, &auth.Principal{UserID: users[1], Role: auth.RoleCustomer})
+	args := struct{ ID graphql.ID }{"qr"}
+
+	if s, err := r.QrUploadSession(other, args); s != nil || err != nil {
+		t.Errorf("qrUploadSession as another user = %v, %v, want nil", s, err)
+	}
+	var e *Error
+	if _, err := r.RevokeQrUploadSession(other, args); !errors.As(err, &e) || e.Code != codeNotFound {
+		t.Errorf("revoking another user's session: error = %v, want %s", err, codeNotFound)
+	}
+	if _, err := r.RevokeQrUploadSession(ctx, args); err != errUnauthenticated {
+		t.Errorf("revoking anonymously: error = %v, want %v", err, errUnauthenticated)
+	}
+
+	s, err := r.RevokeQrUploadSession(owner, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status() != "REVOKED" || s.RevokedAt() == nil {
+		t.Errorf("revoked session is %s, revoked at %v", s.Status(), s.RevokedAt())
+	}
+}
@@ -3,14 +3,29 @@ package resolvers
 import (
 	"context"
 	"fmt"
-	"go-backend/db"
 	"go-backend/models"
+	"strings"
 
 	"github.com/graph-gophers/graphql-go"
 )
 
 type UserResolver struct {
-	u models.User
+	root *Resolver
+	u    models.User
+	// own is set when the caller is known to be u without a principal
+	// saying so yet, having just registered or logged in.
+	own bool
+}
+
+// private reports whether the caller may see u's email address and role:
+// only u and admins may, as users are reachable by anyone through reviews
+// and order histories.
+func (r *UserResolver) private(ctx context.Context) bool {
+	if r.own {
+		return true
+	}
+	_, err := requireSelf(ctx, r.u.ID)
+	return err == nil
 }
 
 // Resolve ID field
@@ -19,8 +34,11 @@ func (r *UserResolver) ID() graphql.ID {
 }
 
 // Resolve Email field
-func (r *UserResolver) Email() string {
-	return r.u.Email
+func (r *UserResolver) Email(ctx context.Context) *string {
+	if !r.private(ctx) {
+		return nil
+	}
+	return &r.u.Email
 }
 
 // Resolve FirstName field
@@ -33,22 +51,11 @@ func (r *UserResolver) LastName() *string {
 	return &r.u.LastName
 }
 
-// Resolve Orders field (optional, if you want to resolve user's orders)
-func (r *UserResolver) Orders(ctx context.Context) ([]*OrderResolver, error) {
-	rows, err := db.DB.QueryContext(ctx, "SELECT id, user_id, total_amount, status, created_at FROM orders WHERE user_id = $1", r.u.ID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var orders []*OrderResolver
-	for rows.Next() {
-		var o models.Order
-		if err := rows.Scan(&o.ID, &o.UserID, &o.TotalAmount, &o.Status, &o.CreatedAt); err != nil {
-			return nil, err
-		}
-		orders = append(orders, &OrderResolver{o})
+// Resolve Role field
+func (r *UserResolver) Role(ctx context.Context) *string {
+	if !r.private(ctx) {
+		return nil
 	}
-
-	return orders, nil
+	role := strings.ToUpper(r.u.Role)
+	return &role
 }
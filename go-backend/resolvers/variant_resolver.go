@@ -0,0 +1,197 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-backend/attrdef"
+	"go-backend/auth"
+	"go-backend/models"
+	"go-backend/store"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// ProductVariantResolver resolves the ProductVariant type
+type ProductVariantResolver struct {
+	root *Resolver
+	v    models.ProductVariant
+}
+
+func (r *ProductVariantResolver) ID() graphql.ID {
+	return graphql.ID(fmt.Sprint(r.v.ID))
+}
+
+func (r *ProductVariantResolver) Product(ctx context.Context) (*ProductResolver, error) {
+	p, err := r.root.loaders(ctx).ProductByID.Load(ctx, r.v.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("product %d not found", r.v.ProductID)
+	}
+	return &ProductResolver{r.root, *p}, nil
+}
+
+func (r *ProductVariantResolver) Sku() string {
+	return r.v.SKU
+}
+
+func (r *ProductVariantResolver) Price() float64 {
+	return r.v.Price
+}
+
+func (r *ProductVariantResolver) StockQuantity() int32 {
+	return r.v.StockQuantity
+}
+
+func (r *ProductVariantResolver) Options() []*VariantOptionResolver {
+	options := make([]*VariantOptionResolver, len(r.v.Options))
+	for i, o := range r.v.Options {
+		options[i] = &VariantOptionResolver{o}
+	}
+	return options
+}
+
+func (r *ProductVariantResolver) Images(ctx context.Context) ([]*ProductImageResolver, error) {
+	images, err := r.root.loaders(ctx).ImagesByVariantID.Load(ctx, r.v.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*ProductImageResolver, len(images))
+	for i, img := range images {
+		resolvers[i] = &ProductImageResolver{r.root, *img}
+	}
+	return resolvers, nil
+}
+
+func (r *ProductVariantResolver) CreatedAt() string {
+	return r.v.CreatedAt
+}
+
+// VariantOptionResolver resolves the VariantOption type
+type VariantOptionResolver struct {
+	o models.VariantOption
+}
+
+func (r *VariantOptionResolver) Name() string {
+	return r.o.Name
+}
+
+func (r *VariantOptionResolver) Value() string {
+	return r.o.Value
+}
+
+// checkVariant validates the input of a variant mutation. Options are
+// attribute values, so they are checked against the definitions of the
+// product's category.
+func (r *Resolver) checkVariant(ctx context.Context, p *models.Product, input *models.ProductVariantInput) error {
+	input.SKU = strings.TrimSpace(input.SKU)
+	if input.SKU == "" {
+		return newError(codeBadUserInput, "SKU must not be empty")
+	}
+	if input.Price < 0 {
+		return newError(codeBadUserInput, "price must not be negative")
+	}
+	if input.StockQuantity < 0 {
+		return newError(codeBadUserInput, "stock quantity must not be negative")
+	}
+
+	values := make([]attrdef.Value, len(input.Options))
+	seen := make(map[string]bool, len(input.Options))
+	for i, o := range input.Options {
+		key := strings.ToLower(o.Name)
+		if seen[key] {
+			return newError(codeBadUserInput, "option %q is given twice", o.Name)
+		}
+		seen[key] = true
+		values[i] = attrdef.Value{Name: o.Name, Value: o.Value}
+	}
+	defs, err := r.definitionSet(ctx, p.CategoryID)
+	if err != nil {
+		return err
+	}
+	return userError(defs.Validate(values, false))
+}
+
+// Adds a purchasable variant, such as a size and colour, to a product.
+// Once a product has variants, carts and orders must name one.
+func (r *Resolver) CreateProductVariant(ctx context.Context, args struct {
+	ProductID graphql.ID
+	Input     models.ProductVariantInput
+}) (*ProductVariantResolver, error) {
+	seller, err := requireRole(ctx, auth.RoleSeller)
+	if err != nil {
+		return nil, err
+	}
+	productID, err := parseID(args.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.existingProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkVariant(ctx, p, &args.Input); err != nil {
+		return nil, err
+	}
+	if err := r.checkImages(ctx, seller, args.Input.Images); err != nil {
+		return nil, err
+	}
+	v, err := r.store.Products.CreateVariant(ctx, productID, args.Input)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &ProductVariantResolver{r, *v}, nil
+}
+
+func (r *Resolver) UpdateProductVariant(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input models.ProductVariantInput
+}) (*ProductVariantResolver, error) {
+	seller, err := requireRole(ctx, auth.RoleSeller)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	old, err := r.store.Products.GetVariant(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, newError(codeNotFound, "variant %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.existingProduct(ctx, old.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkVariant(ctx, p, &args.Input); err != nil {
+		return nil, err
+	}
+	if err := r.checkImages(ctx, seller, args.Input.Images); err != nil {
+		return nil, err
+	}
+	v, err := r.store.Products.UpdateVariant(ctx, id, args.Input)
+	if err != nil {
+		return nil, userError(err)
+	}
+	return &ProductVariantResolver{r, *v}, nil
+}
+
+// Deletes a variant. Cart lines for it are removed; past orders keep its
+// SKU.
+func (r *Resolver) DeleteProductVariant(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if _, err := requireRole(ctx, auth.RoleSeller); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	return r.store.Products.DeleteVariant(ctx, id)
+}
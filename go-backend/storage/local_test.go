@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir(), "http://localhost:8080/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Path() != "/uploads" || l.URL("images/a.png") != "http://localhost:8080/uploads/images/a.png" {
+		t.Errorf("Path() = %q, URL() = %q", l.Path(), l.URL("images/a.png"))
+	}
+
+	for _, data := range []string{"first", "second"} {
+		if err := l.Put(ctx, "images/a.png", []byte(data), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rc, err := l.Open(ctx, "images/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(got) != "second" {
+		t.Errorf("Open() read %q, %v, want the replacement", got, err)
+	}
+
+	rec := httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/images/a.png", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "second" {
+		t.Errorf("GET /images/a.png = %d %q", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/images", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /images = %d, want directories not listed", rec.Code)
+	}
+
+	if err := l.Delete(ctx, "images/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Delete(ctx, "images/a.png"); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+	if _, err := l.Open(ctx, "images/a.png"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Open() after Delete error = %v, want ErrNotExist", err)
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "images/../../secret", "images//a.png", `images\a.png`} {
+		if err := l.Put(ctx, key, nil, "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package storage keeps uploaded files. Objects are addressed by a key, a
+// slash-separated path such as "images/3f2a.jpg", and served from the URL
+// their backend gives them. Metadata about what an object is and who owns
+// it lives in the database, not here.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Storage is a place to keep objects.
+type Storage interface {
+	// Put stores data under key with the given content type, replacing any
+	// object already there.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Open returns the object stored under key. It returns ErrNotExist if
+	// there is none.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing object
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL is where clients can fetch the object stored under key.
+	URL(key string) string
+}
+
+// ErrNotExist is returned by Open for a key with no object.
+var ErrNotExist = errors.New("object does not exist")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or climb out
+// of the storage root with "..".
+var ErrInvalidKey = errors.New("invalid object key")
+
+// checkKey reports whether key is a relative, clean slash-separated path.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-backend/models"
+)
+
+// StockShortage is a product or variant that was wanted in a larger
+// quantity than is available.
+type StockShortage struct {
+	StockKey
+	Requested int32
+	Available int32
+}
+
+// InsufficientStockError is returned when stock can't cover an order or a
+// reservation. It lists every product that falls short.
+type InsufficientStockError struct {
+	Shortages []StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	ids := make([]string, len(e.Shortages))
+	for i, s := range e.Shortages {
+		ids[i] = fmt.Sprint(s.ProductID)
+		if s.VariantID != 0 {
+			ids[i] += fmt.Sprintf(" (variant %d)", s.VariantID)
+		}
+	}
+	return "insufficient stock for products " + strings.Join(ids, ", ")
+}
+
+// sortedKeys returns the keys of quantities ordered by product, then
+// variant. Taking row locks in this order keeps concurrent orders from
+// deadlocking.
+func sortedKeys(quantities map[StockKey]int32) []StockKey {
+	keys := make([]StockKey, 0, len(quantities))
+	for k := range quantities {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ProductID != keys[j].ProductID {
+			return keys[i].ProductID < keys[j].ProductID
+		}
+		return keys[i].VariantID < keys[j].VariantID
+	})
+	return keys
+}
+
+// checkStock compares the wanted quantities with the available ones,
+// returning an *InsufficientStockError for any that fall short. Products
+// missing from available have none.
+func checkStock(wanted, available map[StockKey]int32) error {
+	var e InsufficientStockError
+	for _, k := range sortedKeys(wanted) {
+		if have := max(available[k], 0); wanted[k] > have {
+			e.Shortages = append(e.Shortages, StockShortage{StockKey: k, Requested: wanted[k], Available: have})
+		}
+	}
+	if len(e.Shortages) > 0 {
+		return &e
+	}
+	return nil
+}
+
+// orderQuantities sums the quantities of an order's items per product and
+// variant.
+func orderQuantities(items []NewOrderItem) map[StockKey]int32 {
+	wanted := make(map[StockKey]int32)
+	for _, item := range items {
+		wanted[StockKey{item.ProductID, item.VariantID}] += item.Quantity
+	}
+	return wanted
+}
+
+// nullableVariant returns k's variant ID for a nullable column.
+func (k StockKey) nullableVariant() *int32 {
+	if k.VariantID == 0 {
+		return nil
+	}
+	id := k.VariantID
+	return &id
+}
+
+func cartItemKey(it *models.CartItem) StockKey {
+	k := StockKey{ProductID: it.ProductID}
+	if it.VariantID != nil {
+		k.VariantID = *it.VariantID
+	}
+	return k
+}
+
+func orderItemKey(item *models.OrderItem) StockKey {
+	k := StockKey{ProductID: item.ProductID}
+	if item.VariantID != nil {
+		k.VariantID = *item.VariantID
+	}
+	return k
+}
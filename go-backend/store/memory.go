@@ -0,0 +1,218 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-backend/models"
+)
+
+// memDB holds the tables shared by the in-memory stores. A single mutex
+// guards every table so multi-table writes stay consistent.
+type memDB struct {
+	mu sync.RWMutex
+
+	nextID map[string]int32
+
+	categories  map[int32]*models.Category
+	definitions map[int32]*models.AttributeDefinition
+	products    map[int32]*models.Product
+	variants    map[int32]*models.ProductVariant
+	options     map[int32]*models.CustomizationOption
+	images      map[int32]*models.ProductImage
+	attributes  map[int32]*models.ProductAttribute
+	users       map[int32]*models.User
+	passwords   map[int32]string
+	orders      map[int32]*models.Order
+	orderItems  map[int32]*models.OrderItem
+	reviews     map[int32]*models.Review
+	carts       map[int32]*models.Cart
+	cartItems   map[int32]*models.CartItem
+	// reservations are keyed by cart, then product and variant.
+	reservations  map[int32]map[StockKey]*Reservation
+	statusHistory map[int32]*models.OrderStatusChange
+	payments      map[int32]*models.Payment
+	// uploads is the images table; images holds product_images.
+	uploads    map[int32]*models.Image
+	renditions map[int32]*models.ImageRendition
+	sessions   map[string]*memSession
+
+	idempotencyKeys map[idempotencyID]*IdempotencyKey
+
+	signingKeys   map[string]*SigningKey
+	refreshTokens map[string]*RefreshToken
+}
+
+// NewMemory returns a Store that keeps everything in process memory. It is
+// meant for tests and local experiments; nothing is persisted.
+func NewMemory() *Store {
+	m := &memDB{
+		nextID:      make(map[string]int32),
+		categories:  make(map[int32]*models.Category),
+		definitions: make(map[int32]*models.AttributeDefinition),
+		products:    make(map[int32]*models.Product),
+		variants:    make(map[int32]*models.ProductVariant),
+		options:     make(map[int32]*models.CustomizationOption),
+		images:      make(map[int32]*models.ProductImage),
+		attributes:  make(map[int32]*models.ProductAttribute),
+		users:       make(map[int32]*models.User),
+		passwords:   make(map[int32]string),
+		orders:      make(map[int32]*models.Order),
+		orderItems:  make(map[int32]*models.OrderItem),
+		reviews:     make(map[int32]*models.Review),
+		carts:       make(map[int32]*models.Cart),
+		cartItems:   make(map[int32]*models.CartItem),
+
+		reservations:  make(map[int32]map[StockKey]*Reservation),
+		statusHistory: make(map[int32]*models.OrderStatusChange),
+		payments:      make(map[int32]*models.Payment),
+		uploads:       make(map[int32]*models.Image),
+		renditions:    make(map[int32]*models.ImageRendition),
+		sessions:      make(map[string]*memSession),
+
+		idempotencyKeys: make(map[idempotencyID]*IdempotencyKey),
+
+		signingKeys:   make(map[string]*SigningKey),
+		refreshTokens: make(map[string]*RefreshToken),
+	}
+	return &Store{
+		Products:    &memProducts{m},
+		Categories:  &memCategories{m},
+		Orders:      &memOrders{m},
+		Reviews:     &memReviews{m},
+		Users:       &memUsers{m},
+		Tokens:      &memTokens{m},
+		Carts:       &memCarts{m},
+		Inventory:   &memInventory{m},
+		Payments:    &memPayments{m},
+		Idempotency: &memIdempotency{m},
+		Images:      &memImages{m},
+		Sessions:    &memSessions{m},
+	}
+}
+
+// id returns the next serial value for table. Callers must hold mu.
+func (m *memDB) id(table string) int32 {
+	m.nextID[table]++
+	return m.nextID[table]
+}
+
+// now renders the current time with formatTime.
+func now() string {
+	return formatTime(time.Now())
+}
+
+// formatTime renders t in UTC with fixed-width fractional seconds so that
+// timestamps compare correctly as strings.
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+}
+
+// sortedValues returns copies of the map values ordered by key.
+func sortedValues[T any](rows map[int32]*T, keep func(*T) bool) []*T {
+	keys := make([]int32, 0, len(rows))
+	for k := range rows {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	var out []*T
+	for _, k := range keys {
+		row := rows[k]
+		if keep != nil && !keep(row) {
+			continue
+		}
+		c := *row
+		out = append(out, &c)
+	}
+	return out
+}
+
+// pick copies the rows whose keys are in ids.
+func pick[T any](rows map[int32]*T, ids []int32) map[int32]*T {
+	out := make(map[int32]*T, len(ids))
+	for _, id := range ids {
+		if row, ok := rows[id]; ok {
+			c := *row
+			out[id] = &c
+		}
+	}
+	return out
+}
+
+// groupBy copies the rows matching one of ids, keyed by the id returned by key.
+func groupBy[T any](rows map[int32]*T, ids []int32, key func(*T) int32) map[int32][]*T {
+	want := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	out := make(map[int32][]*T)
+	for _, row := range sortedValues(rows, func(row *T) bool { return want[key(row)] }) {
+		out[key(row)] = append(out[key(row)], row)
+	}
+	return out
+}
+
+// memPage paginates rows that are already sorted in the listing's natural
+// order. sort names the ordering as in Cursor.Sort, cursor returns a row's
+// cursor and cmp orders a row relative to a cursor, returning a negative
+// number when the row comes first.
+func memPage[T any](rows []*T, page PageArgs, sort string, cursor func(*T) Cursor, cmp func(*T, Cursor) int) (*Page[T], error) {
+	limit, err := page.limit()
+	if err != nil {
+		return nil, err
+	}
+	if err := page.check(sort); err != nil {
+		return nil, err
+	}
+
+	var window []*T
+	for _, row := range rows {
+		if page.After != nil && cmp(row, *page.After) <= 0 {
+			continue
+		}
+		if page.Before != nil && cmp(row, *page.Before) >= 0 {
+			continue
+		}
+		window = append(window, row)
+	}
+
+	var edges []Edge[T]
+	for i := range window {
+		if len(edges) > limit {
+			break
+		}
+		row := window[i]
+		if page.backward() {
+			row = window[len(window)-1-i]
+		}
+		edges = append(edges, Edge[T]{Node: row, Cursor: cursor(row)})
+	}
+	return finishPage(edges, len(rows), limit, page), nil
+}
+
+// cmpKey orders by a text sort key, then by ID. desc flips the order.
+func cmpKey(key string, id int32, c Cursor, desc bool) int {
+	n := strings.Compare(key, c.Key)
+	if n == 0 {
+		n = int(id) - int(c.ID)
+	}
+	if desc {
+		return -n
+	}
+	return n
+}
+
+// sortNewestFirst orders rows by creation time, then ID, descending.
+func sortNewestFirst[T any](rows []*T, key func(*T) (string, int32)) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		ki, idi := key(rows[i])
+		kj, idj := key(rows[j])
+		if ki != kj {
+			return ki > kj
+		}
+		return idi > idj
+	})
+}
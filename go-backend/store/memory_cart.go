@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go-backend/models"
+)
+
+type memCarts struct {
+	m *memDB
+}
+
+// find returns the owner's cart, expired or not. Callers must hold mu.
+func (s *memCarts) find(owner CartOwner) *models.Cart {
+	for _, c := range s.m.carts {
+		if (owner.Token != "" && c.Token == owner.Token) || (owner.Token == "" && c.UserID == owner.UserID) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *memCarts) Get(ctx context.Context, owner CartOwner) (*models.Cart, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	c := s.find(owner)
+	if c == nil || c.ExpiresAt <= now() {
+		return nil, ErrNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (s *memCarts) Open(ctx context.Context, owner CartOwner, expiresAt time.Time) (*models.Cart, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	cp := *s.open(owner, expiresAt)
+	return &cp, nil
+}
+
+// open upserts the owner's cart, replacing an expired one. Callers must
+// hold mu.
+func (s *memCarts) open(owner CartOwner, expiresAt time.Time) *models.Cart {
+	c := s.find(owner)
+	if c != nil && c.ExpiresAt <= now() {
+		s.delete(c.ID)
+		c = nil
+	}
+	if c == nil {
+		c = &models.Cart{ID: s.m.id("carts"), Token: owner.Token, UserID: owner.UserID}
+		s.m.carts[c.ID] = c
+	}
+	s.touch(c.ID, expiresAt)
+	return c
+}
+
+// touch pushes back the cart's expiry. Callers must hold mu.
+func (s *memCarts) touch(cartID int32, expiresAt time.Time) {
+	c := s.m.carts[cartID]
+	c.UpdatedAt = now()
+	c.ExpiresAt = formatTime(expiresAt)
+}
+
+// delete removes a cart and its lines. Callers must hold mu.
+func (s *memCarts) delete(cartID int32) {
+	for id, it := range s.m.cartItems {
+		if it.CartID == cartID {
+			delete(s.m.cartItems, id)
+		}
+	}
+	delete(s.m.carts, cartID)
+	delete(s.m.reservations, cartID)
+}
+
+func (s *memCarts) Items(ctx context.Context, cartID int32) ([]*models.CartItem, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.cartItems, func(it *models.CartItem) bool {
+		return it.CartID == cartID
+	}), nil
+}
+
+func (s *memCarts) AddItem(ctx context.Context, cartID int32, item NewCartItem, expiresAt time.Time) (*models.CartItem, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.carts[cartID]; !ok {
+		return nil, ErrNotFound
+	}
+	it := s.add(cartID, &models.CartItem{
+		ProductID:      item.ProductID,
+		VariantID:      item.nullableVariant(),
+		Customizations: item.Customizations,
+		Quantity:       item.Quantity,
+		PriceAtAdd:     item.Price,
+		AddedAt:        now(),
+	})
+	s.touch(cartID, expiresAt)
+	cp := *it
+	return &cp, nil
+}
+
+// add merges line into the cart's line for the same product, variant and
+// customizations. Callers must hold mu.
+func (s *memCarts) add(cartID int32, line *models.CartItem) *models.CartItem {
+	for _, it := range s.m.cartItems {
+		if it.CartID == cartID && cartItemKey(it) == cartItemKey(line) && slices.Equal(it.Customizations, line.Customizations) {
+			it.Quantity += line.Quantity
+			return it
+		}
+	}
+	it := *line
+	it.ID = s.m.id("cart_items")
+	it.CartID = cartID
+	s.m.cartItems[it.ID] = &it
+	return &it
+}
+
+func (s *memCarts) UpdateItem(ctx context.Context, cartID, itemID, quantity int32, expiresAt time.Time) (*models.CartItem, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	it, ok := s.m.cartItems[itemID]
+	if !ok || it.CartID != cartID {
+		return nil, ErrNotFound
+	}
+	it.Quantity = quantity
+	s.touch(cartID, expiresAt)
+	cp := *it
+	return &cp, nil
+}
+
+func (s *memCarts) RemoveItem(ctx context.Context, cartID, itemID int32, expiresAt time.Time) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	it, ok := s.m.cartItems[itemID]
+	if ok && it.CartID == cartID {
+		delete(s.m.cartItems, itemID)
+	}
+	if _, exists := s.m.carts[cartID]; exists {
+		s.touch(cartID, expiresAt)
+	}
+	return ok && it.CartID == cartID, nil
+}
+
+func (s *memCarts) Clear(ctx context.Context, cartID int32, expiresAt time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for id, it := range s.m.cartItems {
+		if it.CartID == cartID {
+			delete(s.m.cartItems, id)
+		}
+	}
+	if _, ok := s.m.carts[cartID]; ok {
+		s.touch(cartID, expiresAt)
+	}
+	return nil
+}
+
+func (s *memCarts) Merge(ctx context.Context, token string, userID int32, expiresAt time.Time) (*models.Cart, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	cart := s.open(CartOwner{UserID: userID}, expiresAt)
+	if anon := s.find(CartOwner{Token: token}); anon != nil {
+		if anon.ExpiresAt > now() {
+			for _, it := range sortedValues(s.m.cartItems, func(it *models.CartItem) bool { return it.CartID == anon.ID }) {
+				s.add(cart.ID, it)
+			}
+		}
+		s.delete(anon.ID)
+	}
+	cp := *cart
+	return &cp, nil
+}
+
+func (s *memCarts) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	cutoff := formatTime(t)
+	var n int64
+	for id, c := range s.m.carts {
+		if c.ExpiresAt < cutoff {
+			s.delete(id)
+			n++
+		}
+	}
+	return n, nil
+}
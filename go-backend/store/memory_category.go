@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-backend/models"
+)
+
+type memCategories struct {
+	m *memDB
+}
+
+func (s *memCategories) Get(ctx context.Context, id int32) (*models.Category, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	c, ok := s.m.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (s *memCategories) GetMany(ctx context.Context, ids []int32) (map[int32]*models.Category, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return pick(s.m.categories, ids), nil
+}
+
+func (s *memCategories) List(ctx context.Context) ([]*models.Category, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.categories, nil), nil
+}
+
+func (s *memCategories) Create(ctx context.Context, name string, parentID *int32) (*models.Category, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c := &models.Category{ID: s.m.id("categories"), Name: name}
+	if parentID != nil {
+		if _, ok := s.m.categories[*parentID]; !ok {
+			return nil, fmt.Errorf("category with ID %d does not exist", *parentID)
+		}
+		c.ParentCategory = &models.Category{ID: *parentID}
+	}
+	s.m.categories[c.ID] = c
+
+	cp := *c
+	return &cp, nil
+}
+
+func (s *memCategories) Update(ctx context.Context, id int32, name string) (*models.Category, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c.Name = name
+
+	cp := *c
+	return &cp, nil
+}
+
+func (s *memCategories) Move(ctx context.Context, id int32, parentID *int32) (*models.Category, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if parentID == nil {
+		c.ParentCategory = nil
+	} else {
+		if _, ok := s.m.categories[*parentID]; !ok {
+			return nil, fmt.Errorf("parent category %d: %w", *parentID, ErrNotFound)
+		}
+		if s.m.subtree(id)[*parentID] {
+			return nil, ErrCategoryCycle
+		}
+		c.ParentCategory = &models.Category{ID: *parentID}
+	}
+
+	cp := *c
+	return &cp, nil
+}
+
+func (s *memCategories) Delete(ctx context.Context, id int32, reassignTo *int32) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.categories[id]
+	if !ok {
+		return false, nil
+	}
+	var products []*models.Product
+	for _, p := range s.m.products {
+		if p.CategoryID == id {
+			products = append(products, p)
+		}
+	}
+	if len(products) > 0 {
+		if reassignTo == nil {
+			return false, &CategoryInUseError{CategoryID: id, Products: int32(len(products))}
+		}
+		if _, ok := s.m.categories[*reassignTo]; !ok || *reassignTo == id {
+			return false, fmt.Errorf("category %d to reassign products to: %w", *reassignTo, ErrNotFound)
+		}
+		for _, p := range products {
+			p.CategoryID = *reassignTo
+		}
+	}
+	for _, child := range s.m.categories {
+		if child.ParentCategory != nil && child.ParentCategory.ID == id {
+			child.ParentCategory = c.ParentCategory
+		}
+	}
+	for defID, d := range s.m.definitions {
+		if d.CategoryID == id {
+			delete(s.m.definitions, defID)
+		}
+	}
+	delete(s.m.categories, id)
+	return true, nil
+}
+
+func (s *memCategories) ChildrenByParents(ctx context.Context, parentIDs []int32) (map[int32][]*models.Category, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return groupBy(s.m.categories, parentIDs, func(c *models.Category) int32 {
+		if c.ParentCategory == nil {
+			return 0
+		}
+		return c.ParentCategory.ID
+	}), nil
+}
+
+func (s *memCategories) Ancestors(ctx context.Context, id int32) ([]*models.Category, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	c, ok := s.m.categories[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var ancestors []*models.Category
+	for c.ParentCategory != nil {
+		c = s.m.categories[c.ParentCategory.ID]
+		cp := *c
+		ancestors = append([]*models.Category{&cp}, ancestors...)
+	}
+	return ancestors, nil
+}
+
+func (s *memCategories) Descendants(ctx context.Context, id int32) ([]*models.Category, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	subtree := s.m.subtree(id)
+	return sortedValues(s.m.categories, func(c *models.Category) bool {
+		return c.ID != id && subtree[c.ID]
+	}), nil
+}
+
+func (s *memCategories) ProductCounts(ctx context.Context, ids []int32) (map[int32]int32, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	counts := make(map[int32]int32, len(ids))
+	for _, id := range ids {
+		if _, ok := s.m.categories[id]; !ok {
+			continue
+		}
+		subtree := s.m.subtree(id)
+		counts[id] = 0
+		for _, p := range s.m.products {
+			if subtree[p.CategoryID] {
+				counts[id]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// subtree returns the IDs of the category and every category beneath it.
+// Callers must hold mu.
+func (m *memDB) subtree(id int32) map[int32]bool {
+	ids := map[int32]bool{id: true}
+	for {
+		grew := false
+		for _, c := range m.categories {
+			if c.ParentCategory != nil && ids[c.ParentCategory.ID] && !ids[c.ID] {
+				ids[c.ID] = true
+				grew = true
+			}
+		}
+		if !grew {
+			return ids
+		}
+	}
+}
+
+func (s *memCategories) DefinitionsByCategories(ctx context.Context, ids []int32) (map[int32][]*models.AttributeDefinition, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return groupBy(s.m.definitions, ids, func(d *models.AttributeDefinition) int32 { return d.CategoryID }), nil
+}
+
+func (s *memCategories) GetDefinition(ctx context.Context, id int32) (*models.AttributeDefinition, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	d, ok := s.m.definitions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *d
+	return &c, nil
+}
+
+// defined reports whether a definition other than id on categoryID has
+// name. Callers must hold mu.
+func (s *memCategories) defined(categoryID int32, name string, id int32) bool {
+	for _, d := range s.m.definitions {
+		if d.CategoryID == categoryID && strings.EqualFold(d.Name, name) && d.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memCategories) CreateDefinition(ctx context.Context, def models.AttributeDefinition) (*models.AttributeDefinition, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.categories[def.CategoryID]; !ok {
+		return nil, ErrNotFound
+	}
+	if s.defined(def.CategoryID, def.Name, 0) {
+		return nil, ErrAttributeDefined
+	}
+	d := def
+	d.ID = s.m.id("attribute_definitions")
+	s.m.definitions[d.ID] = &d
+
+	c := d
+	return &c, nil
+}
+
+func (s *memCategories) UpdateDefinition(ctx context.Context, id int32, def models.AttributeDefinition) (*models.AttributeDefinition, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	d, ok := s.m.definitions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if s.defined(d.CategoryID, def.Name, id) {
+		return nil, ErrAttributeDefined
+	}
+	d.Name = def.Name
+	d.Type = def.Type
+	d.AllowedValues = def.AllowedValues
+	d.Required = def.Required
+
+	c := *d
+	return &c, nil
+}
+
+func (s *memCategories) DeleteDefinition(ctx context.Context, id int32) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.definitions[id]; !ok {
+		return false, nil
+	}
+	delete(s.m.definitions, id)
+	return true, nil
+}
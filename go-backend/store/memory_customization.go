@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"go-backend/models"
+)
+
+func (s *memProducts) CustomizationOptionsByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.CustomizationOption, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return groupBy(s.m.options, productIDs, func(o *models.CustomizationOption) int32 { return o.ProductID }), nil
+}
+
+func (s *memProducts) GetCustomizationOption(ctx context.Context, id int32) (*models.CustomizationOption, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	o, ok := s.m.options[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *o
+	return &c, nil
+}
+
+// optionDefined reports whether an option other than id on productID has
+// name. Callers must hold mu.
+func (s *memProducts) optionDefined(productID int32, name string, id int32) bool {
+	for _, o := range s.m.options {
+		if o.ProductID == productID && strings.EqualFold(o.Name, name) && o.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memProducts) CreateCustomizationOption(ctx context.Context, opt models.CustomizationOption) (*models.CustomizationOption, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.products[opt.ProductID]; !ok {
+		return nil, ErrNotFound
+	}
+	if s.optionDefined(opt.ProductID, opt.Name, 0) {
+		return nil, ErrOptionDefined
+	}
+	o := opt
+	o.ID = s.m.id("customization_options")
+	o.CreatedAt = now()
+	s.m.options[o.ID] = &o
+
+	c := o
+	return &c, nil
+}
+
+func (s *memProducts) UpdateCustomizationOption(ctx context.Context, id int32, opt models.CustomizationOption) (*models.CustomizationOption, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	o, ok := s.m.options[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if s.optionDefined(o.ProductID, opt.Name, id) {
+		return nil, ErrOptionDefined
+	}
+	opt.ID, opt.ProductID, opt.CreatedAt = o.ID, o.ProductID, o.CreatedAt
+	*o = opt
+
+	c := *o
+	return &c, nil
+}
+
+func (s *memProducts) DeleteCustomizationOption(ctx context.Context, id int32) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.options[id]; !ok {
+		return false, nil
+	}
+	delete(s.m.options, id)
+	return true, nil
+}
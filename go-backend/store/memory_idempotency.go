@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+type memIdempotency struct {
+	m *memDB
+}
+
+// idempotencyID keys the idempotency keys table.
+type idempotencyID struct {
+	userID int32
+	key    string
+}
+
+func (s *memIdempotency) Begin(ctx context.Context, key IdempotencyKey, expiresAt time.Time) (*IdempotencyKey, bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	id := idempotencyID{key.UserID, key.Key}
+	if existing, ok := s.m.idempotencyKeys[id]; ok && existing.ExpiresAt.After(time.Now()) {
+		c := *existing
+		return &c, false, nil
+	}
+	k := key
+	k.Response = nil
+	k.ExpiresAt = expiresAt
+	s.m.idempotencyKeys[id] = &k
+
+	c := k
+	return &c, true, nil
+}
+
+func (s *memIdempotency) Complete(ctx context.Context, userID int32, key string, response []byte) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	k, ok := s.m.idempotencyKeys[idempotencyID{userID, key}]
+	if !ok {
+		return ErrNotFound
+	}
+	k.Response = append([]byte(nil), response...)
+	return nil
+}
+
+func (s *memIdempotency) Release(ctx context.Context, userID int32, key string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	delete(s.m.idempotencyKeys, idempotencyID{userID, key})
+	return nil
+}
+
+func (s *memIdempotency) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var n int64
+	for id, k := range s.m.idempotencyKeys {
+		if k.ExpiresAt.Before(t) {
+			delete(s.m.idempotencyKeys, id)
+			n++
+		}
+	}
+	return n, nil
+}
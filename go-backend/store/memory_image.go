@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-backend/models"
+)
+
+type memImages struct {
+	m *memDB
+}
+
+func (s *memImages) Get(ctx context.Context, id int32) (*models.Image, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	img, ok := s.m.uploads[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *img
+	return &c, nil
+}
+
+func (s *memImages) ListByOwner(ctx context.Context, ownerID int32) ([]*models.Image, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.uploads, func(img *models.Image) bool {
+		return img.OwnerID == ownerID
+	}), nil
+}
+
+func (s *memImages) ListBySession(ctx context.Context, sessionID string) ([]*models.Image, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.uploads, func(img *models.Image) bool {
+		return img.SessionID != nil && *img.SessionID == sessionID
+	}), nil
+}
+
+func (s *memImages) Create(ctx context.Context, img models.Image, renditions []models.ImageRendition) (*models.Image, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.users[img.OwnerID]; !ok {
+		return nil, ErrNotFound
+	}
+	if img.SessionID != nil {
+		session, ok := s.m.sessions[*img.SessionID]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		if session.status(time.Now()) != "ACTIVE" {
+			return nil, ErrSessionClosed
+		}
+		session.UploadCount++
+	}
+	img.ID = s.m.id("images")
+	img.CreatedAt = now()
+	s.m.uploads[img.ID] = &img
+	for _, r := range renditions {
+		r.ImageID = img.ID
+		s.m.renditions[s.m.id("image_renditions")] = &r
+	}
+
+	c := img
+	return &c, nil
+}
+
+func (s *memImages) Delete(ctx context.Context, id int32) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.uploads[id]; !ok {
+		return false, nil
+	}
+	delete(s.m.uploads, id)
+	// Mirror ON DELETE CASCADE on image_renditions and ON DELETE SET NULL
+	// on product_images.
+	for renditionID, r := range s.m.renditions {
+		if r.ImageID == id {
+			delete(s.m.renditions, renditionID)
+		}
+	}
+	for _, img := range s.m.images {
+		if img.ImageID != nil && *img.ImageID == id {
+			img.ImageID = nil
+		}
+	}
+	return true, nil
+}
+
+func (s *memImages) RenditionsByImages(ctx context.Context, imageIDs []int32) (map[int32][]*models.ImageRendition, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return groupBy(s.m.renditions, imageIDs, func(r *models.ImageRendition) int32 { return r.ImageID }), nil
+}
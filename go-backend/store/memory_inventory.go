@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type memInventory struct {
+	m *memDB
+}
+
+// stock returns a pointer to the stock quantity kept for k, or nil if k
+// names no product or variant. Callers must hold mu.
+func (m *memDB) stock(k StockKey) *int32 {
+	if k.VariantID != 0 {
+		v, ok := m.variants[k.VariantID]
+		if !ok || v.ProductID != k.ProductID {
+			return nil
+		}
+		return &v.StockQuantity
+	}
+	p, ok := m.products[k.ProductID]
+	if !ok {
+		return nil
+	}
+	return &p.StockQuantity
+}
+
+// available returns what cartID may order of each product or variant.
+// Callers must hold mu.
+func (s *memInventory) available(items []StockKey, cartID int32) map[StockKey]int32 {
+	now := time.Now()
+	available := make(map[StockKey]int32, len(items))
+	for _, k := range items {
+		stock := s.m.stock(k)
+		if stock == nil {
+			continue
+		}
+		n := *stock
+		for holder, held := range s.m.reservations {
+			if r, ok := held[k]; ok && holder != cartID && r.ExpiresAt.After(now) {
+				n -= r.Quantity
+			}
+		}
+		available[k] = n
+	}
+	return available
+}
+
+func (s *memInventory) Reserve(ctx context.Context, cartID int32, expiresAt time.Time) ([]*Reservation, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	wanted := make(map[StockKey]int32)
+	for _, it := range s.m.cartItems {
+		if it.CartID == cartID {
+			wanted[cartItemKey(it)] += it.Quantity
+		}
+	}
+	if err := checkStock(wanted, s.available(sortedKeys(wanted), cartID)); err != nil {
+		return nil, err
+	}
+
+	held := make(map[StockKey]*Reservation, len(wanted))
+	reservations := make([]*Reservation, 0, len(wanted))
+	for _, k := range sortedKeys(wanted) {
+		r := &Reservation{CartID: cartID, StockKey: k, Quantity: wanted[k], ExpiresAt: expiresAt}
+		held[k] = r
+		c := *r
+		reservations = append(reservations, &c)
+	}
+	s.m.reservations[cartID] = held
+	return reservations, nil
+}
+
+func (s *memInventory) Reservations(ctx context.Context, cartID int32) ([]*Reservation, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	now := time.Now()
+	var reservations []*Reservation
+	for _, r := range s.m.reservations[cartID] {
+		if r.ExpiresAt.After(now) {
+			c := *r
+			reservations = append(reservations, &c)
+		}
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		a, b := reservations[i].StockKey, reservations[j].StockKey
+		return a.ProductID < b.ProductID || a.ProductID == b.ProductID && a.VariantID < b.VariantID
+	})
+	return reservations, nil
+}
+
+func (s *memInventory) Release(ctx context.Context, cartID int32) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	delete(s.m.reservations, cartID)
+	return nil
+}
+
+func (s *memInventory) Available(ctx context.Context, items []StockKey, cartID int32) (map[StockKey]int32, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return s.available(items, cartID), nil
+}
+
+func (s *memInventory) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var n int64
+	for cartID, held := range s.m.reservations {
+		for k, r := range held {
+			if r.ExpiresAt.Before(t) {
+				delete(held, k)
+				n++
+			}
+		}
+		if len(held) == 0 {
+			delete(s.m.reservations, cartID)
+		}
+	}
+	return n, nil
+}
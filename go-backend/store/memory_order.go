@@ -0,0 +1,183 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-backend/models"
+)
+
+type memOrders struct {
+	m *memDB
+}
+
+func (s *memOrders) Get(ctx context.Context, id int32) (*models.Order, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	o, ok := s.m.orders[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *o
+	return &c, nil
+}
+
+func (s *memOrders) List(ctx context.Context) ([]*models.Order, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.orders, nil), nil
+}
+
+func (s *memOrders) ListByUser(ctx context.Context, userID int32) ([]*models.Order, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.orders, func(o *models.Order) bool {
+		return o.UserID == userID
+	}), nil
+}
+
+func (s *memOrders) PageByUser(ctx context.Context, userID int32, page PageArgs) (*Page[models.Order], error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	orders := sortedValues(s.m.orders, func(o *models.Order) bool {
+		return o.UserID == userID
+	})
+	sortNewestFirst(orders, func(o *models.Order) (string, int32) { return o.CreatedAt, o.ID })
+	return memPage(orders, page, "",
+		func(o *models.Order) Cursor { return Cursor{Key: o.CreatedAt, ID: o.ID} },
+		func(o *models.Order, c Cursor) int { return cmpKey(o.CreatedAt, o.ID, c, true) })
+}
+
+func (s *memOrders) Items(ctx context.Context, orderID int32) ([]*models.OrderItem, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.orderItems, func(item *models.OrderItem) bool {
+		return item.OrderID == orderID
+	}), nil
+}
+
+func (s *memOrders) Create(ctx context.Context, userID int32, order NewOrder) (*models.Order, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	wanted := orderQuantities(order.Items)
+	inventory := memInventory{s.m}
+	if err := checkStock(wanted, inventory.available(sortedKeys(wanted), order.CartID)); err != nil {
+		return nil, err
+	}
+	for k, quantity := range wanted {
+		*s.m.stock(k) -= quantity
+	}
+
+	o := &models.Order{
+		ID:            s.m.id("orders"),
+		UserID:        userID,
+		Subtotal:      order.Subtotal,
+		DiscountTotal: order.DiscountTotal,
+		TaxTotal:      order.TaxTotal,
+		ShippingTotal: order.ShippingTotal,
+		TotalAmount:   order.Total,
+		Status:        "PENDING",
+		CreatedAt:     now(),
+	}
+	s.m.orders[o.ID] = o
+
+	for _, item := range order.Items {
+		id := s.m.id("order_items")
+		oi := &models.OrderItem{
+			ID:             id,
+			OrderID:        o.ID,
+			ProductID:      item.ProductID,
+			VariantID:      StockKey{item.ProductID, item.VariantID}.nullableVariant(),
+			Customizations: item.Customizations,
+			Quantity:       item.Quantity,
+			PriceAtTime:    item.UnitPrice,
+			Discount:       item.Discount,
+			Tax:            item.Tax,
+			LineTotal:      item.LineTotal,
+		}
+		if item.SKU != "" {
+			sku := item.SKU
+			oi.SKU = &sku
+		}
+		s.m.orderItems[id] = oi
+	}
+
+	s.record(o.ID, "", o.Status, userID, "")
+	if order.CartID != 0 {
+		delete(s.m.reservations, order.CartID)
+	}
+
+	c := *o
+	return &c, nil
+}
+
+func (s *memOrders) PendingBefore(ctx context.Context, t time.Time) ([]*models.Order, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	before := formatTime(t)
+	return sortedValues(s.m.orders, func(o *models.Order) bool {
+		return o.Status == "PENDING" && o.CreatedAt < before
+	}), nil
+}
+
+func (s *memOrders) SetStatus(ctx context.Context, id int32, change StatusChange) (*models.Order, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	o, ok := s.m.orders[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if o.Status != change.From {
+		return nil, ErrStatusChanged
+	}
+	o.Status = change.To
+	s.record(id, change.From, change.To, change.ActorID, change.Note)
+	if change.Restock {
+		for _, item := range s.m.orderItems {
+			if stock := s.m.stock(orderItemKey(item)); stock != nil && item.OrderID == id {
+				*stock += item.Quantity
+			}
+		}
+	}
+
+	c := *o
+	return &c, nil
+}
+
+// record appends a status change to an order's history. Callers must hold
+// mu.
+func (s *memOrders) record(orderID int32, from, to string, actorID int32, note string) {
+	c := &models.OrderStatusChange{
+		ID:        s.m.id("order_status_history"),
+		OrderID:   orderID,
+		ToStatus:  to,
+		CreatedAt: now(),
+	}
+	if from != "" {
+		c.FromStatus = &from
+	}
+	if actorID != 0 {
+		c.ActorID = &actorID
+	}
+	if note != "" {
+		c.Note = &note
+	}
+	s.m.statusHistory[c.ID] = c
+}
+
+func (s *memOrders) StatusHistory(ctx context.Context, orderID int32) ([]*models.OrderStatusChange, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.statusHistory, func(c *models.OrderStatusChange) bool {
+		return c.OrderID == orderID
+	}), nil
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"go-backend/models"
+	"go-backend/pricing"
+)
+
+type memPayments struct {
+	m *memDB
+}
+
+func (s *memPayments) Get(ctx context.Context, id int32) (*models.Payment, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	p, ok := s.m.payments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *p
+	return &c, nil
+}
+
+func (s *memPayments) GetByReference(ctx context.Context, provider, reference string) (*models.Payment, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	for _, p := range s.m.payments {
+		if p.Provider == provider && p.Reference != nil && *p.Reference == reference {
+			c := *p
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memPayments) ListByOrder(ctx context.Context, orderID int32) ([]*models.Payment, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.payments, func(p *models.Payment) bool {
+		return p.OrderID == orderID
+	}), nil
+}
+
+func (s *memPayments) Create(ctx context.Context, orderID int32, provider string, amount pricing.Cents) (*models.Payment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.orders[orderID]; !ok {
+		return nil, ErrNotFound
+	}
+	p := &models.Payment{
+		ID:        s.m.id("payment_intents"),
+		OrderID:   orderID,
+		Provider:  provider,
+		Amount:    amount.Float(),
+		Status:    "PENDING",
+		CreatedAt: now(),
+	}
+	p.UpdatedAt = p.CreatedAt
+	s.m.payments[p.ID] = p
+
+	c := *p
+	return &c, nil
+}
+
+func (s *memPayments) ReserveRefund(ctx context.Context, id int32, amount pricing.Cents) (*models.Payment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.payments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	refunded := pricing.FromFloat(p.AmountRefunded) + amount
+	if p.Status != "CAPTURED" || refunded > pricing.FromFloat(p.Amount) {
+		return nil, ErrRefundExceeded
+	}
+	p.AmountRefunded = refunded.Float()
+	p.UpdatedAt = now()
+
+	c := *p
+	return &c, nil
+}
+
+func (s *memPayments) Update(ctx context.Context, id int32, update PaymentUpdate) (*models.Payment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.payments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	// As UNIQUE (provider, reference) does
+	if update.Reference != "" {
+		for _, other := range s.m.payments {
+			if other.ID != id && other.Provider == p.Provider && other.Reference != nil && *other.Reference == update.Reference {
+				return nil, fmt.Errorf("%s payment reference %s is already recorded", p.Provider, update.Reference)
+			}
+		}
+	}
+	if update.Status != "" {
+		p.Status = update.Status
+	}
+	if update.Reference != "" {
+		p.Reference = &update.Reference
+	}
+	if update.FailureReason != "" {
+		p.FailureReason = &update.FailureReason
+	}
+	p.AmountRefunded = (pricing.FromFloat(p.AmountRefunded) + update.Refunded).Float()
+	p.UpdatedAt = now()
+
+	c := *p
+	return &c, nil
+}
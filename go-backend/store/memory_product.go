@@ -0,0 +1,411 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-backend/models"
+)
+
+type memProducts struct {
+	m *memDB
+}
+
+func (s *memProducts) Get(ctx context.Context, id int32) (*models.Product, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	p, ok := s.m.products[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *p
+	return &c, nil
+}
+
+func (s *memProducts) GetMany(ctx context.Context, ids []int32) (map[int32]*models.Product, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return pick(s.m.products, ids), nil
+}
+
+func (s *memProducts) List(ctx context.Context, filter ProductFilter) ([]*models.Product, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return s.filter(filter)
+}
+
+func (s *memProducts) Page(ctx context.Context, filter ProductFilter, page PageArgs) (*Page[models.Product], error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	products, err := s.filter(filter)
+	if err != nil {
+		return nil, err
+	}
+	key, desc := s.sortKey(filter.Sort)
+	return memPage(products, page, string(filter.Sort),
+		func(p *models.Product) Cursor { return Cursor{Sort: string(filter.Sort), Key: key(p), ID: p.ID} },
+		func(p *models.Product, c Cursor) int { return cmpKey(key(p), p.ID, c, desc) })
+}
+
+// filter returns the products matching filter in the requested order.
+// Callers must hold mu.
+func (s *memProducts) filter(filter ProductFilter) ([]*models.Product, error) {
+	if _, ok := productKeysets[filter.Sort]; !ok {
+		return nil, fmt.Errorf("unknown sort order %q", filter.Sort)
+	}
+
+	var categories map[int32]bool
+	if filter.CategoryID != nil {
+		categories = map[int32]bool{*filter.CategoryID: true}
+		if filter.IncludeSubcategories {
+			categories = s.m.subtree(*filter.CategoryID)
+		}
+	}
+
+	products := sortedValues(s.m.products, func(p *models.Product) bool {
+		if categories != nil && !categories[p.CategoryID] {
+			return false
+		}
+		if filter.Search != nil && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(*filter.Search)) &&
+			s.termScore(p, searchTerms(*filter.Search)) == 0 {
+			return false
+		}
+		if (filter.MinPrice != nil || filter.MaxPrice != nil || filter.InStock) && !s.offers(p, filter) {
+			return false
+		}
+		for _, want := range filter.Attributes {
+			if !s.hasAttribute(p.ID, want) {
+				return false
+			}
+		}
+		if filter.MinRating != nil && s.averageRating(p.ID) < *filter.MinRating {
+			return false
+		}
+		return true
+	})
+
+	key, desc := s.sortKey(filter.Sort)
+	sort.SliceStable(products, func(i, j int) bool {
+		return cmpKey(key(products[i]), products[i].ID, Cursor{Key: key(products[j]), ID: products[j].ID}, desc) < 0
+	})
+	return products, nil
+}
+
+// purchasable returns what can be bought of a product: its variants once it
+// has any, and otherwise the product itself as a variant. Callers must hold
+// mu.
+func (s *memProducts) purchasable(p *models.Product) []models.ProductVariant {
+	var variants []models.ProductVariant
+	for _, v := range s.m.variants {
+		if v.ProductID == p.ID {
+			variants = append(variants, *v)
+		}
+	}
+	if len(variants) == 0 {
+		variants = append(variants, models.ProductVariant{ProductID: p.ID, Price: p.Price, StockQuantity: p.StockQuantity})
+	}
+	return variants
+}
+
+// offers reports whether something purchasable of p is within the price
+// bounds and, if asked for, in stock. Callers must hold mu.
+func (s *memProducts) offers(p *models.Product, filter ProductFilter) bool {
+	for _, v := range s.purchasable(p) {
+		if (filter.MinPrice == nil || v.Price >= *filter.MinPrice) &&
+			(filter.MaxPrice == nil || v.Price <= *filter.MaxPrice) &&
+			(!filter.InStock || v.StockQuantity > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowestPrice is the cheapest p can be bought at. Callers must hold mu.
+func (s *memProducts) lowestPrice(p *models.Product) float64 {
+	variants := s.purchasable(p)
+	lowest := variants[0].Price
+	for _, v := range variants[1:] {
+		lowest = min(lowest, v.Price)
+	}
+	return lowest
+}
+
+func (s *memProducts) hasAttribute(productID int32, want AttributeFilter) bool {
+	for _, a := range s.m.attributes {
+		if a.ProductID == productID && strings.EqualFold(a.Name, want.Name) && strings.EqualFold(a.Value, want.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memProducts) averageRating(productID int32) float64 {
+	var sum, n int32
+	for _, rev := range s.m.reviews {
+		if rev.ProductID == productID {
+			sum += rev.Rating
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(sum) / float64(n)
+}
+
+// sortKey returns a function rendering a product's sort key as a string that
+// orders correctly under string comparison, and whether the order is
+// descending.
+func (s *memProducts) sortKey(sort ProductSort) (func(*models.Product) string, bool) {
+	switch sort {
+	case SortPriceAsc, SortPriceDesc:
+		return func(p *models.Product) string { return fmt.Sprintf("%020.4f", s.lowestPrice(p)) }, sort == SortPriceDesc
+	case SortNewest:
+		return func(p *models.Product) string { return p.CreatedAt }, true
+	case SortRating:
+		return func(p *models.Product) string { return fmt.Sprintf("%08.4f", s.averageRating(p.ID)) }, true
+	case SortName:
+		return func(p *models.Product) string { return p.Name }, false
+	default:
+		return func(p *models.Product) string { return "" }, false
+	}
+}
+
+func (s *memProducts) Create(ctx context.Context, input models.ProductInput) (*models.Product, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.categories[input.CategoryID]; !ok {
+		return nil, fmt.Errorf("category with ID %d does not exist", input.CategoryID)
+	}
+
+	p := &models.Product{
+		ID:            s.m.id("products"),
+		Name:          input.Name,
+		Description:   input.Description,
+		Price:         input.Price,
+		StockQuantity: input.StockQuantity,
+		CategoryID:    input.CategoryID,
+		CreatedAt:     now(),
+	}
+	s.m.products[p.ID] = p
+	s.insertImages(p.ID, nil, input.Images)
+	if input.Attributes != nil {
+		s.insertAttributes(p.ID, *input.Attributes)
+	}
+
+	c := *p
+	return &c, nil
+}
+
+func (s *memProducts) Update(ctx context.Context, id int32, input models.ProductInput) (*models.Product, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.products[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	p.Name = input.Name
+	p.Description = input.Description
+	p.Price = input.Price
+	p.StockQuantity = input.StockQuantity
+	p.CategoryID = input.CategoryID
+
+	for imgID, img := range s.m.images {
+		if img.ProductID == id && img.VariantID == nil {
+			delete(s.m.images, imgID)
+		}
+	}
+	s.insertImages(id, nil, input.Images)
+	if input.Attributes != nil {
+		for attrID, a := range s.m.attributes {
+			if a.ProductID == id {
+				delete(s.m.attributes, attrID)
+			}
+		}
+		s.insertAttributes(id, *input.Attributes)
+	}
+
+	c := *p
+	return &c, nil
+}
+
+// insertImages adds image rows for a product, or for one of its variants
+// when variantID is set. Callers must hold mu.
+func (s *memProducts) insertImages(productID int32, variantID *int32, images []models.ProductImageInput) {
+	for _, img := range images {
+		id := s.m.id("product_images")
+		s.m.images[id] = &models.ProductImage{
+			ID:        id,
+			ProductID: productID,
+			VariantID: variantID,
+			ImageID:   img.ImageID,
+			ImageUrl:  *img.ImageUrl,
+			IsPrimary: img.IsPrimary,
+		}
+	}
+}
+
+// insertAttributes adds attribute rows for a product. Callers must hold mu.
+func (s *memProducts) insertAttributes(productID int32, attributes []models.ProductAttributeInput) {
+	for _, a := range attributes {
+		id := s.m.id("product_attributes")
+		s.m.attributes[id] = &models.ProductAttribute{ID: id, ProductID: productID, Name: a.Name, Value: a.Value}
+	}
+}
+
+func (s *memProducts) Delete(ctx context.Context, id int32) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.products[id]; !ok {
+		return false, nil
+	}
+	delete(s.m.products, id)
+	// Mirror ON DELETE CASCADE on cart_items.
+	for itemID, it := range s.m.cartItems {
+		if it.ProductID == id {
+			delete(s.m.cartItems, itemID)
+		}
+	}
+	for _, held := range s.m.reservations {
+		for k := range held {
+			if k.ProductID == id {
+				delete(held, k)
+			}
+		}
+	}
+	for variantID, v := range s.m.variants {
+		if v.ProductID == id {
+			s.deleteVariant(variantID)
+		}
+	}
+	for optionID, o := range s.m.options {
+		if o.ProductID == id {
+			delete(s.m.options, optionID)
+		}
+	}
+	return true, nil
+}
+
+func (s *memProducts) Images(ctx context.Context, productID int32) ([]*models.ProductImage, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.images, func(img *models.ProductImage) bool {
+		return img.ProductID == productID && img.VariantID == nil
+	}), nil
+}
+
+func (s *memProducts) ImagesByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductImage, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	productImages := make(map[int32]*models.ProductImage)
+	for id, img := range s.m.images {
+		if img.VariantID == nil {
+			productImages[id] = img
+		}
+	}
+	return groupBy(productImages, productIDs, func(img *models.ProductImage) int32 { return img.ProductID }), nil
+}
+
+func (s *memProducts) AddImage(ctx context.Context, productID int32, input models.ProductImageInput) (*models.ProductImage, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.products[productID]; !ok {
+		return nil, ErrNotFound
+	}
+	img := &models.ProductImage{
+		ID:        s.m.id("product_images"),
+		ProductID: productID,
+		ImageID:   input.ImageID,
+		ImageUrl:  *input.ImageUrl,
+		IsPrimary: input.IsPrimary,
+	}
+	s.m.images[img.ID] = img
+
+	c := *img
+	return &c, nil
+}
+
+func (s *memProducts) Attributes(ctx context.Context, productID int32) ([]*models.ProductAttribute, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.attributes, func(a *models.ProductAttribute) bool {
+		return a.ProductID == productID
+	}), nil
+}
+
+func (s *memProducts) AttributesByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductAttribute, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return groupBy(s.m.attributes, productIDs, func(a *models.ProductAttribute) int32 { return a.ProductID }), nil
+}
+
+func (s *memProducts) GetAttribute(ctx context.Context, id int32) (*models.ProductAttribute, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	a, ok := s.m.attributes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *a
+	return &c, nil
+}
+
+func (s *memProducts) AddAttribute(ctx context.Context, productID int32, input models.ProductAttributeInput) (*models.ProductAttribute, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.products[productID]; !ok {
+		return nil, ErrNotFound
+	}
+	a := &models.ProductAttribute{
+		ID:        s.m.id("product_attributes"),
+		ProductID: productID,
+		Name:      input.Name,
+		Value:     input.Value,
+	}
+	s.m.attributes[a.ID] = a
+
+	c := *a
+	return &c, nil
+}
+
+func (s *memProducts) UpdateAttribute(ctx context.Context, id int32, input models.ProductAttributeInput) (*models.ProductAttribute, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	a, ok := s.m.attributes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	a.Name = input.Name
+	a.Value = input.Value
+
+	c := *a
+	return &c, nil
+}
+
+func (s *memProducts) DeleteAttribute(ctx context.Context, id int32) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.attributes[id]; !ok {
+		return false, nil
+	}
+	delete(s.m.attributes, id)
+	return true, nil
+}
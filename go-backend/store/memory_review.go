@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+
+	"go-backend/models"
+)
+
+type memReviews struct {
+	m *memDB
+}
+
+func (s *memReviews) ListByProduct(ctx context.Context, productID int32) ([]*models.Review, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return sortedValues(s.m.reviews, func(rev *models.Review) bool {
+		return rev.ProductID == productID
+	}), nil
+}
+
+func (s *memReviews) ListByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.Review, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return groupBy(s.m.reviews, productIDs, func(rev *models.Review) int32 { return rev.ProductID }), nil
+}
+
+func (s *memReviews) PageByProduct(ctx context.Context, productID int32, page PageArgs) (*Page[models.Review], error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	reviews := sortedValues(s.m.reviews, func(rev *models.Review) bool {
+		return rev.ProductID == productID
+	})
+	sortNewestFirst(reviews, func(rev *models.Review) (string, int32) { return rev.CreatedAt, rev.ID })
+	return memPage(reviews, page, "",
+		func(rev *models.Review) Cursor { return Cursor{Key: rev.CreatedAt, ID: rev.ID} },
+		func(rev *models.Review, c Cursor) int { return cmpKey(rev.CreatedAt, rev.ID, c, true) })
+}
+
+func (s *memReviews) Create(ctx context.Context, userID int32, input models.ReviewInput) (*models.Review, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	rev := &models.Review{
+		ID:        s.m.id("reviews"),
+		ProductID: input.ProductID,
+		UserID:    userID,
+		Rating:    input.Rating,
+		CreatedAt: now(),
+	}
+	if input.Comment != nil {
+		rev.Comment = *input.Comment
+	}
+	s.m.reviews[rev.ID] = rev
+
+	c := *rev
+	return &c, nil
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go-backend/models"
+)
+
+// Field weights mirror the A/B/C weights of products.search_vector.
+const (
+	memNameWeight        = 1.0
+	memDescriptionWeight = 0.4
+	memAttributeWeight   = 0.2
+	memFuzzyThreshold    = 0.3
+)
+
+// Search approximates the Postgres implementation with simple term matching
+// and an edit-distance fallback.
+func (s *memProducts) Search(ctx context.Context, query string, page PageArgs) (*Page[models.SearchHit], error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	terms := searchTerms(query)
+	var fuzzy bool
+	if c := page.After; c != nil {
+		fuzzy = c.Sort == fuzzySort
+	} else if c := page.Before; c != nil {
+		fuzzy = c.Sort == fuzzySort
+	}
+
+	var hits []*models.SearchHit
+	if !fuzzy {
+		for _, p := range sortedValues(s.m.products, nil) {
+			if score := s.termScore(p, terms); score > 0 {
+				hits = append(hits, &models.SearchHit{Product: *p, Score: score, Snippet: highlight(searchSnippetSource(p), terms)})
+			}
+		}
+		fuzzy = len(hits) == 0 && page.After == nil && page.Before == nil
+	}
+	if fuzzy {
+		for _, p := range sortedValues(s.m.products, nil) {
+			if score := wordSimilarity(strings.ToLower(query), strings.ToLower(p.Name)); score >= memFuzzyThreshold {
+				hits = append(hits, &models.SearchHit{Product: *p, Score: score, Snippet: html.EscapeString(searchSnippetSource(p)), Fuzzy: true})
+			}
+		}
+	}
+
+	name := searchSort
+	if fuzzy {
+		name = fuzzySort
+	}
+	key := func(hit *models.SearchHit) string { return fmt.Sprintf("%012.6f", hit.Score) }
+	sort.SliceStable(hits, func(i, j int) bool {
+		return cmpKey(key(hits[i]), hits[i].Product.ID, Cursor{Key: key(hits[j]), ID: hits[j].Product.ID}, true) < 0
+	})
+	return memPage(hits, page, name,
+		func(hit *models.SearchHit) Cursor { return Cursor{Sort: name, Key: key(hit), ID: hit.Product.ID} },
+		func(hit *models.SearchHit, c Cursor) int { return cmpKey(key(hit), hit.Product.ID, c, true) })
+}
+
+// termScore sums the field weights of every query term found in p.
+// Callers must hold mu.
+func (s *memProducts) termScore(p *models.Product, terms []string) float64 {
+	name := searchTerms(p.Name)
+	var description []string
+	if p.Description != nil {
+		description = searchTerms(*p.Description)
+	}
+	var attributes []string
+	for _, a := range s.m.attributes {
+		if a.ProductID == p.ID {
+			attributes = append(attributes, searchTerms(a.Value)...)
+		}
+	}
+
+	var score float64
+	for _, t := range terms {
+		switch {
+		case containsTerm(name, t):
+			score += memNameWeight
+		case containsTerm(description, t):
+			score += memDescriptionWeight
+		case containsTerm(attributes, t):
+			score += memAttributeWeight
+		}
+	}
+	return score
+}
+
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func containsTerm(words []string, term string) bool {
+	for _, w := range words {
+		if w == term {
+			return true
+		}
+	}
+	return false
+}
+
+func searchSnippetSource(p *models.Product) string {
+	if p.Description != nil && *p.Description != "" {
+		return *p.Description
+	}
+	return p.Name
+}
+
+// highlight HTML-escapes text and wraps every word of it that matches one
+// of terms in <b></b>.
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	word := func(w string) {
+		if containsTerm(terms, strings.ToLower(w)) {
+			b.WriteString("<b>" + w + "</b>")
+		} else {
+			b.WriteString(w)
+		}
+	}
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord {
+			if start >= 0 {
+				word(text[start:i])
+				start = -1
+			}
+			b.WriteString(html.EscapeString(string(r)))
+		}
+	}
+	if start >= 0 {
+		word(text[start:])
+	}
+	return b.String()
+}
+
+// wordSimilarity returns the best similarity between query and any word of
+// text, based on edit distance and scaled to [0, 1].
+func wordSimilarity(query, text string) float64 {
+	var best float64
+	for _, w := range searchTerms(text) {
+		longest := len([]rune(w))
+		if n := len([]rune(query)); n > longest {
+			longest = n
+		}
+		if longest == 0 {
+			continue
+		}
+		sim := 1 - float64(levenshtein(query, w))/float64(longest)
+		if sim > best {
+			best = sim
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
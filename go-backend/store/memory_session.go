@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-backend/models"
+)
+
+type memSessions struct {
+	m *memDB
+}
+
+// memSession is a row of upload_sessions; the times are kept as time.Time
+// so the status can be worked out when the session is read.
+type memSession struct {
+	models.UploadSession
+	expiresAt time.Time
+	revokedAt *time.Time
+}
+
+// status works out the session's status at t, as upload_sessions does in
+// sessionStatus.
+func (s *memSession) status(t time.Time) string {
+	switch {
+	case s.revokedAt != nil:
+		return "REVOKED"
+	case s.UploadCount >= s.MaxUploads:
+		return "COMPLETED"
+	case !t.Before(s.expiresAt):
+		return "EXPIRED"
+	}
+	return "ACTIVE"
+}
+
+func (s *memSession) row() *models.UploadSession {
+	c := s.UploadSession
+	c.Status = s.status(time.Now())
+	c.ExpiresAt = formatTime(s.expiresAt)
+	if s.revokedAt != nil {
+		revokedAt := formatTime(*s.revokedAt)
+		c.RevokedAt = &revokedAt
+	}
+	return &c
+}
+
+func (s *memSessions) Create(ctx context.Context, session NewUploadSession) (*models.UploadSession, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.users[session.UserID]; !ok {
+		return nil, ErrNotFound
+	}
+	if session.ProductID != nil {
+		if _, ok := s.m.products[*session.ProductID]; !ok {
+			return nil, ErrNotFound
+		}
+	}
+	row := &memSession{
+		UploadSession: models.UploadSession{
+			ID:         session.ID,
+			UserID:     session.UserID,
+			ProductID:  session.ProductID,
+			MaxUploads: session.MaxUploads,
+			CreatedAt:  now(),
+		},
+		expiresAt: session.ExpiresAt,
+	}
+	s.m.sessions[session.ID] = row
+	return row.row(), nil
+}
+
+func (s *memSessions) Get(ctx context.Context, id string) (*models.UploadSession, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	row, ok := s.m.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return row.row(), nil
+}
+
+func (s *memSessions) Revoke(ctx context.Context, id string) (*models.UploadSession, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	row, ok := s.m.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if row.revokedAt == nil {
+		t := time.Now()
+		row.revokedAt = &t
+	}
+	return row.row(), nil
+}
+
+func (s *memSessions) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var n int64
+	for id, row := range s.m.sessions {
+		if row.expiresAt.Before(t) {
+			delete(s.m.sessions, id)
+			// Mirror ON DELETE SET NULL on images.
+			for _, img := range s.m.uploads {
+				if img.SessionID != nil && *img.SessionID == id {
+					img.SessionID = nil
+				}
+			}
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type memTokens struct {
+	m *memDB
+}
+
+func (s *memTokens) SigningKeys(ctx context.Context) ([]*SigningKey, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(s.m.signingKeys))
+	for _, k := range s.m.signingKeys {
+		c := *k
+		keys = append(keys, &c)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (s *memTokens) AddSigningKey(ctx context.Context, key *SigningKey) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c := *key
+	s.m.signingKeys[key.ID] = &c
+	return nil
+}
+
+func (s *memTokens) DeleteSigningKeysBefore(ctx context.Context, t time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for id, k := range s.m.signingKeys {
+		if k.CreatedAt.Before(t) {
+			delete(s.m.signingKeys, id)
+		}
+	}
+	return nil
+}
+
+func (s *memTokens) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c := *token
+	s.m.refreshTokens[token.Hash] = &c
+	return nil
+}
+
+func (s *memTokens) ConsumeRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	t, ok := s.m.refreshTokens[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if t.RevokedAt != nil {
+		c := *t
+		return &c, ErrTokenReused
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	c := *t
+	return &c, nil
+}
+
+func (s *memTokens) RevokeRefreshToken(ctx context.Context, hash string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if t, ok := s.m.refreshTokens[hash]; ok && t.RevokedAt == nil {
+		now := time.Now()
+		t.RevokedAt = &now
+	}
+	return nil
+}
+
+func (s *memTokens) RevokeRefreshFamily(ctx context.Context, family string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range s.m.refreshTokens {
+		if t.Family == family && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *memTokens) DeleteRefreshTokensBefore(ctx context.Context, t time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for hash, rt := range s.m.refreshTokens {
+		if rt.ExpiresAt.Before(t) {
+			delete(s.m.refreshTokens, hash)
+		}
+	}
+	return nil
+}
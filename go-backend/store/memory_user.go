@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"go-backend/models"
+)
+
+type memUsers struct {
+	m *memDB
+}
+
+func (s *memUsers) Get(ctx context.Context, id int32) (*models.User, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	u, ok := s.m.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *u
+	return &c, nil
+}
+
+func (s *memUsers) GetMany(ctx context.Context, ids []int32) (map[int32]*models.User, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return pick(s.m.users, ids), nil
+}
+
+func (s *memUsers) Create(ctx context.Context, nu NewUser) (*models.User, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if s.byEmail(nu.Email) != nil {
+		return nil, ErrEmailTaken
+	}
+	u := &models.User{
+		ID:        s.m.id("users"),
+		Email:     nu.Email,
+		FirstName: nu.FirstName,
+		LastName:  nu.LastName,
+		Role:      "customer",
+	}
+	s.m.users[u.ID] = u
+	s.m.passwords[u.ID] = nu.PasswordHash
+
+	c := *u
+	return &c, nil
+}
+
+func (s *memUsers) Credentials(ctx context.Context, email string) (*models.User, string, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	u := s.byEmail(email)
+	if u == nil {
+		return nil, "", ErrNotFound
+	}
+	c := *u
+	return &c, s.m.passwords[u.ID], nil
+}
+
+// byEmail must be called with the lock held.
+func (s *memUsers) byEmail(email string) *models.User {
+	for _, u := range s.m.users {
+		if strings.EqualFold(u.Email, email) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *memUsers) SetRole(ctx context.Context, id int32, role string) (*models.User, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	u, ok := s.m.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	u.Role = role
+	c := *u
+	return &c, nil
+}
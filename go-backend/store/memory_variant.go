@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+
+	"go-backend/models"
+)
+
+func (s *memProducts) GetVariant(ctx context.Context, id int32) (*models.ProductVariant, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	v, ok := s.m.variants[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	c := *v
+	return &c, nil
+}
+
+func (s *memProducts) GetVariants(ctx context.Context, ids []int32) (map[int32]*models.ProductVariant, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return pick(s.m.variants, ids), nil
+}
+
+func (s *memProducts) VariantsByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductVariant, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	return groupBy(s.m.variants, productIDs, func(v *models.ProductVariant) int32 { return v.ProductID }), nil
+}
+
+// skuTaken reports whether a variant other than id has sku. Callers must
+// hold mu.
+func (s *memProducts) skuTaken(sku string, id int32) bool {
+	for _, v := range s.m.variants {
+		if v.SKU == sku && v.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func variantOptions(input []models.VariantOptionInput) []models.VariantOption {
+	options := make([]models.VariantOption, len(input))
+	for i, o := range input {
+		options[i] = models.VariantOption{Name: o.Name, Value: o.Value}
+	}
+	return options
+}
+
+func (s *memProducts) CreateVariant(ctx context.Context, productID int32, input models.ProductVariantInput) (*models.ProductVariant, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.products[productID]; !ok {
+		return nil, ErrNotFound
+	}
+	if s.skuTaken(input.SKU, 0) {
+		return nil, ErrSKUTaken
+	}
+	v := &models.ProductVariant{
+		ID:            s.m.id("product_variants"),
+		ProductID:     productID,
+		SKU:           input.SKU,
+		Price:         input.Price,
+		StockQuantity: input.StockQuantity,
+		Options:       variantOptions(input.Options),
+		CreatedAt:     now(),
+	}
+	s.m.variants[v.ID] = v
+	variantID := v.ID
+	s.insertImages(productID, &variantID, input.Images)
+
+	c := *v
+	return &c, nil
+}
+
+func (s *memProducts) UpdateVariant(ctx context.Context, id int32, input models.ProductVariantInput) (*models.ProductVariant, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	v, ok := s.m.variants[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if s.skuTaken(input.SKU, id) {
+		return nil, ErrSKUTaken
+	}
+	v.SKU = input.SKU
+	v.Price = input.Price
+	v.StockQuantity = input.StockQuantity
+	v.Options = variantOptions(input.Options)
+
+	for imgID, img := range s.m.images {
+		if img.VariantID != nil && *img.VariantID == id {
+			delete(s.m.images, imgID)
+		}
+	}
+	s.insertImages(v.ProductID, &id, input.Images)
+
+	c := *v
+	return &c, nil
+}
+
+func (s *memProducts) DeleteVariant(ctx context.Context, id int32) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.variants[id]; !ok {
+		return false, nil
+	}
+	s.deleteVariant(id)
+	return true, nil
+}
+
+// deleteVariant removes a variant, mirroring the foreign keys on its
+// images, cart lines, reservations and order items. Callers must hold mu.
+func (s *memProducts) deleteVariant(id int32) {
+	v := s.m.variants[id]
+	delete(s.m.variants, id)
+	for imgID, img := range s.m.images {
+		if img.VariantID != nil && *img.VariantID == id {
+			delete(s.m.images, imgID)
+		}
+	}
+	for itemID, it := range s.m.cartItems {
+		if it.VariantID != nil && *it.VariantID == id {
+			delete(s.m.cartItems, itemID)
+		}
+	}
+	for _, held := range s.m.reservations {
+		delete(held, StockKey{v.ProductID, id})
+	}
+	for _, item := range s.m.orderItems {
+		if item.VariantID != nil && *item.VariantID == id {
+			item.VariantID = nil
+		}
+	}
+}
+
+func (s *memProducts) ImagesByVariants(ctx context.Context, variantIDs []int32) (map[int32][]*models.ProductImage, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+
+	variantImages := make(map[int32]*models.ProductImage)
+	for id, img := range s.m.images {
+		if img.VariantID != nil {
+			variantImages[id] = img
+		}
+	}
+	return groupBy(variantImages, variantIDs, func(img *models.ProductImage) int32 { return *img.VariantID }), nil
+}
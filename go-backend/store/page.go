@@ -0,0 +1,124 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// DefaultPageSize is used when neither First nor Last is given.
+	DefaultPageSize = 20
+	// MaxPageSize caps First and Last.
+	MaxPageSize = 100
+)
+
+// Cursor identifies a row's position in a keyset-ordered listing: the value
+// of the sort key rendered as text, and the row ID as a tie-breaker. Sort
+// names the ordering the cursor was issued for, where a listing has several.
+type Cursor struct {
+	Sort string `json:"s,omitempty"`
+	Key  string `json:"k,omitempty"`
+	ID   int32  `json:"i"`
+}
+
+// Encode returns the opaque string form handed to clients.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a string produced by Cursor.Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &c, nil
+}
+
+// PageArgs is a Relay-style pagination request. Forward pagination uses
+// First/After, backward pagination Last/Before.
+type PageArgs struct {
+	First  *int32
+	After  *Cursor
+	Last   *int32
+	Before *Cursor
+}
+
+// backward reports whether the page is requested from the end.
+func (a PageArgs) backward() bool {
+	return a.Last != nil || (a.First == nil && a.Before != nil)
+}
+
+// check rejects cursors issued for a different ordering.
+func (a PageArgs) check(sort string) error {
+	for _, c := range []*Cursor{a.After, a.Before} {
+		if c != nil && c.Sort != sort {
+			return fmt.Errorf("cursor does not match the requested sort order")
+		}
+	}
+	return nil
+}
+
+// limit returns the validated page size.
+func (a PageArgs) limit() (int, error) {
+	if a.First != nil && a.Last != nil {
+		return 0, fmt.Errorf("cannot combine first and last")
+	}
+	n := int32(DefaultPageSize)
+	if a.First != nil {
+		n = *a.First
+	} else if a.Last != nil {
+		n = *a.Last
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("page size must not be negative")
+	}
+	if n > MaxPageSize {
+		n = MaxPageSize
+	}
+	return int(n), nil
+}
+
+// Edge is one row of a page with its cursor.
+type Edge[T any] struct {
+	Node   *T
+	Cursor Cursor
+}
+
+// Page is one slice of a keyset-paginated listing.
+type Page[T any] struct {
+	Edges           []Edge[T]
+	HasNextPage     bool
+	HasPreviousPage bool
+	// TotalCount is the size of the whole listing, ignoring the cursors.
+	TotalCount int
+}
+
+// finishPage trims the extra look-ahead row fetched to detect further pages
+// and restores forward order for backward requests.
+func finishPage[T any](edges []Edge[T], total, limit int, args PageArgs) *Page[T] {
+	more := len(edges) > limit
+	if more {
+		edges = edges[:limit]
+	}
+
+	page := &Page[T]{TotalCount: total}
+	if args.backward() {
+		for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+			edges[i], edges[j] = edges[j], edges[i]
+		}
+		page.HasPreviousPage = more
+		page.HasNextPage = args.Before != nil
+	} else {
+		page.HasNextPage = more
+		page.HasPreviousPage = args.After != nil
+	}
+	page.Edges = edges
+	return page
+}
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-backend/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []Cursor{
+		{ID: 1},
+		{Sort: string(SortPriceAsc), Key: "0000012.990000", ID: 7},
+		{Sort: string(SortName), Key: "Tasse «Grün» & co/?+=", ID: 42},
+		{Sort: fuzzySort, Key: "", ID: -3},
+	}
+	for _, c := range tests {
+		s := c.Encode()
+		if strings.ContainsAny(s, "+/=") {
+			t.Errorf("%+v encodes to %q, which isn't URL-safe", c, s)
+		}
+		got, err := DecodeCursor(s)
+		if err != nil {
+			t.Errorf("DecodeCursor(%q): %v", s, err)
+			continue
+		}
+		if *got != c {
+			t.Errorf("DecodeCursor(%q) = %+v, want %+v", s, *got, c)
+		}
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{"empty", ""},
+		{"not base64", "a cursor!"},
+		{"padded base64", Cursor{ID: 1}.Encode() + "=="},
+		{"not JSON", "bm90IGpzb24"},
+		{"wrong types", "eyJpIjoieCJ9"}, // {"i":"x"}
+	}
+	for _, tt := range tests {
+		if c, err := DecodeCursor(tt.s); err == nil {
+			t.Errorf("%s: DecodeCursor(%q) = %+v, want an error", tt.name, tt.s, *c)
+		}
+	}
+}
+
+func TestPageArgsLimit(t *testing.T) {
+	n := func(v int32) *int32 { return &v }
+	tests := []struct {
+		name    string
+		args    PageArgs
+		want    int
+		wantErr bool
+	}{
+		{name: "default", want: DefaultPageSize},
+		{name: "first", args: PageArgs{First: n(5)}, want: 5},
+		{name: "last", args: PageArgs{Last: n(3)}, want: 3},
+		{name: "zero", args: PageArgs{First: n(0)}, want: 0},
+		{name: "capped", args: PageArgs{First: n(MaxPageSize + 1)}, want: MaxPageSize},
+		{name: "negative", args: PageArgs{Last: n(-1)}, wantErr: true},
+		{name: "first and last", args: PageArgs{First: n(1), Last: n(1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.args.limit()
+		switch {
+		case tt.wantErr && err == nil:
+			t.Errorf("%s: limit() = %d, want an error", tt.name, got)
+		case !tt.wantErr && (err != nil || got != tt.want):
+			t.Errorf("%s: limit() = %d, %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// TestProductPages walks the products of a memory store a page at a time
+// with the cursors it hands out, in both directions.
+func TestProductPages(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	cat, err := s.Categories.Create(ctx, "Mugs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two products share a price, so the ID breaks the tie.
+	for i, price := range []float64{5, 3, 4, 3, 1} {
+		_, err := s.Products.Create(ctx, models.ProductInput{Name: string(rune('A' + i)), Price: price, CategoryID: cat.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := func(v int32) *int32 { return &v }
+	names := func(p *Page[models.Product]) string {
+		var b strings.Builder
+		for _, e := range p.Edges {
+			b.WriteString(e.Node.Name)
+		}
+		return b.String()
+	}
+	byPrice := ProductFilter{Sort: SortPriceAsc}
+
+	tests := []struct {
+		name     string
+		filter   ProductFilter
+		page     func(prev *Page[models.Product]) PageArgs
+		want     string
+		wantNext bool
+		wantPrev bool
+	}{
+		{
+			name:     "first page",
+			filter:   byPrice,
+			page:     func(*Page[models.Product]) PageArgs { return PageArgs{First: n(2)} },
+			want:     "EB",
+			wantNext: true,
+		},
+		{
+			name:     "after the last edge",
+			filter:   byPrice,
+			page:     func(prev *Page[models.Product]) PageArgs { return PageArgs{First: n(2), After: &prev.Edges[1].Cursor} },
+			want:     "DC",
+			wantNext: true,
+			wantPrev: true,
+		},
+		{
+			name:     "last page",
+			filter:   byPrice,
+			page:     func(prev *Page[models.Product]) PageArgs { return PageArgs{First: n(2), After: &prev.Edges[1].Cursor} },
+			want:     "A",
+			wantPrev: true,
+		},
+		{
+			name:     "back before the first edge",
+			filter:   byPrice,
+			page:     func(prev *Page[models.Product]) PageArgs { return PageArgs{Last: n(2), Before: &prev.Edges[0].Cursor} },
+			want:     "DC",
+			wantNext: true,
+			wantPrev: true,
+		},
+		{
+			name:     "descending",
+			filter:   ProductFilter{Sort: SortPriceDesc},
+			page:     func(*Page[models.Product]) PageArgs { return PageArgs{First: n(3)} },
+			want:     "ACD",
+			wantNext: true,
+		},
+	}
+	var prev *Page[models.Product]
+	for _, tt := range tests {
+		page, err := s.Products.Page(ctx, tt.filter, tt.page(prev))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := names(page); got != tt.want || page.HasNextPage != tt.wantNext || page.HasPreviousPage != tt.wantPrev {
+			t.Errorf("%s: got %s, next %v, previous %v; want %s, next %v, previous %v",
+				tt.name, got, page.HasNextPage, page.HasPreviousPage, tt.want, tt.wantNext, tt.wantPrev)
+		}
+		if page.TotalCount != 5 {
+			t.Errorf("%s: TotalCount = %d, want 5", tt.name, page.TotalCount)
+		}
+		prev = page
+	}
+
+	// A cursor only fits the ordering it was issued for.
+	first, err := s.Products.Page(ctx, byPrice, PageArgs{First: n(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Products.Page(ctx, ProductFilter{Sort: SortName}, PageArgs{After: &first.Edges[0].Cursor}); err == nil {
+		t.Error("a PRICE_ASC cursor was accepted for NAME")
+	}
+}
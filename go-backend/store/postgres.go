@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// NewPostgres returns a Store backed by the given Postgres connection pool.
+func NewPostgres(db *sql.DB) *Store {
+	return &Store{
+		Products:    &pgProducts{db: db},
+		Categories:  &pgCategories{db: db},
+		Orders:      &pgOrders{db: db},
+		Reviews:     &pgReviews{db: db},
+		Users:       &pgUsers{db: db},
+		Tokens:      &pgTokens{db: db},
+		Carts:       &pgCarts{db: db},
+		Inventory:   &pgInventory{db: db},
+		Payments:    &pgPayments{db: db},
+		Idempotency: &pgIdempotency{db: db},
+		Images:      &pgImages{db: db},
+		Sessions:    &pgSessions{db: db},
+	}
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// uniqueViolation is the Postgres error code for a unique constraint failure.
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a unique constraint failure.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"go-backend/models"
+)
+
+type pgCarts struct {
+	db *sql.DB
+}
+
+const cartColumns = "id, token, user_id, updated_at, expires_at"
+
+func scanCart(row scanner) (*models.Cart, error) {
+	var c models.Cart
+	var token sql.NullString
+	var userID sql.NullInt32
+	if err := row.Scan(&c.ID, &token, &userID, &c.UpdatedAt, &c.ExpiresAt); err != nil {
+		return nil, err
+	}
+	c.Token = token.String
+	c.UserID = userID.Int32
+	return &c, nil
+}
+
+const cartItemColumns = "id, cart_id, product_id, variant_id, customizations, quantity, price_at_add, added_at"
+
+func scanCartItem(row scanner) (*models.CartItem, error) {
+	var it models.CartItem
+	var variantID sql.NullInt32
+	var customizations []byte
+	if err := row.Scan(&it.ID, &it.CartID, &it.ProductID, &variantID, &customizations, &it.Quantity, &it.PriceAtAdd, &it.AddedAt); err != nil {
+		return nil, err
+	}
+	if variantID.Valid {
+		it.VariantID = &variantID.Int32
+	}
+	if err := json.Unmarshal(customizations, &it.Customizations); err != nil {
+		return nil, err
+	}
+	return &it, nil
+}
+
+// marshalCustomizations encodes customizations for a JSONB column, as an
+// empty array rather than null when there are none.
+func marshalCustomizations(customizations []models.Customization) ([]byte, error) {
+	if customizations == nil {
+		customizations = []models.Customization{}
+	}
+	return json.Marshal(customizations)
+}
+
+// cartLineConflict is the conflict target matching cart_items_line_idx: one
+// line per product, variant and set of customizations.
+const cartLineConflict = "(cart_id, product_id, COALESCE(variant_id, 0), customizations)"
+
+// ownerCondition returns the WHERE condition selecting owner's cart and its
+// parameter.
+func ownerCondition(owner CartOwner) (string, any) {
+	if owner.Token != "" {
+		return "token = $1", owner.Token
+	}
+	return "user_id = $1", owner.UserID
+}
+
+func (s *pgCarts) Get(ctx context.Context, owner CartOwner) (*models.Cart, error) {
+	cond, arg := ownerCondition(owner)
+	c, err := scanCart(s.db.QueryRowContext(ctx, "SELECT "+cartColumns+" FROM carts WHERE "+cond+" AND expires_at > now()", arg))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return c, nil
+}
+
+func (s *pgCarts) Open(ctx context.Context, owner CartOwner, expiresAt time.Time) (*models.Cart, error) {
+	return openCart(ctx, s.db, owner, expiresAt)
+}
+
+// openCart upserts the owner's cart. An expired cart that hasn't been
+// cleaned up yet is replaced rather than revived with its stale lines.
+func openCart(ctx context.Context, q queryer, owner CartOwner, expiresAt time.Time) (*models.Cart, error) {
+	cond, arg := ownerCondition(owner)
+	if _, err := q.ExecContext(ctx, "DELETE FROM carts WHERE "+cond+" AND expires_at <= now()", arg); err != nil {
+		return nil, err
+	}
+	conflict := "user_id"
+	if owner.Token != "" {
+		conflict = "token"
+	}
+	return scanCart(q.QueryRowContext(ctx, `
+        INSERT INTO carts (token, user_id, expires_at)
+        VALUES (NULLIF($1, ''), NULLIF($2, 0), $3)
+        ON CONFLICT (`+conflict+`) DO UPDATE SET expires_at = EXCLUDED.expires_at, updated_at = now()
+        RETURNING `+cartColumns,
+		owner.Token, owner.UserID, expiresAt))
+}
+
+func (s *pgCarts) Items(ctx context.Context, cartID int32) ([]*models.CartItem, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+cartItemColumns+" FROM cart_items WHERE cart_id = $1 ORDER BY id", cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.CartItem
+	for rows.Next() {
+		it, err := scanCartItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// touchCart pushes back the cart's expiry after a write.
+func touchCart(ctx context.Context, q queryer, cartID int32, expiresAt time.Time) error {
+	_, err := q.ExecContext(ctx, "UPDATE carts SET updated_at = now(), expires_at = $2 WHERE id = $1", cartID, expiresAt)
+	return err
+}
+
+func (s *pgCarts) AddItem(ctx context.Context, cartID int32, item NewCartItem, expiresAt time.Time) (*models.CartItem, error) {
+	customizations, err := marshalCustomizations(item.Customizations)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	it, err := scanCartItem(tx.QueryRowContext(ctx, `
+        INSERT INTO cart_items (cart_id, product_id, variant_id, customizations, quantity, price_at_add)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT `+cartLineConflict+` DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+        RETURNING `+cartItemColumns,
+		cartID, item.ProductID, item.nullableVariant(), customizations, item.Quantity, item.Price))
+	if err != nil {
+		return nil, err
+	}
+	if err := touchCart(ctx, tx, cartID, expiresAt); err != nil {
+		return nil, err
+	}
+	return it, tx.Commit()
+}
+
+func (s *pgCarts) UpdateItem(ctx context.Context, cartID, itemID, quantity int32, expiresAt time.Time) (*models.CartItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	it, err := scanCartItem(tx.QueryRowContext(ctx,
+		"UPDATE cart_items SET quantity = $3 WHERE id = $2 AND cart_id = $1 RETURNING "+cartItemColumns,
+		cartID, itemID, quantity))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := touchCart(ctx, tx, cartID, expiresAt); err != nil {
+		return nil, err
+	}
+	return it, tx.Commit()
+}
+
+func (s *pgCarts) RemoveItem(ctx context.Context, cartID, itemID int32, expiresAt time.Time) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM cart_items WHERE id = $2 AND cart_id = $1", cartID, itemID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if err := touchCart(ctx, tx, cartID, expiresAt); err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+func (s *pgCarts) Clear(ctx context.Context, cartID int32, expiresAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM cart_items WHERE cart_id = $1", cartID); err != nil {
+		return err
+	}
+	if err := touchCart(ctx, tx, cartID, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *pgCarts) Merge(ctx context.Context, token string, userID int32, expiresAt time.Time) (*models.Cart, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cart, err := openCart(ctx, tx, CartOwner{UserID: userID}, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	// Lines of an expired anonymous cart are dropped with it.
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO cart_items (cart_id, product_id, variant_id, customizations, quantity, price_at_add, added_at)
+        SELECT $1, i.product_id, i.variant_id, i.customizations, i.quantity, i.price_at_add, i.added_at
+        FROM cart_items i JOIN carts c ON c.id = i.cart_id
+        WHERE c.token = $2 AND c.expires_at > now()
+        ON CONFLICT `+cartLineConflict+` DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+    `, cart.ID, token)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM carts WHERE token = $1", token); err != nil {
+		return nil, err
+	}
+	return cart, tx.Commit()
+}
+
+func (s *pgCarts) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM carts WHERE expires_at < $1", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
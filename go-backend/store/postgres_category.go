@@ -0,0 +1,334 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-backend/models"
+
+	"github.com/lib/pq"
+)
+
+type pgCategories struct {
+	db *sql.DB
+}
+
+func scanCategory(row scanner) (*models.Category, error) {
+	var c models.Category
+	var parentID sql.NullInt32
+	if err := row.Scan(&c.ID, &c.Name, &parentID); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		c.ParentCategory = &models.Category{ID: parentID.Int32}
+	}
+	return &c, nil
+}
+
+func (s *pgCategories) Get(ctx context.Context, id int32) (*models.Category, error) {
+	c, err := scanCategory(s.db.QueryRowContext(ctx, "SELECT id, name, parent_id FROM categories WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return c, nil
+}
+
+func (s *pgCategories) GetMany(ctx context.Context, ids []int32) (map[int32]*models.Category, error) {
+	categories, err := queryCategories(ctx, s.db, "SELECT id, name, parent_id FROM categories WHERE id = ANY($1)", pq.Int32Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int32]*models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+	return byID, nil
+}
+
+func (s *pgCategories) List(ctx context.Context) ([]*models.Category, error) {
+	return queryCategories(ctx, s.db, "SELECT id, name, parent_id FROM categories ORDER BY id")
+}
+
+func queryCategories(ctx context.Context, q queryer, query string, args ...any) ([]*models.Category, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (s *pgCategories) Create(ctx context.Context, name string, parentID *int32) (*models.Category, error) {
+	row := s.db.QueryRowContext(ctx, `
+        INSERT INTO categories (name, parent_id)
+        VALUES ($1, $2)
+        RETURNING id, name, parent_id
+    `, name, parentID)
+	return scanCategory(row)
+}
+
+func (s *pgCategories) Update(ctx context.Context, id int32, name string) (*models.Category, error) {
+	c, err := scanCategory(s.db.QueryRowContext(ctx,
+		"UPDATE categories SET name = $2 WHERE id = $1 RETURNING id, name, parent_id", id, name))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return c, nil
+}
+
+// lockCategories serialises changes to the category tree, so two concurrent
+// moves can't each pass the cycle check and together form a cycle. Reads are
+// not blocked.
+func lockCategories(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE")
+	return err
+}
+
+func (s *pgCategories) Move(ctx context.Context, id int32, parentID *int32) (*models.Category, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockCategories(ctx, tx); err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		var exists, cycle bool
+		err := tx.QueryRowContext(ctx, `
+            WITH RECURSIVE subtree AS (
+                SELECT id FROM categories WHERE id = $1
+                UNION
+                SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+            )
+            SELECT EXISTS (SELECT 1 FROM categories WHERE id = $2),
+                   EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+        `, id, *parentID).Scan(&exists, &cycle)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("parent category %d: %w", *parentID, ErrNotFound)
+		}
+		if cycle {
+			return nil, ErrCategoryCycle
+		}
+	}
+	c, err := scanCategory(tx.QueryRowContext(ctx,
+		"UPDATE categories SET parent_id = $2 WHERE id = $1 RETURNING id, name, parent_id", id, parentID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return c, tx.Commit()
+}
+
+func (s *pgCategories) Delete(ctx context.Context, id int32, reassignTo *int32) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := lockCategories(ctx, tx); err != nil {
+		return false, err
+	}
+	var parentID sql.NullInt32
+	if err := tx.QueryRowContext(ctx, "SELECT parent_id FROM categories WHERE id = $1", id).Scan(&parentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	// Lock the attached products so none can be added to the category while
+	// they are counted and moved.
+	var products int32
+	err = tx.QueryRowContext(ctx, `
+        SELECT count(*) FROM (SELECT id FROM products WHERE category_id = $1 FOR UPDATE) p
+    `, id).Scan(&products)
+	if err != nil {
+		return false, err
+	}
+	if products > 0 {
+		if reassignTo == nil {
+			return false, &CategoryInUseError{CategoryID: id, Products: products}
+		}
+		var exists bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND id <> $2)", *reassignTo, id).Scan(&exists)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return false, fmt.Errorf("category %d to reassign products to: %w", *reassignTo, ErrNotFound)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE products SET category_id = $2 WHERE category_id = $1", id, *reassignTo); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE categories SET parent_id = $2 WHERE parent_id = $1", id, parentID); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (s *pgCategories) ChildrenByParents(ctx context.Context, parentIDs []int32) (map[int32][]*models.Category, error) {
+	categories, err := queryCategories(ctx, s.db,
+		"SELECT id, name, parent_id FROM categories WHERE parent_id = ANY($1) ORDER BY id", pq.Int32Array(parentIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	byParent := make(map[int32][]*models.Category)
+	for _, c := range categories {
+		byParent[c.ParentCategory.ID] = append(byParent[c.ParentCategory.ID], c)
+	}
+	return byParent, nil
+}
+
+func (s *pgCategories) Ancestors(ctx context.Context, id int32) ([]*models.Category, error) {
+	return queryCategories(ctx, s.db, `
+        WITH RECURSIVE ancestors AS (
+            SELECT p.id, p.name, p.parent_id, 1 AS depth
+            FROM categories c JOIN categories p ON p.id = c.parent_id
+            WHERE c.id = $1
+            UNION ALL
+            SELECT p.id, p.name, p.parent_id, a.depth + 1
+            FROM categories p JOIN ancestors a ON p.id = a.parent_id
+        )
+        SELECT id, name, parent_id FROM ancestors ORDER BY depth DESC
+    `, id)
+}
+
+func (s *pgCategories) Descendants(ctx context.Context, id int32) ([]*models.Category, error) {
+	return queryCategories(ctx, s.db, `
+        WITH RECURSIVE subtree AS (
+            SELECT id FROM categories WHERE parent_id = $1
+            UNION
+            SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+        )
+        SELECT id, name, parent_id FROM categories WHERE id IN (SELECT id FROM subtree) ORDER BY id
+    `, id)
+}
+
+func (s *pgCategories) ProductCounts(ctx context.Context, ids []int32) (map[int32]int32, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        WITH RECURSIVE subtree AS (
+            SELECT id AS root, id FROM categories WHERE id = ANY($1)
+            UNION
+            SELECT subtree.root, c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+        )
+        SELECT subtree.root, count(p.id)
+        FROM subtree LEFT JOIN products p ON p.category_id = subtree.id
+        GROUP BY subtree.root
+    `, pq.Int32Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int32]int32, len(ids))
+	for rows.Next() {
+		var id, n int32
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		counts[id] = n
+	}
+	return counts, rows.Err()
+}
+
+const definitionColumns = "id, category_id, name, type, allowed_values, required"
+
+func scanDefinition(row scanner) (*models.AttributeDefinition, error) {
+	var d models.AttributeDefinition
+	var allowed pq.StringArray
+	if err := row.Scan(&d.ID, &d.CategoryID, &d.Name, &d.Type, &allowed, &d.Required); err != nil {
+		return nil, err
+	}
+	d.AllowedValues = allowed
+	return &d, nil
+}
+
+func (s *pgCategories) DefinitionsByCategories(ctx context.Context, ids []int32) (map[int32][]*models.AttributeDefinition, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+definitionColumns+" FROM attribute_definitions WHERE category_id = ANY($1) ORDER BY id", pq.Int32Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	definitions := make(map[int32][]*models.AttributeDefinition)
+	for rows.Next() {
+		d, err := scanDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		definitions[d.CategoryID] = append(definitions[d.CategoryID], d)
+	}
+	return definitions, rows.Err()
+}
+
+func (s *pgCategories) GetDefinition(ctx context.Context, id int32) (*models.AttributeDefinition, error) {
+	d, err := scanDefinition(s.db.QueryRowContext(ctx, "SELECT "+definitionColumns+" FROM attribute_definitions WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return d, nil
+}
+
+func (s *pgCategories) CreateDefinition(ctx context.Context, def models.AttributeDefinition) (*models.AttributeDefinition, error) {
+	d, err := scanDefinition(s.db.QueryRowContext(ctx, `
+        INSERT INTO attribute_definitions (category_id, name, type, allowed_values, required)
+        SELECT id, $2, $3, $4, $5 FROM categories WHERE id = $1
+        RETURNING `+definitionColumns,
+		def.CategoryID, def.Name, def.Type, pq.StringArray(def.AllowedValues), def.Required))
+	if isUniqueViolation(err) {
+		return nil, ErrAttributeDefined
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return d, nil
+}
+
+func (s *pgCategories) UpdateDefinition(ctx context.Context, id int32, def models.AttributeDefinition) (*models.AttributeDefinition, error) {
+	d, err := scanDefinition(s.db.QueryRowContext(ctx, `
+        UPDATE attribute_definitions SET name = $2, type = $3, allowed_values = $4, required = $5
+        WHERE id = $1
+        RETURNING `+definitionColumns,
+		id, def.Name, def.Type, pq.StringArray(def.AllowedValues), def.Required))
+	if isUniqueViolation(err) {
+		return nil, ErrAttributeDefined
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return d, nil
+}
+
+func (s *pgCategories) DeleteDefinition(ctx context.Context, id int32) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM attribute_definitions WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+
+	"go-backend/models"
+
+	"github.com/lib/pq"
+)
+
+const customizationOptionColumns = `id, product_id, name, kind, required, max_length, allowed_values,
+    min_width, max_width, min_height, max_height, price, unit_price, created_at`
+
+func scanCustomizationOption(row scanner) (*models.CustomizationOption, error) {
+	var o models.CustomizationOption
+	var allowed pq.StringArray
+	if err := row.Scan(&o.ID, &o.ProductID, &o.Name, &o.Kind, &o.Required, &o.MaxLength, &allowed,
+		&o.MinWidth, &o.MaxWidth, &o.MinHeight, &o.MaxHeight, &o.Price, &o.UnitPrice, &o.CreatedAt); err != nil {
+		return nil, err
+	}
+	o.AllowedValues = allowed
+	return &o, nil
+}
+
+func (s *pgProducts) CustomizationOptionsByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.CustomizationOption, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+customizationOptionColumns+" FROM customization_options WHERE product_id = ANY($1) ORDER BY id", pq.Int32Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := make(map[int32][]*models.CustomizationOption)
+	for rows.Next() {
+		o, err := scanCustomizationOption(rows)
+		if err != nil {
+			return nil, err
+		}
+		options[o.ProductID] = append(options[o.ProductID], o)
+	}
+	return options, rows.Err()
+}
+
+func (s *pgProducts) GetCustomizationOption(ctx context.Context, id int32) (*models.CustomizationOption, error) {
+	o, err := scanCustomizationOption(s.db.QueryRowContext(ctx,
+		"SELECT "+customizationOptionColumns+" FROM customization_options WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return o, nil
+}
+
+func (s *pgProducts) CreateCustomizationOption(ctx context.Context, opt models.CustomizationOption) (*models.CustomizationOption, error) {
+	o, err := scanCustomizationOption(s.db.QueryRowContext(ctx, `
+        INSERT INTO customization_options (product_id, name, kind, required, max_length, allowed_values,
+            min_width, max_width, min_height, max_height, price, unit_price)
+        SELECT id, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12 FROM products WHERE id = $1
+        RETURNING `+customizationOptionColumns,
+		opt.ProductID, opt.Name, opt.Kind, opt.Required, opt.MaxLength, pq.StringArray(opt.AllowedValues),
+		opt.MinWidth, opt.MaxWidth, opt.MinHeight, opt.MaxHeight, opt.Price, opt.UnitPrice))
+	if isUniqueViolation(err) {
+		return nil, ErrOptionDefined
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return o, nil
+}
+
+func (s *pgProducts) UpdateCustomizationOption(ctx context.Context, id int32, opt models.CustomizationOption) (*models.CustomizationOption, error) {
+	o, err := scanCustomizationOption(s.db.QueryRowContext(ctx, `
+        UPDATE customization_options SET name = $2, kind = $3, required = $4, max_length = $5, allowed_values = $6,
+            min_width = $7, max_width = $8, min_height = $9, max_height = $10, price = $11, unit_price = $12
+        WHERE id = $1
+        RETURNING `+customizationOptionColumns,
+		id, opt.Name, opt.Kind, opt.Required, opt.MaxLength, pq.StringArray(opt.AllowedValues),
+		opt.MinWidth, opt.MaxWidth, opt.MinHeight, opt.MaxHeight, opt.Price, opt.UnitPrice))
+	if isUniqueViolation(err) {
+		return nil, ErrOptionDefined
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return o, nil
+}
+
+func (s *pgProducts) DeleteCustomizationOption(ctx context.Context, id int32) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM customization_options WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type pgIdempotency struct {
+	db *sql.DB
+}
+
+func (s *pgIdempotency) Begin(ctx context.Context, key IdempotencyKey, expiresAt time.Time) (*IdempotencyKey, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND expires_at <= now()",
+		key.UserID, key.Key)
+	if err != nil {
+		return nil, false, err
+	}
+	res, err := tx.ExecContext(ctx, `
+        INSERT INTO idempotency_keys (user_id, idempotency_key, operation, fingerprint, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id, idempotency_key) DO NOTHING
+    `, key.UserID, key.Key, key.Operation, key.Fingerprint, expiresAt)
+	if err != nil {
+		return nil, false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	k := IdempotencyKey{UserID: key.UserID, Key: key.Key}
+	err = tx.QueryRowContext(ctx, `
+        SELECT operation, fingerprint, response, expires_at FROM idempotency_keys
+        WHERE user_id = $1 AND idempotency_key = $2
+    `, key.UserID, key.Key).Scan(&k.Operation, &k.Fingerprint, &k.Response, &k.ExpiresAt)
+	if err != nil {
+		return nil, false, err
+	}
+	return &k, n > 0, tx.Commit()
+}
+
+func (s *pgIdempotency) Complete(ctx context.Context, userID int32, key string, response []byte) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE idempotency_keys SET response = $3 WHERE user_id = $1 AND idempotency_key = $2",
+		userID, key, response)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *pgIdempotency) Release(ctx context.Context, userID int32, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2", userID, key)
+	return err
+}
+
+func (s *pgIdempotency) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < $1", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"go-backend/models"
+
+	"github.com/lib/pq"
+)
+
+type pgImages struct {
+	db *sql.DB
+}
+
+const imageColumns = "id, owner_id, storage_key, filename, mime_type, size, checksum, width, height, session_id, created_at"
+
+func scanImage(row scanner) (*models.Image, error) {
+	var img models.Image
+	var sessionID sql.NullString
+	err := row.Scan(&img.ID, &img.OwnerID, &img.Key, &img.Filename, &img.MimeType, &img.Size, &img.Checksum, &img.Width, &img.Height, &sessionID, &img.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if sessionID.Valid {
+		img.SessionID = &sessionID.String
+	}
+	return &img, nil
+}
+
+func (s *pgImages) Get(ctx context.Context, id int32) (*models.Image, error) {
+	img, err := scanImage(s.db.QueryRowContext(ctx, "SELECT "+imageColumns+" FROM images WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return img, nil
+}
+
+func (s *pgImages) ListByOwner(ctx context.Context, ownerID int32) ([]*models.Image, error) {
+	return s.list(ctx, "owner_id = $1", ownerID)
+}
+
+func (s *pgImages) ListBySession(ctx context.Context, sessionID string) ([]*models.Image, error) {
+	return s.list(ctx, "session_id = $1", sessionID)
+}
+
+func (s *pgImages) list(ctx context.Context, where string, arg any) ([]*models.Image, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+imageColumns+" FROM images WHERE "+where+" ORDER BY id", arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []*models.Image
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+func (s *pgImages) Create(ctx context.Context, img models.Image, renditions []models.ImageRendition) (*models.Image, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if img.SessionID != nil {
+		if err := claimUpload(ctx, tx, *img.SessionID); err != nil {
+			return nil, err
+		}
+	}
+	created, err := scanImage(tx.QueryRowContext(ctx, `
+        INSERT INTO images (owner_id, storage_key, filename, mime_type, size, checksum, width, height, session_id)
+        SELECT id, $2, $3, $4, $5, $6, $7, $8, $9 FROM users WHERE id = $1
+        RETURNING `+imageColumns,
+		img.OwnerID, img.Key, img.Filename, img.MimeType, img.Size, img.Checksum, img.Width, img.Height, img.SessionID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	for _, r := range renditions {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO image_renditions (image_id, size, format, storage_key, width, height, bytes)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+        `, created.ID, r.Size, r.Format, r.Key, r.Width, r.Height, r.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// claimUpload takes up one of an upload session's uploads, returning
+// ErrSessionClosed if it isn't ACTIVE. The row lock it takes keeps
+// concurrent uploads from going over the session's limit.
+func claimUpload(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	var status string
+	err := tx.QueryRowContext(ctx, "SELECT "+sessionStatus+" FROM upload_sessions WHERE id = $1 FOR UPDATE", sessionID).Scan(&status)
+	if err != nil {
+		return notFound(err)
+	}
+	if status != "ACTIVE" {
+		return ErrSessionClosed
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE upload_sessions SET upload_count = upload_count + 1 WHERE id = $1", sessionID)
+	return err
+}
+
+func (s *pgImages) RenditionsByImages(ctx context.Context, imageIDs []int32) (map[int32][]*models.ImageRendition, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT image_id, size, format, storage_key, width, height, bytes
+        FROM image_renditions
+        WHERE image_id = ANY($1)
+        ORDER BY id`, pq.Int32Array(imageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	renditions := make(map[int32][]*models.ImageRendition)
+	for rows.Next() {
+		var r models.ImageRendition
+		if err := rows.Scan(&r.ImageID, &r.Size, &r.Format, &r.Key, &r.Width, &r.Height, &r.Bytes); err != nil {
+			return nil, err
+		}
+		renditions[r.ImageID] = append(renditions[r.ImageID], &r)
+	}
+	return renditions, rows.Err()
+}
+
+func (s *pgImages) Delete(ctx context.Context, id int32) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM images WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type pgInventory struct {
+	db *sql.DB
+}
+
+const reservationColumns = "cart_id, product_id, COALESCE(variant_id, 0), quantity, expires_at"
+
+func scanReservation(row scanner) (*Reservation, error) {
+	var r Reservation
+	if err := row.Scan(&r.CartID, &r.ProductID, &r.VariantID, &r.Quantity, &r.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func queryReservations(ctx context.Context, q queryer, query string, args ...any) ([]*Reservation, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []*Reservation
+	for rows.Next() {
+		r, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, r)
+	}
+	return reservations, rows.Err()
+}
+
+// availableQuery computes what cart $3 may order of the products and
+// variants paired up in $1 and $2, a variant ID of 0 standing for the
+// product itself. Stock of a variant is kept on the variant.
+const availableQuery = `
+    SELECT k.product_id, k.variant_id, COALESCE(v.stock_quantity, p.stock_quantity) - COALESCE((
+        SELECT SUM(r.quantity) FROM stock_reservations r
+        WHERE r.product_id = k.product_id AND COALESCE(r.variant_id, 0) = k.variant_id
+          AND r.cart_id <> $3 AND r.expires_at > now()
+    ), 0)
+    FROM unnest($1::integer[], $2::integer[]) AS k (product_id, variant_id)
+    JOIN products p ON p.id = k.product_id
+    LEFT JOIN product_variants v ON v.id = k.variant_id AND v.product_id = k.product_id
+    WHERE k.variant_id = 0 OR v.id IS NOT NULL
+    ORDER BY k.product_id, k.variant_id`
+
+func queryAvailable(ctx context.Context, q queryer, query string, items []StockKey, cartID int32) (map[StockKey]int32, error) {
+	productIDs := make(pq.Int32Array, len(items))
+	variantIDs := make(pq.Int32Array, len(items))
+	for i, k := range items {
+		productIDs[i], variantIDs[i] = k.ProductID, k.VariantID
+	}
+	rows, err := q.QueryContext(ctx, query, productIDs, variantIDs, cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	available := make(map[StockKey]int32, len(items))
+	for rows.Next() {
+		var k StockKey
+		var n int32
+		if err := rows.Scan(&k.ProductID, &k.VariantID, &n); err != nil {
+			return nil, err
+		}
+		available[k] = n
+	}
+	return available, rows.Err()
+}
+
+// lockStock locks the wanted products' rows for the rest of the transaction
+// and checks that cartID may take the wanted quantities of them. A variant
+// is covered by locking its product, so orders of the same product's
+// variants are serialised too.
+func lockStock(ctx context.Context, tx *sql.Tx, wanted map[StockKey]int32, cartID int32) error {
+	available, err := queryAvailable(ctx, tx, availableQuery+" FOR UPDATE OF p", sortedKeys(wanted), cartID)
+	if err != nil {
+		return err
+	}
+	return checkStock(wanted, available)
+}
+
+// lockProducts takes the row locks lockStock takes on the products whose
+// IDs the ids subquery selects. Every other write to the stock of a
+// product or its variants takes them first, so it waits for orders being
+// placed and they wait for it. Rows are locked in ID order, as lockStock
+// locks them, so the two can't deadlock.
+func lockProducts(ctx context.Context, tx *sql.Tx, ids string, args ...any) error {
+	_, err := tx.ExecContext(ctx, "SELECT id FROM products WHERE id IN ("+ids+") ORDER BY id FOR UPDATE", args...)
+	return err
+}
+
+func (s *pgInventory) Reserve(ctx context.Context, cartID int32, expiresAt time.Time) ([]*Reservation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT product_id, COALESCE(variant_id, 0), sum(quantity) FROM cart_items WHERE cart_id = $1
+        GROUP BY product_id, COALESCE(variant_id, 0)`, cartID)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[StockKey]int32)
+	for rows.Next() {
+		var k StockKey
+		var quantity int32
+		if err := rows.Scan(&k.ProductID, &k.VariantID, &quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		wanted[k] = quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := lockStock(ctx, tx, wanted, cartID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM stock_reservations WHERE cart_id = $1", cartID); err != nil {
+		return nil, err
+	}
+	reservations, err := queryReservations(ctx, tx, `
+        INSERT INTO stock_reservations (cart_id, product_id, variant_id, quantity, expires_at)
+        SELECT cart_id, product_id, variant_id, sum(quantity), $2 FROM cart_items WHERE cart_id = $1
+        GROUP BY cart_id, product_id, variant_id
+        RETURNING `+reservationColumns,
+		cartID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return reservations, tx.Commit()
+}
+
+func (s *pgInventory) Reservations(ctx context.Context, cartID int32) ([]*Reservation, error) {
+	return queryReservations(ctx, s.db,
+		"SELECT "+reservationColumns+" FROM stock_reservations WHERE cart_id = $1 AND expires_at > now() ORDER BY product_id, variant_id NULLS FIRST", cartID)
+}
+
+func (s *pgInventory) Release(ctx context.Context, cartID int32) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM stock_reservations WHERE cart_id = $1", cartID)
+	return err
+}
+
+func (s *pgInventory) Available(ctx context.Context, items []StockKey, cartID int32) (map[StockKey]int32, error) {
+	return queryAvailable(ctx, s.db, availableQuery, items, cartID)
+}
+
+func (s *pgInventory) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM stock_reservations WHERE expires_at < $1", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
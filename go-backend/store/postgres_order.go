@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-backend/models"
+)
+
+type pgOrders struct {
+	db *sql.DB
+}
+
+const orderColumns = "id, user_id, subtotal, discount_total, tax_total, shipping_total, total_amount, status, created_at"
+
+func scanOrder(row scanner) (*models.Order, error) {
+	var o models.Order
+	var userID sql.NullInt32
+	if err := row.Scan(&o.ID, &userID, &o.Subtotal, &o.DiscountTotal, &o.TaxTotal, &o.ShippingTotal, &o.TotalAmount, &o.Status, &o.CreatedAt); err != nil {
+		return nil, err
+	}
+	o.UserID = userID.Int32
+	return &o, nil
+}
+
+func queryOrders(ctx context.Context, q queryer, query string, args ...any) ([]*models.Order, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*models.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (s *pgOrders) Get(ctx context.Context, id int32) (*models.Order, error) {
+	o, err := scanOrder(s.db.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return o, nil
+}
+
+func (s *pgOrders) List(ctx context.Context) ([]*models.Order, error) {
+	return queryOrders(ctx, s.db, "SELECT "+orderColumns+" FROM orders ORDER BY id")
+}
+
+func (s *pgOrders) ListByUser(ctx context.Context, userID int32) ([]*models.Order, error) {
+	return queryOrders(ctx, s.db, "SELECT "+orderColumns+" FROM orders WHERE user_id = $1 ORDER BY id", userID)
+}
+
+// orderKeyset lists orders newest first.
+var orderKeyset = keyset{sortExpr: "created_at", cast: "timestamp", idExpr: "id", desc: true}
+
+func (s *pgOrders) PageByUser(ctx context.Context, userID int32, page PageArgs) (*Page[models.Order], error) {
+	return pgPage(ctx, s.db, orderColumns, "FROM orders WHERE user_id = $1", []any{userID}, orderKeyset, page, scanOrder,
+		func(o *models.Order) int32 { return o.ID })
+}
+
+func (s *pgOrders) Items(ctx context.Context, orderID int32) ([]*models.OrderItem, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, order_id, product_id, variant_id, sku, customizations, quantity, price_at_time, discount, tax, line_total FROM order_items WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.OrderItem
+	for rows.Next() {
+		var item models.OrderItem
+		var variantID sql.NullInt32
+		var sku sql.NullString
+		var customizations []byte
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &variantID, &sku, &customizations, &item.Quantity, &item.PriceAtTime, &item.Discount, &item.Tax, &item.LineTotal); err != nil {
+			return nil, err
+		}
+		if variantID.Valid {
+			item.VariantID = &variantID.Int32
+		}
+		if sku.Valid {
+			item.SKU = &sku.String
+		}
+		if err := json.Unmarshal(customizations, &item.Customizations); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+func (s *pgOrders) Create(ctx context.Context, userID int32, order NewOrder) (*models.Order, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	wanted := orderQuantities(order.Items)
+	if err := lockStock(ctx, tx, wanted, order.CartID); err != nil {
+		return nil, err
+	}
+	for _, k := range sortedKeys(wanted) {
+		var err error
+		if k.VariantID != 0 {
+			_, err = tx.ExecContext(ctx, "UPDATE product_variants SET stock_quantity = stock_quantity - $2 WHERE id = $1", k.VariantID, wanted[k])
+		} else {
+			_, err = tx.ExecContext(ctx, "UPDATE products SET stock_quantity = stock_quantity - $2 WHERE id = $1", k.ProductID, wanted[k])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var orderID int32
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO orders (user_id, subtotal, discount_total, tax_total, shipping_total, total_amount, status)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `, userID, order.Subtotal, order.DiscountTotal, order.TaxTotal, order.ShippingTotal, order.Total, "PENDING").Scan(&orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range order.Items {
+		customizations, err := marshalCustomizations(item.Customizations)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO order_items (order_id, product_id, variant_id, sku, customizations, quantity, price_at_time, discount, tax, line_total)
+            VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10)
+        `, orderID, item.ProductID, StockKey{item.ProductID, item.VariantID}.nullableVariant(), item.SKU, customizations,
+			item.Quantity, item.UnitPrice, item.Discount, item.Tax, item.LineTotal)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordStatus(ctx, tx, orderID, "", "PENDING", userID, ""); err != nil {
+		return nil, err
+	}
+	if order.CartID != 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM stock_reservations WHERE cart_id = $1", order.CartID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, orderID)
+}
+
+func (s *pgOrders) PendingBefore(ctx context.Context, t time.Time) ([]*models.Order, error) {
+	return queryOrders(ctx, s.db, "SELECT "+orderColumns+" FROM orders WHERE status = 'PENDING' AND created_at < $1 ORDER BY id", t)
+}
+
+func (s *pgOrders) SetStatus(ctx context.Context, id int32, change StatusChange) (*models.Order, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if change.Restock {
+		if err := lockProducts(ctx, tx, "SELECT product_id FROM order_items WHERE order_id = $1", id); err != nil {
+			return nil, err
+		}
+	}
+	o, err := scanOrder(tx.QueryRowContext(ctx,
+		"UPDATE orders SET status = $3 WHERE id = $1 AND status = $2 RETURNING "+orderColumns, id, change.From, change.To))
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)", id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrStatusChanged
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordStatus(ctx, tx, id, change.From, change.To, change.ActorID, change.Note); err != nil {
+		return nil, err
+	}
+	if change.Restock {
+		_, err = tx.ExecContext(ctx, `
+            UPDATE products p SET stock_quantity = p.stock_quantity + i.quantity
+            FROM (
+                SELECT product_id, SUM(quantity) AS quantity FROM order_items
+                WHERE order_id = $1 AND variant_id IS NULL GROUP BY product_id
+            ) i
+            WHERE p.id = i.product_id
+        `, id)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `
+            UPDATE product_variants v SET stock_quantity = v.stock_quantity + i.quantity
+            FROM (
+                SELECT variant_id, SUM(quantity) AS quantity FROM order_items
+                WHERE order_id = $1 AND variant_id IS NOT NULL GROUP BY variant_id
+            ) i
+            WHERE v.id = i.variant_id
+        `, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return o, tx.Commit()
+}
+
+// recordStatus appends a status change to an order's history. An empty from
+// marks the order's creation, a zero actorID the system.
+func recordStatus(ctx context.Context, q queryer, orderID int32, from, to string, actorID int32, note string) error {
+	_, err := q.ExecContext(ctx, `
+        INSERT INTO order_status_history (order_id, from_status, to_status, actor_id, note)
+        VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, 0), NULLIF($5, ''))
+    `, orderID, from, to, actorID, note)
+	return err
+}
+
+func (s *pgOrders) StatusHistory(ctx context.Context, orderID int32) ([]*models.OrderStatusChange, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, order_id, from_status, to_status, actor_id, note, created_at
+        FROM order_status_history WHERE order_id = $1 ORDER BY created_at, id
+    `, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*models.OrderStatusChange
+	for rows.Next() {
+		var c models.OrderStatusChange
+		var from, note sql.NullString
+		var actorID sql.NullInt32
+		if err := rows.Scan(&c.ID, &c.OrderID, &from, &c.ToStatus, &actorID, &note, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		if from.Valid {
+			c.FromStatus = &from.String
+		}
+		if actorID.Valid {
+			c.ActorID = &actorID.Int32
+		}
+		if note.Valid {
+			c.Note = &note.String
+		}
+		history = append(history, &c)
+	}
+	return history, rows.Err()
+}
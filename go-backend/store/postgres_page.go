@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// keyset describes the ordering of a paginated query: an optional sort
+// expression, with the row ID as a tie-breaker so the order is total.
+type keyset struct {
+	name     string // recorded in cursors, see Cursor.Sort
+	sortExpr string // e.g. "created_at"; empty orders by ID alone
+	cast     string // SQL type the cursor key is cast back to, e.g. "timestamp"
+	idExpr   string // e.g. "id" or "p.id"
+	desc     bool
+}
+
+func (k keyset) orderBy(reverse bool) string {
+	dir := "ASC"
+	if k.desc != reverse {
+		dir = "DESC"
+	}
+	if k.sortExpr == "" {
+		return fmt.Sprintf(" ORDER BY %s %s", k.idExpr, dir)
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, %s %s", k.sortExpr, dir, k.idExpr, dir)
+}
+
+// bound returns the condition selecting rows strictly after (or before) c in
+// the keyset's natural order, appending its parameters to args.
+func (k keyset) bound(c *Cursor, after bool, args []any) (string, []any) {
+	op := ">"
+	if k.desc == after {
+		op = "<"
+	}
+	if k.sortExpr == "" {
+		args = append(args, c.ID)
+		return fmt.Sprintf(" AND %s %s $%d", k.idExpr, op, len(args)), args
+	}
+	args = append(args, c.Key, c.ID)
+	return fmt.Sprintf(" AND (%s, %s) %s ($%d::%s, $%d)", k.sortExpr, k.idExpr, op, len(args)-1, k.cast, len(args)), args
+}
+
+// extraScanner appends destinations for trailing columns to every Scan
+// call, so row scanners can be reused on queries selecting extra columns.
+type extraScanner struct {
+	scanner
+	extra []any
+}
+
+func (s extraScanner) Scan(dest ...any) error {
+	return s.scanner.Scan(append(dest, s.extra...)...)
+}
+
+// pgPage runs a keyset-paginated query. from is the FROM clause including a
+// WHERE clause that further conditions can be ANDed onto; args are its
+// parameters. scan reads the selected columns into a T and id returns its
+// row ID.
+func pgPage[T any](ctx context.Context, q queryer, columns, from string, args []any, ks keyset, page PageArgs, scan func(scanner) (*T, error), id func(*T) int32) (*Page[T], error) {
+	limit, err := page.limit()
+	if err != nil {
+		return nil, err
+	}
+	if err := page.check(ks.name); err != nil {
+		return nil, err
+	}
+
+	var total int
+	if err := q.QueryRowContext(ctx, "SELECT COUNT(*) "+from, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	keyExpr := "NULL"
+	if ks.sortExpr != "" {
+		keyExpr = ks.sortExpr + "::text"
+	}
+	query := "SELECT " + columns + ", " + keyExpr + " " + from
+	pageArgs := append([]any(nil), args...)
+	var cond string
+	if page.After != nil {
+		cond, pageArgs = ks.bound(page.After, true, pageArgs)
+		query += cond
+	}
+	if page.Before != nil {
+		cond, pageArgs = ks.bound(page.Before, false, pageArgs)
+		query += cond
+	}
+	query += ks.orderBy(page.backward())
+	pageArgs = append(pageArgs, limit+1)
+	query += fmt.Sprintf(" LIMIT $%d", len(pageArgs))
+
+	rows, err := q.QueryContext(ctx, query, pageArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []Edge[T]
+	for rows.Next() {
+		var key sql.NullString
+		node, err := scan(extraScanner{rows, []any{&key}})
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, Edge[T]{Node: node, Cursor: Cursor{Sort: ks.name, Key: key.String, ID: id(node)}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return finishPage(edges, total, limit, page), nil
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go-backend/models"
+	"go-backend/pricing"
+)
+
+type pgPayments struct {
+	db *sql.DB
+}
+
+const paymentColumns = "id, order_id, provider, reference, amount, amount_refunded, status, failure_reason, created_at, updated_at"
+
+func scanPayment(row scanner) (*models.Payment, error) {
+	var p models.Payment
+	var reference, failureReason sql.NullString
+	err := row.Scan(&p.ID, &p.OrderID, &p.Provider, &reference, &p.Amount, &p.AmountRefunded, &p.Status, &failureReason, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if reference.Valid {
+		p.Reference = &reference.String
+	}
+	if failureReason.Valid {
+		p.FailureReason = &failureReason.String
+	}
+	return &p, nil
+}
+
+func (s *pgPayments) Get(ctx context.Context, id int32) (*models.Payment, error) {
+	p, err := scanPayment(s.db.QueryRowContext(ctx, "SELECT "+paymentColumns+" FROM payment_intents WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return p, nil
+}
+
+func (s *pgPayments) GetByReference(ctx context.Context, provider, reference string) (*models.Payment, error) {
+	p, err := scanPayment(s.db.QueryRowContext(ctx,
+		"SELECT "+paymentColumns+" FROM payment_intents WHERE provider = $1 AND reference = $2", provider, reference))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return p, nil
+}
+
+func (s *pgPayments) ListByOrder(ctx context.Context, orderID int32) ([]*models.Payment, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+paymentColumns+" FROM payment_intents WHERE order_id = $1 ORDER BY id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*models.Payment
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+func (s *pgPayments) Create(ctx context.Context, orderID int32, provider string, amount pricing.Cents) (*models.Payment, error) {
+	return scanPayment(s.db.QueryRowContext(ctx, `
+        INSERT INTO payment_intents (order_id, provider, amount, status)
+        VALUES ($1, $2, $3::numeric, 'PENDING')
+        RETURNING `+paymentColumns,
+		orderID, provider, amount.String()))
+}
+
+func (s *pgPayments) ReserveRefund(ctx context.Context, id int32, amount pricing.Cents) (*models.Payment, error) {
+	// The row lock taken by the UPDATE makes a concurrent reservation wait
+	// and then check the amount left after this one.
+	p, err := scanPayment(s.db.QueryRowContext(ctx, `
+        UPDATE payment_intents SET
+            amount_refunded = amount_refunded + $2::numeric,
+            updated_at = now()
+        WHERE id = $1 AND status = 'CAPTURED' AND amount_refunded + $2::numeric <= amount
+        RETURNING `+paymentColumns,
+		id, amount.String()))
+	if !errors.Is(err, sql.ErrNoRows) {
+		return p, err
+	}
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return nil, ErrRefundExceeded
+}
+
+func (s *pgPayments) Update(ctx context.Context, id int32, update PaymentUpdate) (*models.Payment, error) {
+	p, err := scanPayment(s.db.QueryRowContext(ctx, `
+        UPDATE payment_intents SET
+            status = COALESCE(NULLIF($2, ''), status),
+            reference = COALESCE(NULLIF($3, ''), reference),
+            failure_reason = COALESCE(NULLIF($4, ''), failure_reason),
+            amount_refunded = amount_refunded + $5::numeric,
+            updated_at = now()
+        WHERE id = $1
+        RETURNING `+paymentColumns,
+		id, update.Status, update.Reference, update.FailureReason, update.Refunded.String()))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return p, nil
+}
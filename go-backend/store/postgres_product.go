@@ -0,0 +1,455 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"go-backend/models"
+
+	"github.com/lib/pq"
+)
+
+type pgProducts struct {
+	db *sql.DB
+}
+
+const (
+	productColumns = "id, name, description, price, stock_quantity, category_id, created_at"
+	// productColumnsP is productColumns qualified with the alias p.
+	productColumnsP = "p.id, p.name, p.description, p.price, p.stock_quantity, p.category_id, p.created_at"
+)
+
+func scanProduct(row scanner) (*models.Product, error) {
+	var p models.Product
+	var categoryID sql.NullInt32
+	var createdAt sql.NullString
+	if err := row.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.StockQuantity, &categoryID, &createdAt); err != nil {
+		return nil, err
+	}
+	p.CategoryID = categoryID.Int32
+	p.CreatedAt = createdAt.String
+	return &p, nil
+}
+
+func queryProducts(ctx context.Context, q queryer, query string, args ...any) ([]*models.Product, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*models.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func (s *pgProducts) Get(ctx context.Context, id int32) (*models.Product, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+productColumns+" FROM products WHERE id = $1", id)
+	p, err := scanProduct(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return p, nil
+}
+
+func (s *pgProducts) GetMany(ctx context.Context, ids []int32) (map[int32]*models.Product, error) {
+	products, err := queryProducts(ctx, s.db, "SELECT "+productColumns+" FROM products WHERE id = ANY($1)", pq.Int32Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int32]*models.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	return byID, nil
+}
+
+// productRatings joins each product's average review rating as
+// ratings.avg_rating.
+const productRatings = ` LEFT JOIN (SELECT product_id, AVG(rating) AS avg_rating FROM reviews GROUP BY product_id) ratings ON ratings.product_id = p.id`
+
+// productHasVariants is true for the products aliased p that are bought
+// through their variants.
+const productHasVariants = `EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id)`
+
+// productLowestPrice is the lowest price the product aliased p can be
+// bought at: that of its cheapest variant once it has any.
+const productLowestPrice = `COALESCE((SELECT MIN(v.price) FROM product_variants v WHERE v.product_id = p.id), p.price)`
+
+// productKeysets maps each sort order to its keyset.
+var productKeysets = map[ProductSort]keyset{
+	SortDefault:   {idExpr: "p.id"},
+	SortPriceAsc:  {name: string(SortPriceAsc), sortExpr: productLowestPrice, cast: "numeric", idExpr: "p.id"},
+	SortPriceDesc: {name: string(SortPriceDesc), sortExpr: productLowestPrice, cast: "numeric", idExpr: "p.id", desc: true},
+	SortNewest:    {name: string(SortNewest), sortExpr: "COALESCE(p.created_at, 'epoch'::timestamp)", cast: "timestamp", idExpr: "p.id", desc: true},
+	SortRating:    {name: string(SortRating), sortExpr: "COALESCE(ratings.avg_rating, 0)", cast: "numeric", idExpr: "p.id", desc: true},
+	SortName:      {name: string(SortName), sortExpr: "p.name", cast: "text", idExpr: "p.id"},
+}
+
+// productQuery compiles filter into a FROM/WHERE clause over products
+// aliased as p, with its parameters and the keyset for the requested sort.
+func productQuery(filter ProductFilter) (string, []any, keyset, error) {
+	ks, ok := productKeysets[filter.Sort]
+	if !ok {
+		return "", nil, ks, fmt.Errorf("unknown sort order %q", filter.Sort)
+	}
+
+	from := "FROM products p"
+	if filter.MinRating != nil || filter.Sort == SortRating {
+		from += productRatings
+	}
+	from += " WHERE 1=1"
+
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.CategoryID != nil {
+		if filter.IncludeSubcategories {
+			from += ` AND p.category_id IN (
+                WITH RECURSIVE subtree AS (
+                    SELECT id FROM categories WHERE id = ` + arg(*filter.CategoryID) + `
+                    UNION
+                    SELECT c.id FROM categories c JOIN subtree ON c.parent_id = subtree.id
+                )
+                SELECT id FROM subtree)`
+		} else {
+			from += " AND p.category_id = " + arg(*filter.CategoryID)
+		}
+	}
+	if filter.Search != nil {
+		// Full-text match over name, description and attributes, keeping
+		// plain substring matches on the name for partial words.
+		from += " AND (p.search_vector @@ websearch_to_tsquery('english', " + arg(*filter.Search) + ")" +
+			" OR p.name ILIKE " + arg("%"+escapeLike(*filter.Search)+"%") + ` ESCAPE '\')`
+	}
+	// Prices and stock are those of the variants once a product has any,
+	// as only they can be bought; one of them has to match.
+	var offer []string
+	if filter.MinPrice != nil {
+		offer = append(offer, "price >= "+arg(*filter.MinPrice))
+	}
+	if filter.MaxPrice != nil {
+		offer = append(offer, "price <= "+arg(*filter.MaxPrice))
+	}
+	if filter.InStock {
+		offer = append(offer, "stock_quantity > 0")
+	}
+	if len(offer) > 0 {
+		from += " AND CASE WHEN " + productHasVariants +
+			" THEN EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v." + strings.Join(offer, " AND v.") + ")" +
+			" ELSE p." + strings.Join(offer, " AND p.") + " END"
+	}
+	for _, attr := range filter.Attributes {
+		from += ` AND EXISTS (SELECT 1 FROM product_attributes a WHERE a.product_id = p.id` +
+			` AND lower(a.attribute_name) = lower(` + arg(attr.Name) + `)` +
+			` AND lower(a.attribute_value) = lower(` + arg(attr.Value) + `))`
+	}
+	if filter.MinRating != nil {
+		from += " AND COALESCE(ratings.avg_rating, 0) >= " + arg(*filter.MinRating)
+	}
+	return from, args, ks, nil
+}
+
+func (s *pgProducts) List(ctx context.Context, filter ProductFilter) ([]*models.Product, error) {
+	from, args, ks, err := productQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	return queryProducts(ctx, s.db, "SELECT "+productColumnsP+" "+from+ks.orderBy(false), args...)
+}
+
+func (s *pgProducts) Page(ctx context.Context, filter ProductFilter, page PageArgs) (*Page[models.Product], error) {
+	from, args, ks, err := productQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	return pgPage(ctx, s.db, productColumnsP, from, args, ks, page, scanProduct, productID)
+}
+
+func productID(p *models.Product) int32 { return p.ID }
+
+func (s *pgProducts) Create(ctx context.Context, input models.ProductInput) (*models.Product, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var categoryExists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1)", input.CategoryID).Scan(&categoryExists)
+	if err != nil {
+		return nil, err
+	}
+	if !categoryExists {
+		return nil, fmt.Errorf("category with ID %d does not exist", input.CategoryID)
+	}
+
+	var productID int32
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO products (name, description, price, stock_quantity, category_id)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `, input.Name, input.Description, input.Price, input.StockQuantity, input.CategoryID).Scan(&productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertProductImages(ctx, tx, productID, nil, input.Images); err != nil {
+		return nil, err
+	}
+	if input.Attributes != nil {
+		if err := insertProductAttributes(ctx, tx, productID, *input.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, productID)
+}
+
+func (s *pgProducts) Update(ctx context.Context, id int32, input models.ProductInput) (*models.Product, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockProducts(ctx, tx, "$1", id); err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, `
+        UPDATE products
+        SET name = $1, description = $2, price = $3, stock_quantity = $4, category_id = $5, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $6
+    `, input.Name, input.Description, input.Price, input.StockQuantity, input.CategoryID, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrNotFound
+	}
+
+	// The image list in the input replaces the existing one.
+	if _, err = tx.ExecContext(ctx, "DELETE FROM product_images WHERE product_id = $1 AND variant_id IS NULL", id); err != nil {
+		return nil, err
+	}
+	if err := insertProductImages(ctx, tx, id, nil, input.Images); err != nil {
+		return nil, err
+	}
+	if input.Attributes != nil {
+		if _, err = tx.ExecContext(ctx, "DELETE FROM product_attributes WHERE product_id = $1", id); err != nil {
+			return nil, err
+		}
+		if err := insertProductAttributes(ctx, tx, id, *input.Attributes); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+// insertProductImages adds image rows for a product, or for one of its
+// variants when variantID is set.
+func insertProductImages(ctx context.Context, tx *sql.Tx, productID int32, variantID *int32, images []models.ProductImageInput) error {
+	for _, img := range images {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO product_images (product_id, variant_id, image_id, image_url, is_primary)
+            VALUES ($1, $2, $3, $4, $5)
+        `, productID, variantID, img.ImageID, img.ImageUrl, img.IsPrimary)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertProductAttributes(ctx context.Context, tx *sql.Tx, productID int32, attributes []models.ProductAttributeInput) error {
+	for _, a := range attributes {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO product_attributes (product_id, attribute_name, attribute_value)
+            VALUES ($1, $2, $3)
+        `, productID, a.Name, a.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *pgProducts) Delete(ctx context.Context, id int32) (bool, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM products WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (s *pgProducts) Images(ctx context.Context, productID int32) ([]*models.ProductImage, error) {
+	images, err := s.ImagesByProducts(ctx, []int32{productID})
+	if err != nil {
+		return nil, err
+	}
+	return images[productID], nil
+}
+
+func (s *pgProducts) ImagesByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductImage, error) {
+	return queryImages(ctx, s.db, "WHERE product_id = ANY($1) AND variant_id IS NULL", pq.Int32Array(productIDs),
+		func(img *models.ProductImage) int32 { return img.ProductID })
+}
+
+// queryImages selects the images matching where, grouped by the key
+// returned by key.
+func queryImages(ctx context.Context, q queryer, where string, arg any, key func(*models.ProductImage) int32) (map[int32][]*models.ProductImage, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, product_id, variant_id, image_id, image_url, is_primary FROM product_images "+where+" ORDER BY id", arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := make(map[int32][]*models.ProductImage)
+	for rows.Next() {
+		var img models.ProductImage
+		var variantID, imageID sql.NullInt32
+		if err := rows.Scan(&img.ID, &img.ProductID, &variantID, &imageID, &img.ImageUrl, &img.IsPrimary); err != nil {
+			return nil, err
+		}
+		if variantID.Valid {
+			img.VariantID = &variantID.Int32
+		}
+		if imageID.Valid {
+			img.ImageID = &imageID.Int32
+		}
+		images[key(&img)] = append(images[key(&img)], &img)
+	}
+	return images, rows.Err()
+}
+
+func (s *pgProducts) AddImage(ctx context.Context, productID int32, input models.ProductImageInput) (*models.ProductImage, error) {
+	img := models.ProductImage{
+		ProductID: productID,
+		ImageID:   input.ImageID,
+		ImageUrl:  *input.ImageUrl,
+		IsPrimary: input.IsPrimary,
+	}
+	err := s.db.QueryRowContext(ctx, `
+        INSERT INTO product_images (product_id, image_id, image_url, is_primary)
+        SELECT id, $2, $3, $4 FROM products WHERE id = $1
+        RETURNING id
+    `, productID, input.ImageID, input.ImageUrl, input.IsPrimary).Scan(&img.ID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &img, nil
+}
+
+func (s *pgProducts) Attributes(ctx context.Context, productID int32) ([]*models.ProductAttribute, error) {
+	attributes, err := s.AttributesByProducts(ctx, []int32{productID})
+	if err != nil {
+		return nil, err
+	}
+	return attributes[productID], nil
+}
+
+func (s *pgProducts) AttributesByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductAttribute, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+attributeColumns+" FROM product_attributes WHERE product_id = ANY($1) ORDER BY id", pq.Int32Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attributes := make(map[int32][]*models.ProductAttribute)
+	for rows.Next() {
+		attr, err := scanAttribute(rows)
+		if err != nil {
+			return nil, err
+		}
+		attributes[attr.ProductID] = append(attributes[attr.ProductID], attr)
+	}
+	return attributes, rows.Err()
+}
+
+const attributeColumns = "id, product_id, attribute_name, attribute_value"
+
+func scanAttribute(row scanner) (*models.ProductAttribute, error) {
+	var a models.ProductAttribute
+	if err := row.Scan(&a.ID, &a.ProductID, &a.Name, &a.Value); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *pgProducts) GetAttribute(ctx context.Context, id int32) (*models.ProductAttribute, error) {
+	a, err := scanAttribute(s.db.QueryRowContext(ctx, "SELECT "+attributeColumns+" FROM product_attributes WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return a, nil
+}
+
+func (s *pgProducts) AddAttribute(ctx context.Context, productID int32, input models.ProductAttributeInput) (*models.ProductAttribute, error) {
+	// Inserting through a SELECT on products turns a missing product into
+	// no row rather than a foreign key error.
+	a, err := scanAttribute(s.db.QueryRowContext(ctx, `
+        INSERT INTO product_attributes (product_id, attribute_name, attribute_value)
+        SELECT id, $2, $3 FROM products WHERE id = $1
+        RETURNING `+attributeColumns,
+		productID, input.Name, input.Value))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return a, nil
+}
+
+func (s *pgProducts) UpdateAttribute(ctx context.Context, id int32, input models.ProductAttributeInput) (*models.ProductAttribute, error) {
+	a, err := scanAttribute(s.db.QueryRowContext(ctx, `
+        UPDATE product_attributes SET attribute_name = $2, attribute_value = $3
+        WHERE id = $1
+        RETURNING `+attributeColumns,
+		id, input.Name, input.Value))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return a, nil
+}
+
+func (s *pgProducts) DeleteAttribute(ctx context.Context, id int32) (bool, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM product_attributes WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, for matching it literally
+// with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
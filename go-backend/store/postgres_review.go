@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"go-backend/models"
+
+	"github.com/lib/pq"
+)
+
+type pgReviews struct {
+	db *sql.DB
+}
+
+const reviewColumns = "id, product_id, user_id, rating, comment, created_at"
+
+func scanReview(row scanner) (*models.Review, error) {
+	var rev models.Review
+	var productID, userID sql.NullInt32
+	var comment sql.NullString
+	if err := row.Scan(&rev.ID, &productID, &userID, &rev.Rating, &comment, &rev.CreatedAt); err != nil {
+		return nil, err
+	}
+	rev.ProductID = productID.Int32
+	rev.UserID = userID.Int32
+	rev.Comment = comment.String
+	return &rev, nil
+}
+
+func (s *pgReviews) ListByProduct(ctx context.Context, productID int32) ([]*models.Review, error) {
+	reviews, err := s.ListByProducts(ctx, []int32{productID})
+	if err != nil {
+		return nil, err
+	}
+	return reviews[productID], nil
+}
+
+func (s *pgReviews) ListByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.Review, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+reviewColumns+" FROM reviews WHERE product_id = ANY($1) ORDER BY id", pq.Int32Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make(map[int32][]*models.Review)
+	for rows.Next() {
+		rev, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews[rev.ProductID] = append(reviews[rev.ProductID], rev)
+	}
+	return reviews, rows.Err()
+}
+
+// reviewKeyset lists reviews newest first.
+var reviewKeyset = keyset{sortExpr: "created_at", cast: "timestamp", idExpr: "id", desc: true}
+
+func (s *pgReviews) PageByProduct(ctx context.Context, productID int32, page PageArgs) (*Page[models.Review], error) {
+	return pgPage(ctx, s.db, reviewColumns, "FROM reviews WHERE product_id = $1", []any{productID}, reviewKeyset, page, scanReview,
+		func(rev *models.Review) int32 { return rev.ID })
+}
+
+func (s *pgReviews) Create(ctx context.Context, userID int32, input models.ReviewInput) (*models.Review, error) {
+	row := s.db.QueryRowContext(ctx, `
+        INSERT INTO reviews (product_id, user_id, rating, comment)
+        VALUES ($1, $2, $3, $4)
+        RETURNING `+reviewColumns,
+		input.ProductID, userID, input.Rating, input.Comment)
+	return scanReview(row)
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+
+	"go-backend/models"
+)
+
+const (
+	searchSort = "search"
+	fuzzySort  = "fuzzy"
+
+	// fuzzyThreshold is the minimum pg_trgm word similarity between the
+	// query and a product name for a fuzzy hit. It is set as
+	// pg_trgm.word_similarity_threshold so the <% operator, which can use
+	// products_name_trgm_idx, applies it.
+	fuzzyThreshold = "0.3"
+
+	headlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=25, MinWords=8, MaxFragments=2"
+)
+
+func (s *pgProducts) Search(ctx context.Context, query string, page PageArgs) (*Page[models.SearchHit], error) {
+	// A cursor pins the mode the first page was served in.
+	var fuzzy bool
+	if c := page.After; c != nil {
+		fuzzy = c.Sort == fuzzySort
+	} else if c := page.Before; c != nil {
+		fuzzy = c.Sort == fuzzySort
+	} else {
+		var matches bool
+		err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM products WHERE search_vector @@ websearch_to_tsquery('english', $1))", query).Scan(&matches)
+		if err != nil {
+			return nil, err
+		}
+		fuzzy = !matches
+	}
+
+	var q queryer = s.db
+	var columns, from string
+	var ks keyset
+	if fuzzy {
+		tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(ctx, "SET LOCAL pg_trgm.word_similarity_threshold = "+fuzzyThreshold); err != nil {
+			return nil, err
+		}
+		q = tx
+
+		score := "word_similarity($1, p.name)"
+		columns = productColumnsP + ", " + score + ", left(coalesce(p.description, p.name), 200)"
+		from = "FROM products p WHERE $1 <% p.name"
+		ks = keyset{name: fuzzySort, sortExpr: score, cast: "real", idExpr: "p.id", desc: true}
+	} else {
+		tsquery := "websearch_to_tsquery('english', $1)"
+		score := "ts_rank_cd(p.search_vector, " + tsquery + ")"
+		columns = productColumnsP + ", " + score + ", ts_headline('english', coalesce(p.description, p.name), " + tsquery + ", '" + headlineOptions + "')"
+		from = "FROM products p WHERE p.search_vector @@ " + tsquery
+		ks = keyset{name: searchSort, sortExpr: score, cast: "real", idExpr: "p.id", desc: true}
+	}
+
+	scan := func(row scanner) (*models.SearchHit, error) {
+		hit := models.SearchHit{Fuzzy: fuzzy}
+		p, err := scanProduct(extraScanner{row, []any{&hit.Score, &hit.Snippet}})
+		if err != nil {
+			return nil, err
+		}
+		hit.Product = *p
+		if fuzzy {
+			hit.Snippet = html.EscapeString(hit.Snippet)
+		} else {
+			hit.Snippet = escapeHeadline(hit.Snippet)
+		}
+		return &hit, nil
+	}
+	return pgPage(ctx, q, columns, from, []any{query}, ks, page, scan,
+		func(hit *models.SearchHit) int32 { return hit.Product.ID })
+}
+
+// escapeHeadline HTML-escapes a ts_headline excerpt, which is product text
+// as it was entered, then restores the <b></b> around the matches.
+func escapeHeadline(headline string) string {
+	return strings.NewReplacer("&lt;b&gt;", "<b>", "&lt;/b&gt;", "</b>").Replace(html.EscapeString(headline))
+}
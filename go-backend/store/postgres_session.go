@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go-backend/models"
+)
+
+type pgSessions struct {
+	db *sql.DB
+}
+
+// sessionStatus works out a session's status; a session that is both
+// revoked and full counts as revoked, one that is full and expired as
+// completed.
+const sessionStatus = `CASE
+            WHEN revoked_at IS NOT NULL THEN 'REVOKED'
+            WHEN upload_count >= max_uploads THEN 'COMPLETED'
+            WHEN expires_at <= now() THEN 'EXPIRED'
+            ELSE 'ACTIVE'
+        END`
+
+const sessionColumns = "id, user_id, product_id, max_uploads, upload_count, " + sessionStatus + ", expires_at, revoked_at, created_at"
+
+func scanSession(row scanner) (*models.UploadSession, error) {
+	var s models.UploadSession
+	var productID sql.NullInt32
+	var revokedAt sql.NullString
+	err := row.Scan(&s.ID, &s.UserID, &productID, &s.MaxUploads, &s.UploadCount, &s.Status, &s.ExpiresAt, &revokedAt, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if productID.Valid {
+		s.ProductID = &productID.Int32
+	}
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.String
+	}
+	return &s, nil
+}
+
+func (s *pgSessions) Create(ctx context.Context, session NewUploadSession) (*models.UploadSession, error) {
+	created, err := scanSession(s.db.QueryRowContext(ctx, `
+        INSERT INTO upload_sessions (id, user_id, product_id, max_uploads, expires_at)
+        SELECT $1, u.id, $3, $4, $5 FROM users u
+        WHERE u.id = $2 AND ($3::integer IS NULL OR EXISTS (SELECT 1 FROM products WHERE id = $3))
+        RETURNING `+sessionColumns,
+		session.ID, session.UserID, session.ProductID, session.MaxUploads, session.ExpiresAt))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return created, nil
+}
+
+func (s *pgSessions) Get(ctx context.Context, id string) (*models.UploadSession, error) {
+	session, err := scanSession(s.db.QueryRowContext(ctx, "SELECT "+sessionColumns+" FROM upload_sessions WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return session, nil
+}
+
+func (s *pgSessions) Revoke(ctx context.Context, id string) (*models.UploadSession, error) {
+	session, err := scanSession(s.db.QueryRowContext(ctx, `
+        UPDATE upload_sessions SET revoked_at = COALESCE(revoked_at, now())
+        WHERE id = $1
+        RETURNING `+sessionColumns, id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return session, nil
+}
+
+func (s *pgSessions) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM upload_sessions WHERE expires_at < $1", t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
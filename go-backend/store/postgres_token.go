@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type pgTokens struct {
+	db *sql.DB
+}
+
+func (s *pgTokens) SigningKeys(ctx context.Context) ([]*SigningKey, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, private_key, created_at FROM signing_keys ORDER BY created_at DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*SigningKey
+	for rows.Next() {
+		var k SigningKey
+		if err := rows.Scan(&k.ID, &k.PrivateKey, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *pgTokens) AddSigningKey(ctx context.Context, key *SigningKey) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO signing_keys (id, private_key, created_at) VALUES ($1, $2, $3)",
+		key.ID, key.PrivateKey, key.CreatedAt)
+	return err
+}
+
+func (s *pgTokens) DeleteSigningKeysBefore(ctx context.Context, t time.Time) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM signing_keys WHERE created_at < $1", t)
+	return err
+}
+
+const refreshTokenColumns = "token_hash, user_id, family, expires_at, revoked_at"
+
+func scanRefreshToken(row scanner) (*RefreshToken, error) {
+	var t RefreshToken
+	var revokedAt sql.NullTime
+	if err := row.Scan(&t.Hash, &t.UserID, &t.Family, &t.ExpiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	return &t, nil
+}
+
+func (s *pgTokens) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO refresh_tokens (token_hash, user_id, family, expires_at) VALUES ($1, $2, $3, $4)",
+		token.Hash, token.UserID, token.Family, token.ExpiresAt)
+	return err
+}
+
+func (s *pgTokens) ConsumeRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	t, err := scanRefreshToken(s.db.QueryRowContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = now() WHERE token_hash = $1 AND revoked_at IS NULL RETURNING "+refreshTokenColumns, hash))
+	if !errors.Is(err, sql.ErrNoRows) {
+		return t, err
+	}
+	t, err = scanRefreshToken(s.db.QueryRowContext(ctx, "SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = $1", hash))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return t, ErrTokenReused
+}
+
+func (s *pgTokens) RevokeRefreshToken(ctx context.Context, hash string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE token_hash = $1 AND revoked_at IS NULL", hash)
+	return err
+}
+
+func (s *pgTokens) RevokeRefreshFamily(ctx context.Context, family string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = now() WHERE family = $1 AND revoked_at IS NULL", family)
+	return err
+}
+
+func (s *pgTokens) DeleteRefreshTokensBefore(ctx context.Context, t time.Time) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_at < $1", t)
+	return err
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"go-backend/models"
+
+	"github.com/lib/pq"
+)
+
+type pgUsers struct {
+	db *sql.DB
+}
+
+const userColumns = "id, email, first_name, last_name, role"
+
+func scanUser(row scanner) (*models.User, error) {
+	var u models.User
+	var firstName, lastName sql.NullString
+	if err := row.Scan(&u.ID, &u.Email, &firstName, &lastName, &u.Role); err != nil {
+		return nil, err
+	}
+	u.FirstName = firstName.String
+	u.LastName = lastName.String
+	return &u, nil
+}
+
+func (s *pgUsers) Get(ctx context.Context, id int32) (*models.User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return u, nil
+}
+
+func (s *pgUsers) GetMany(ctx context.Context, ids []int32) (map[int32]*models.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ANY($1)", pq.Int32Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make(map[int32]*models.User)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users[u.ID] = u
+	}
+	return users, rows.Err()
+}
+
+func (s *pgUsers) Create(ctx context.Context, nu NewUser) (*models.User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx,
+		`INSERT INTO users (email, password_hash, first_name, last_name)
+		 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		 RETURNING `+userColumns,
+		nu.Email, nu.PasswordHash, nu.FirstName, nu.LastName))
+	if isUniqueViolation(err) {
+		return nil, ErrEmailTaken
+	}
+	return u, err
+}
+
+func (s *pgUsers) Credentials(ctx context.Context, email string) (*models.User, string, error) {
+	var hash string
+	row := s.db.QueryRowContext(ctx, "SELECT "+userColumns+", password_hash FROM users WHERE lower(email) = lower($1)", email)
+	u, err := scanUser(extraScanner{row, []any{&hash}})
+	if err != nil {
+		return nil, "", notFound(err)
+	}
+	return u, hash, nil
+}
+
+func (s *pgUsers) SetRole(ctx context.Context, id int32, role string) (*models.User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, "UPDATE users SET role = $2 WHERE id = $1 RETURNING "+userColumns, id, role))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return u, nil
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+
+	"go-backend/models"
+
+	"github.com/lib/pq"
+)
+
+const variantColumns = "id, product_id, sku, price, stock_quantity, options, created_at"
+
+func scanVariant(row scanner) (*models.ProductVariant, error) {
+	var v models.ProductVariant
+	var options []byte
+	if err := row.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Price, &v.StockQuantity, &options, &v.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(options, &v.Options); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func queryVariants(ctx context.Context, q queryer, query string, args ...any) ([]*models.ProductVariant, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []*models.ProductVariant
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+func (s *pgProducts) GetVariant(ctx context.Context, id int32) (*models.ProductVariant, error) {
+	v, err := scanVariant(s.db.QueryRowContext(ctx, "SELECT "+variantColumns+" FROM product_variants WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return v, nil
+}
+
+func (s *pgProducts) GetVariants(ctx context.Context, ids []int32) (map[int32]*models.ProductVariant, error) {
+	variants, err := queryVariants(ctx, s.db, "SELECT "+variantColumns+" FROM product_variants WHERE id = ANY($1)", pq.Int32Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int32]*models.ProductVariant, len(variants))
+	for _, v := range variants {
+		byID[v.ID] = v
+	}
+	return byID, nil
+}
+
+func (s *pgProducts) VariantsByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductVariant, error) {
+	variants, err := queryVariants(ctx, s.db,
+		"SELECT "+variantColumns+" FROM product_variants WHERE product_id = ANY($1) ORDER BY id", pq.Int32Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := make(map[int32][]*models.ProductVariant)
+	for _, v := range variants {
+		byProduct[v.ProductID] = append(byProduct[v.ProductID], v)
+	}
+	return byProduct, nil
+}
+
+func (s *pgProducts) CreateVariant(ctx context.Context, productID int32, input models.ProductVariantInput) (*models.ProductVariant, error) {
+	options, err := json.Marshal(variantOptions(input.Options))
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockProducts(ctx, tx, "$1", productID); err != nil {
+		return nil, err
+	}
+	v, err := scanVariant(tx.QueryRowContext(ctx, `
+        INSERT INTO product_variants (product_id, sku, price, stock_quantity, options)
+        SELECT id, $2, $3, $4, $5 FROM products WHERE id = $1
+        RETURNING `+variantColumns,
+		productID, input.SKU, input.Price, input.StockQuantity, options))
+	if isUniqueViolation(err) {
+		return nil, ErrSKUTaken
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := insertProductImages(ctx, tx, productID, &v.ID, input.Images); err != nil {
+		return nil, err
+	}
+	return v, tx.Commit()
+}
+
+func (s *pgProducts) UpdateVariant(ctx context.Context, id int32, input models.ProductVariantInput) (*models.ProductVariant, error) {
+	options, err := json.Marshal(variantOptions(input.Options))
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockProducts(ctx, tx, "SELECT product_id FROM product_variants WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+	v, err := scanVariant(tx.QueryRowContext(ctx, `
+        UPDATE product_variants SET sku = $2, price = $3, stock_quantity = $4, options = $5
+        WHERE id = $1
+        RETURNING `+variantColumns,
+		id, input.SKU, input.Price, input.StockQuantity, options))
+	if isUniqueViolation(err) {
+		return nil, ErrSKUTaken
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	// The image list in the input replaces the existing one.
+	if _, err := tx.ExecContext(ctx, "DELETE FROM product_images WHERE variant_id = $1", id); err != nil {
+		return nil, err
+	}
+	if err := insertProductImages(ctx, tx, v.ProductID, &v.ID, input.Images); err != nil {
+		return nil, err
+	}
+	return v, tx.Commit()
+}
+
+func (s *pgProducts) DeleteVariant(ctx context.Context, id int32) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := lockProducts(ctx, tx, "SELECT product_id FROM product_variants WHERE id = $1", id); err != nil {
+		return false, err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM product_variants WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+func (s *pgProducts) ImagesByVariants(ctx context.Context, variantIDs []int32) (map[int32][]*models.ProductImage, error) {
+	return queryImages(ctx, s.db, "WHERE variant_id = ANY($1)", pq.Int32Array(variantIDs),
+		func(img *models.ProductImage) int32 { return *img.VariantID })
+}
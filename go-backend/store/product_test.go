@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-backend/models"
+)
+
+// Once a product has variants only they can be bought, so its own price and
+// stock no longer count towards the filters and the price sorts.
+func TestProductFilterVariants(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	cat, err := s.Categories.Create(ctx, "Shirts", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	products := []struct {
+		name     string
+		price    float64
+		stock    int32
+		variants []models.ProductVariantInput
+	}{
+		{name: "A", price: 5},
+		// B's own price and stock are left from before it had variants.
+		{name: "B", price: 100, stock: 9, variants: []models.ProductVariantInput{
+			{SKU: "B-S", Price: 10},
+			{SKU: "B-L", Price: 50, StockQuantity: 3},
+		}},
+		{name: "C", price: 20, stock: 2},
+	}
+	for _, p := range products {
+		created, err := s.Products.Create(ctx, models.ProductInput{Name: p.name, Price: p.price, StockQuantity: p.stock, CategoryID: cat.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range p.variants {
+			if _, err := s.Products.CreateVariant(ctx, created.ID, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	price := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name   string
+		filter ProductFilter
+		want   string
+	}{
+		{name: "in stock", filter: ProductFilter{InStock: true}, want: "BC"},
+		{name: "max price", filter: ProductFilter{MaxPrice: price(15)}, want: "AB"},
+		{name: "min price", filter: ProductFilter{MinPrice: price(40)}, want: "B"},
+		{name: "own price ignored", filter: ProductFilter{MinPrice: price(90)}, want: ""},
+		{name: "in stock within price", filter: ProductFilter{MaxPrice: price(15), InStock: true}, want: ""},
+		{name: "in stock above price", filter: ProductFilter{MinPrice: price(15), InStock: true}, want: "BC"},
+		{name: "cheapest first", filter: ProductFilter{Sort: SortPriceAsc}, want: "ABC"},
+		{name: "dearest first", filter: ProductFilter{Sort: SortPriceDesc}, want: "CBA"},
+	}
+	for _, tt := range tests {
+		got, err := s.Products.List(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var names strings.Builder
+		for _, p := range got {
+			names.WriteString(p.Name)
+		}
+		if names.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, names.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-backend/models"
+)
+
+// A session takes uploads until it has had MaxUploads of them, is revoked
+// or expires, whichever comes first.
+func TestUploadSession(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory()
+	u, err := s.Users.Create(ctx, NewUser{Email: "ann@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload := func(id string) error {
+		_, err := s.Images.Create(ctx, models.Image{OwnerID: u.ID, Key: "images/" + id, SessionID: &id}, nil)
+		return err
+	}
+	status := func(id string) string {
+		session, err := s.Sessions.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return session.Status
+	}
+	for _, id := range []string{"full", "revoked", "expired"} {
+		expiresAt := time.Now().Add(time.Hour)
+		if id == "expired" {
+			expiresAt = time.Now().Add(-time.Second)
+		}
+		if _, err := s.Sessions.Create(ctx, NewUploadSession{ID: id, UserID: u.ID, MaxUploads: 2, ExpiresAt: expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := upload("full"); err != nil {
+			t.Fatalf("upload %d: %v", i+1, err)
+		}
+	}
+	if got := status("full"); got != "COMPLETED" {
+		t.Errorf("after MaxUploads the session is %s, want COMPLETED", got)
+	}
+	if err := upload("full"); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("uploading to a completed session: error = %v, want ErrSessionClosed", err)
+	}
+
+	if err := upload("revoked"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.Sessions.Revoke(ctx, "revoked"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := status("revoked"); got != "REVOKED" {
+		t.Errorf("revoked session is %s", got)
+	}
+	if err := upload("revoked"); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("uploading to a revoked session: error = %v, want ErrSessionClosed", err)
+	}
+
+	if got := status("expired"); got != "EXPIRED" {
+		t.Errorf("expired session is %s", got)
+	}
+	if err := upload("expired"); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("uploading to an expired session: error = %v, want ErrSessionClosed", err)
+	}
+
+	// Deleting expired sessions keeps the images uploaded through them.
+	n, err := s.Sessions.DeleteExpired(ctx, time.Now().Add(2*time.Hour))
+	if err != nil || n != 3 {
+		t.Fatalf("DeleteExpired() = %d, %v, want 3", n, err)
+	}
+	images, err := s.Images.ListByOwner(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 3 {
+		t.Errorf("%d images left, want 3", len(images))
+	}
+	for _, img := range images {
+		if img.SessionID != nil {
+			t.Errorf("image %d still points at session %s", img.ID, *img.SessionID)
+		}
+	}
+}
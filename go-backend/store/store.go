@@ -0,0 +1,514 @@
+// Package store defines the data access interfaces used by the GraphQL
+// resolvers, with a Postgres implementation and an in-memory one for tests.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-backend/models"
+	"go-backend/pricing"
+)
+
+// ErrNotFound is returned when a requested row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrEmailTaken is returned when creating a user whose email address is
+// already registered, compared case-insensitively.
+var ErrEmailTaken = errors.New("email address is already registered")
+
+// ErrTokenReused is returned by ConsumeRefreshToken for a refresh token that
+// was already consumed or revoked.
+var ErrTokenReused = errors.New("refresh token already used")
+
+// ErrStatusChanged is returned by SetStatus when the order is no longer in
+// the expected status.
+var ErrStatusChanged = errors.New("order status was changed concurrently")
+
+// ErrCategoryCycle is returned when moving a category under itself or one of
+// its descendants.
+var ErrCategoryCycle = errors.New("a category can't be moved into its own subtree")
+
+// ErrAttributeDefined is returned when defining an attribute that the
+// category already defines, compared case-insensitively.
+var ErrAttributeDefined = errors.New("the category already defines this attribute")
+
+// ErrSKUTaken is returned when creating or updating a variant with a SKU
+// another variant has.
+var ErrSKUTaken = errors.New("SKU is already in use")
+
+// ErrOptionDefined is returned when adding a customization option with the
+// name of another option of the product, compared case-insensitively.
+var ErrOptionDefined = errors.New("the product already has an option of this name")
+
+// ErrSessionClosed is returned when uploading through a QR upload session
+// that has expired, been revoked or received all the uploads it allows.
+var ErrSessionClosed = errors.New("the upload session is no longer accepting uploads")
+
+// ErrRefundExceeded is returned by ReserveRefund when the payment isn't
+// captured or what is left of it doesn't cover the refund.
+var ErrRefundExceeded = errors.New("the refund exceeds what is left of the payment")
+
+// CategoryInUseError is returned when deleting a category that products are
+// still attached to without naming a category to move them to.
+type CategoryInUseError struct {
+	CategoryID int32
+	Products   int32
+}
+
+func (e *CategoryInUseError) Error() string {
+	return fmt.Sprintf("products are still attached to category %d", e.CategoryID)
+}
+
+// Store groups the per-entity stores the resolvers are built with.
+type Store struct {
+	Products    ProductStore
+	Categories  CategoryStore
+	Orders      OrderStore
+	Reviews     ReviewStore
+	Users       UserStore
+	Tokens      TokenStore
+	Carts       CartStore
+	Inventory   InventoryStore
+	Payments    PaymentStore
+	Idempotency IdempotencyStore
+	Images      ImageStore
+	Sessions    UploadSessionStore
+}
+
+// ProductFilter narrows and orders a product listing. Nil and zero fields
+// are ignored.
+type ProductFilter struct {
+	CategoryID *int32
+	// IncludeSubcategories widens CategoryID to the category's whole subtree.
+	IncludeSubcategories bool
+	Search               *string
+	// MinPrice, MaxPrice and InStock look at the product's variants once it
+	// has any, since only they can be bought: one of them must be priced
+	// within the bounds and, with InStock, be in stock. The price sorts
+	// order by the lowest price the product can be bought at.
+	MinPrice *float64
+	MaxPrice *float64
+	InStock  bool
+	// Attributes must all match (name and value, case-insensitively).
+	Attributes []AttributeFilter
+	// MinRating is compared against the average review rating; products
+	// without reviews count as 0.
+	MinRating *float64
+	Sort      ProductSort
+}
+
+type AttributeFilter struct {
+	Name  string
+	Value string
+}
+
+// ProductSort selects the order of a product listing. The zero value orders
+// by ID.
+type ProductSort string
+
+const (
+	SortDefault   ProductSort = ""
+	SortPriceAsc  ProductSort = "PRICE_ASC"
+	SortPriceDesc ProductSort = "PRICE_DESC"
+	SortNewest    ProductSort = "NEWEST"
+	SortRating    ProductSort = "RATING"
+	SortName      ProductSort = "NAME"
+)
+
+// ProductStore manages products with their images and attributes.
+//
+// GetMany and the *ByProducts methods here and on the other stores back the
+// per-request loaders: they take a batch of keys and return results keyed by
+// them, omitting keys that have no rows.
+type ProductStore interface {
+	Get(ctx context.Context, id int32) (*models.Product, error)
+	GetMany(ctx context.Context, ids []int32) (map[int32]*models.Product, error)
+	List(ctx context.Context, filter ProductFilter) ([]*models.Product, error)
+	// Page is the paginated form of List.
+	Page(ctx context.Context, filter ProductFilter, page PageArgs) (*Page[models.Product], error)
+	// Search ranks products against a web-style search query over names,
+	// descriptions and attribute values, best match first. When nothing
+	// matches it falls back to fuzzy matching on names to tolerate typos.
+	Search(ctx context.Context, query string, page PageArgs) (*Page[models.SearchHit], error)
+	Create(ctx context.Context, input models.ProductInput) (*models.Product, error)
+	Update(ctx context.Context, id int32, input models.ProductInput) (*models.Product, error)
+	Delete(ctx context.Context, id int32) (bool, error)
+	Images(ctx context.Context, productID int32) ([]*models.ProductImage, error)
+	ImagesByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductImage, error)
+	AddImage(ctx context.Context, productID int32, input models.ProductImageInput) (*models.ProductImage, error)
+	Attributes(ctx context.Context, productID int32) ([]*models.ProductAttribute, error)
+	AttributesByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductAttribute, error)
+	GetAttribute(ctx context.Context, id int32) (*models.ProductAttribute, error)
+	AddAttribute(ctx context.Context, productID int32, input models.ProductAttributeInput) (*models.ProductAttribute, error)
+	UpdateAttribute(ctx context.Context, id int32, input models.ProductAttributeInput) (*models.ProductAttribute, error)
+	DeleteAttribute(ctx context.Context, id int32) (bool, error)
+	GetVariant(ctx context.Context, id int32) (*models.ProductVariant, error)
+	GetVariants(ctx context.Context, ids []int32) (map[int32]*models.ProductVariant, error)
+	VariantsByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.ProductVariant, error)
+	// CreateVariant adds a variant to a product, returning ErrSKUTaken if
+	// another variant has its SKU.
+	CreateVariant(ctx context.Context, productID int32, input models.ProductVariantInput) (*models.ProductVariant, error)
+	// UpdateVariant replaces a variant's fields and images, returning
+	// ErrSKUTaken if another variant has its new SKU.
+	UpdateVariant(ctx context.Context, id int32, input models.ProductVariantInput) (*models.ProductVariant, error)
+	DeleteVariant(ctx context.Context, id int32) (bool, error)
+	ImagesByVariants(ctx context.Context, variantIDs []int32) (map[int32][]*models.ProductImage, error)
+	// CustomizationOptionsByProducts returns the options offered on each
+	// product, ordered by ID.
+	CustomizationOptionsByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.CustomizationOption, error)
+	GetCustomizationOption(ctx context.Context, id int32) (*models.CustomizationOption, error)
+	// CreateCustomizationOption adds opt to opt.ProductID, returning
+	// ErrOptionDefined if the product has an option of that name.
+	CreateCustomizationOption(ctx context.Context, opt models.CustomizationOption) (*models.CustomizationOption, error)
+	// UpdateCustomizationOption replaces an option's name, kind, rules and
+	// price modifiers.
+	UpdateCustomizationOption(ctx context.Context, id int32, opt models.CustomizationOption) (*models.CustomizationOption, error)
+	DeleteCustomizationOption(ctx context.Context, id int32) (bool, error)
+}
+
+type CategoryStore interface {
+	Get(ctx context.Context, id int32) (*models.Category, error)
+	GetMany(ctx context.Context, ids []int32) (map[int32]*models.Category, error)
+	List(ctx context.Context) ([]*models.Category, error)
+	Create(ctx context.Context, name string, parentID *int32) (*models.Category, error)
+	Update(ctx context.Context, id int32, name string) (*models.Category, error)
+	// Move makes parentID (or nothing, when nil) the category's parent,
+	// failing with ErrCategoryCycle if it is the category or lies beneath it.
+	Move(ctx context.Context, id int32, parentID *int32) (*models.Category, error)
+	// Delete removes a category, moving its subcategories up to its parent.
+	// Its products are moved to reassignTo; when that is nil, a category
+	// with products is refused with a *CategoryInUseError.
+	Delete(ctx context.Context, id int32, reassignTo *int32) (bool, error)
+	ChildrenByParents(ctx context.Context, parentIDs []int32) (map[int32][]*models.Category, error)
+	// Ancestors lists the category's parent, grandparent and so on, root
+	// first.
+	Ancestors(ctx context.Context, id int32) ([]*models.Category, error)
+	// Descendants lists every category beneath id, ordered by ID.
+	Descendants(ctx context.Context, id int32) ([]*models.Category, error)
+	// ProductCounts counts the products in each category and its
+	// subcategories.
+	ProductCounts(ctx context.Context, ids []int32) (map[int32]int32, error)
+	// DefinitionsByCategories returns the attribute definitions made on each
+	// category itself, ordered by ID.
+	DefinitionsByCategories(ctx context.Context, ids []int32) (map[int32][]*models.AttributeDefinition, error)
+	GetDefinition(ctx context.Context, id int32) (*models.AttributeDefinition, error)
+	// CreateDefinition adds def to def.CategoryID, returning
+	// ErrAttributeDefined if the category already defines the name.
+	CreateDefinition(ctx context.Context, def models.AttributeDefinition) (*models.AttributeDefinition, error)
+	// UpdateDefinition replaces a definition's name, type, allowed values
+	// and required flag.
+	UpdateDefinition(ctx context.Context, id int32, def models.AttributeDefinition) (*models.AttributeDefinition, error)
+	DeleteDefinition(ctx context.Context, id int32) (bool, error)
+}
+
+type OrderStore interface {
+	Get(ctx context.Context, id int32) (*models.Order, error)
+	List(ctx context.Context) ([]*models.Order, error)
+	ListByUser(ctx context.Context, userID int32) ([]*models.Order, error)
+	// PageByUser is the paginated form of ListByUser, newest first.
+	PageByUser(ctx context.Context, userID int32, page PageArgs) (*Page[models.Order], error)
+	Items(ctx context.Context, orderID int32) ([]*models.OrderItem, error)
+	// Create inserts an order for userID and its items and takes the ordered
+	// quantities out of stock, all in one transaction. If the stock not
+	// reserved by other carts can't cover every item it stores nothing and
+	// returns an *InsufficientStockError.
+	Create(ctx context.Context, userID int32, order NewOrder) (*models.Order, error)
+	// PendingBefore lists the orders still PENDING that were placed before
+	// t, oldest first.
+	PendingBefore(ctx context.Context, t time.Time) ([]*models.Order, error)
+	// SetStatus moves an order from change.From to change.To and records it
+	// in the status history, in one transaction. It returns ErrStatusChanged
+	// if the order isn't in change.From; the caller checks that the move
+	// itself is allowed.
+	SetStatus(ctx context.Context, id int32, change StatusChange) (*models.Order, error)
+	// StatusHistory lists an order's status changes, oldest first, starting
+	// with its creation.
+	StatusHistory(ctx context.Context, orderID int32) ([]*models.OrderStatusChange, error)
+}
+
+// StatusChange is a move of an order from one status to another.
+type StatusChange struct {
+	From string
+	To   string
+	// ActorID is the user making the change; zero for the system.
+	ActorID int32
+	Note    string
+	// Restock puts the order's items back in stock.
+	Restock bool
+}
+
+// NewOrder is an order priced by the caller, ready to be stored. The amounts
+// are snapshots: later price changes don't affect them.
+type NewOrder struct {
+	Subtotal      float64
+	DiscountTotal float64
+	TaxTotal      float64
+	ShippingTotal float64
+	Total         float64
+	Items         []NewOrderItem
+	// CartID, if not zero, is the buyer's cart. Its reservations count
+	// towards the order and are released once the order is placed.
+	CartID int32
+}
+
+// NewOrderItem is a priced line of a NewOrder. VariantID and SKU name the
+// variant ordered, if any; UnitPrice includes the customizations.
+type NewOrderItem struct {
+	ProductID      int32
+	VariantID      int32
+	SKU            string
+	Customizations []models.Customization
+	Quantity       int32
+	UnitPrice      float64
+	Discount       float64
+	Tax            float64
+	LineTotal      float64
+}
+
+type ReviewStore interface {
+	ListByProduct(ctx context.Context, productID int32) ([]*models.Review, error)
+	ListByProducts(ctx context.Context, productIDs []int32) (map[int32][]*models.Review, error)
+	// PageByProduct lists a product's reviews newest first.
+	PageByProduct(ctx context.Context, productID int32, page PageArgs) (*Page[models.Review], error)
+	// Create records a review by userID; input.UserID is ignored.
+	Create(ctx context.Context, userID int32, input models.ReviewInput) (*models.Review, error)
+}
+
+type UserStore interface {
+	Get(ctx context.Context, id int32) (*models.User, error)
+	GetMany(ctx context.Context, ids []int32) (map[int32]*models.User, error)
+	// Create inserts a user, returning ErrEmailTaken if the address is in use.
+	Create(ctx context.Context, u NewUser) (*models.User, error)
+	// Credentials looks a user up by email, case-insensitively, and returns
+	// it with its password hash.
+	Credentials(ctx context.Context, email string) (*models.User, string, error)
+	SetRole(ctx context.Context, id int32, role string) (*models.User, error)
+}
+
+// NewUser is a user account about to be created. The password must already
+// be hashed.
+type NewUser struct {
+	Email        string
+	PasswordHash string
+	FirstName    string
+	LastName     string
+}
+
+// CartOwner identifies a cart: a user's, or an anonymous one by its token.
+// Exactly one field is set.
+type CartOwner struct {
+	UserID int32
+	Token  string
+}
+
+// CartStore persists shopping carts. Every write extends the cart's expiry
+// to the given time.
+type CartStore interface {
+	// Get returns the owner's cart, or ErrNotFound.
+	Get(ctx context.Context, owner CartOwner) (*models.Cart, error)
+	// Open returns the owner's cart, creating it if needed.
+	Open(ctx context.Context, owner CartOwner, expiresAt time.Time) (*models.Cart, error)
+	Items(ctx context.Context, cartID int32) ([]*models.CartItem, error)
+	// AddItem adds item.Quantity to the cart's line for the same product,
+	// variant and customizations, creating the line at item.Price if there
+	// is none.
+	AddItem(ctx context.Context, cartID int32, item NewCartItem, expiresAt time.Time) (*models.CartItem, error)
+	// UpdateItem sets the quantity of a line, returning ErrNotFound if the
+	// line is not in the cart.
+	UpdateItem(ctx context.Context, cartID, itemID, quantity int32, expiresAt time.Time) (*models.CartItem, error)
+	RemoveItem(ctx context.Context, cartID, itemID int32, expiresAt time.Time) (bool, error)
+	Clear(ctx context.Context, cartID int32, expiresAt time.Time) error
+	// Merge moves the lines of the anonymous cart with token into the user's
+	// cart, adding up quantities, and deletes the anonymous cart. It returns
+	// the user's cart, which is created if needed.
+	Merge(ctx context.Context, token string, userID int32, expiresAt time.Time) (*models.Cart, error)
+	// DeleteExpired removes carts that expired before t and returns how many.
+	DeleteExpired(ctx context.Context, t time.Time) (int64, error)
+}
+
+// NewCartItem is a line to add to a cart.
+type NewCartItem struct {
+	StockKey
+	Customizations []models.Customization
+	Quantity       int32
+	Price          float64
+}
+
+// ImageStore records the files users uploaded. The files themselves are
+// kept in object storage; see package storage.
+type ImageStore interface {
+	Get(ctx context.Context, id int32) (*models.Image, error)
+	// ListByOwner returns a user's images, oldest first.
+	ListByOwner(ctx context.Context, ownerID int32) ([]*models.Image, error)
+	// ListBySession returns the images received through an upload session,
+	// oldest first.
+	ListBySession(ctx context.Context, sessionID string) ([]*models.Image, error)
+	// Create records an image and its renditions. The owner must exist.
+	// An image with a SessionID takes up one of the session's uploads; if
+	// the session is not ACTIVE, Create returns ErrSessionClosed.
+	Create(ctx context.Context, img models.Image, renditions []models.ImageRendition) (*models.Image, error)
+	// RenditionsByImages returns the renditions of each image, largest
+	// first.
+	RenditionsByImages(ctx context.Context, imageIDs []int32) (map[int32][]*models.ImageRendition, error)
+	Delete(ctx context.Context, id int32) (bool, error)
+}
+
+// UploadSessionStore keeps QR upload sessions.
+type UploadSessionStore interface {
+	Create(ctx context.Context, session NewUploadSession) (*models.UploadSession, error)
+	Get(ctx context.Context, id string) (*models.UploadSession, error)
+	// Revoke closes a session to further uploads. Revoking a revoked
+	// session leaves it alone.
+	Revoke(ctx context.Context, id string) (*models.UploadSession, error)
+	// DeleteExpired removes sessions that expired before t and returns how
+	// many. Their images are kept.
+	DeleteExpired(ctx context.Context, t time.Time) (int64, error)
+}
+
+// NewUploadSession is a session to create. The caller picks the ID, which
+// must be unguessable.
+type NewUploadSession struct {
+	ID         string
+	UserID     int32
+	ProductID  *int32
+	MaxUploads int32
+	ExpiresAt  time.Time
+}
+
+// PaymentStore records payment intents. Their statuses are PENDING until
+// the provider has been asked, then AUTHORIZED, CAPTURED, FAILED, VOIDED or,
+// once fully refunded, REFUNDED.
+type PaymentStore interface {
+	Get(ctx context.Context, id int32) (*models.Payment, error)
+	// GetByReference finds a payment by the provider's ID for it.
+	GetByReference(ctx context.Context, provider, reference string) (*models.Payment, error)
+	// ListByOrder returns an order's payments, oldest first.
+	ListByOrder(ctx context.Context, orderID int32) ([]*models.Payment, error)
+	// Create records a PENDING payment of amount for an order.
+	Create(ctx context.Context, orderID int32, provider string, amount pricing.Cents) (*models.Payment, error)
+	Update(ctx context.Context, id int32, update PaymentUpdate) (*models.Payment, error)
+	// ReserveRefund adds amount to what has been refunded of a CAPTURED
+	// payment before the provider is asked to refund it, so that concurrent
+	// refunds can't together exceed the payment. It returns
+	// ErrRefundExceeded, leaving the payment alone, if the payment isn't
+	// captured or amount is more than is left of it. A refund the provider
+	// refuses is released with an Update subtracting amount again.
+	ReserveRefund(ctx context.Context, id int32, amount pricing.Cents) (*models.Payment, error)
+}
+
+// PaymentUpdate changes a payment. Zero fields are left alone.
+type PaymentUpdate struct {
+	Status        string
+	Reference     string
+	FailureReason string
+	// Refunded is added to the amount refunded so far; it is negative to
+	// release a reservation.
+	Refunded pricing.Cents
+}
+
+// IdempotencyStore records the idempotency keys of requests and their
+// responses. Keys are scoped to a user.
+type IdempotencyStore interface {
+	// Begin claims key for a request until expiresAt. If the key is already
+	// claimed and hasn't expired, it returns the existing record and false
+	// instead; the key is then left alone.
+	Begin(ctx context.Context, key IdempotencyKey, expiresAt time.Time) (*IdempotencyKey, bool, error)
+	// Complete stores the response to the request holding the key.
+	Complete(ctx context.Context, userID int32, key string, response []byte) error
+	// Release gives up a key whose request failed, so it can be retried.
+	Release(ctx context.Context, userID int32, key string) error
+	// DeleteExpired removes keys that expired before t and returns how many.
+	DeleteExpired(ctx context.Context, t time.Time) (int64, error)
+}
+
+// IdempotencyKey is a claimed idempotency key. Fingerprint identifies the
+// request made with it; Response is nil while the request is in progress.
+type IdempotencyKey struct {
+	UserID      int32
+	Key         string
+	Operation   string
+	Fingerprint string
+	Response    []byte
+	ExpiresAt   time.Time
+}
+
+// StockKey names what stock is kept for: a product, or one of its variants.
+// Products with variants are sold through their variants, each with its own
+// stock.
+type StockKey struct {
+	ProductID int32
+	// VariantID is zero for the product itself.
+	VariantID int32
+}
+
+// InventoryStore holds stock for carts in checkout. A reservation keeps its
+// quantity of a product or variant from being ordered through any other cart until it
+// expires; expired reservations are ignored and eventually deleted.
+type InventoryStore interface {
+	// Reserve holds stock for the cart's current lines until expiresAt,
+	// replacing the cart's earlier reservations. If the stock not reserved
+	// by other carts can't cover every line it reserves nothing and returns
+	// an *InsufficientStockError.
+	Reserve(ctx context.Context, cartID int32, expiresAt time.Time) ([]*Reservation, error)
+	// Reservations returns the cart's unexpired reservations.
+	Reservations(ctx context.Context, cartID int32) ([]*Reservation, error)
+	Release(ctx context.Context, cartID int32) error
+	// Available returns how much of each product or variant can be ordered
+	// through cartID: its stock less the unexpired reservations of other
+	// carts. Unknown products and variants, and variants of another
+	// product, are omitted.
+	Available(ctx context.Context, items []StockKey, cartID int32) (map[StockKey]int32, error)
+	// DeleteExpired removes reservations that expired before t and returns
+	// how many.
+	DeleteExpired(ctx context.Context, t time.Time) (int64, error)
+}
+
+// Reservation is stock held for a cart.
+type Reservation struct {
+	CartID int32
+	StockKey
+	Quantity  int32
+	ExpiresAt time.Time
+}
+
+// TokenStore keeps the server-side state of API tokens: the signing keys
+// published in the key set and the refresh tokens issued to clients.
+type TokenStore interface {
+	// SigningKeys returns every stored key, newest first.
+	SigningKeys(ctx context.Context) ([]*SigningKey, error)
+	AddSigningKey(ctx context.Context, key *SigningKey) error
+	// DeleteSigningKeysBefore removes keys created before t.
+	DeleteSigningKeysBefore(ctx context.Context, t time.Time) error
+
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	// ConsumeRefreshToken atomically revokes the live token with the given
+	// hash and returns it. A token that exists but is no longer live is
+	// returned together with ErrTokenReused.
+	ConsumeRefreshToken(ctx context.Context, hash string) (*RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, hash string) error
+	// RevokeRefreshFamily revokes every token descended from the same login.
+	RevokeRefreshFamily(ctx context.Context, family string) error
+	// DeleteRefreshTokensBefore removes tokens that expired before t.
+	DeleteRefreshTokensBefore(ctx context.Context, t time.Time) error
+}
+
+// SigningKey is an Ed25519 key used to sign access tokens. PrivateKey holds
+// the 32-byte seed.
+type SigningKey struct {
+	ID         string
+	PrivateKey []byte
+	CreatedAt  time.Time
+}
+
+// RefreshToken is an issued refresh token. Only a hash of the token is
+// stored; Family links the tokens produced by rotating one another.
+type RefreshToken struct {
+	Hash      string
+	UserID    int32
+	Family    string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-backend/auth"
+)
+
+// subscriptionInit authenticates a WebSocket connection by the access
+// token in its connection_init payload, given as authorization: "Bearer
+// <token>", for clients that can't send a cookie or header with the
+// upgrade request. Without one the connection keeps the caller of the
+// upgrade request.
+func subscriptionInit(ctx context.Context, payload json.RawMessage) (context.Context, error) {
+	var init struct {
+		Authorization string `json:"authorization"`
+	}
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &init); err != nil {
+			return nil, err
+		}
+	}
+	if init.Authorization == "" {
+		return ctx, nil
+	}
+	scheme, token, ok := strings.Cut(init.Authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, auth.ErrInvalidToken
+	}
+	p, err := tokens.VerifyToken(ctx, strings.TrimSpace(token))
+	if err != nil {
+		return nil, err
+	}
+	return auth.WithPrincipal(ctx, p), nil
+}
+
+// allowOrigins returns a check that lets browsers on origins, or on the
+// server's own origin, open WebSocket connections. Browsers don't apply
+// CORS to WebSockets, so without it any site could subscribe with the
+// visitor's cookie.
+func allowOrigins(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, o := range origins {
+			if strings.EqualFold(origin, o) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"go-backend/auth"
+)
+
+// tokenError writes an OAuth 2.0 style error response (RFC 6749 §5.2).
+func tokenError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+func writeTokenPair(w http.ResponseWriter, pair *auth.TokenPair) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(pair)
+}
+
+// tokenHandler issues an access/refresh token pair for an email address and
+// password, for clients that can't hold a session cookie.
+func tokenHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	user, err := accounts.Login(r.Context(), credentials.Email, credentials.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		tokenError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("token: %v", err)
+		tokenError(w, http.StatusInternalServerError, "server_error", "login failed")
+		return
+	}
+
+	pair, err := tokens.Issue(r.Context(), user)
+	if err != nil {
+		log.Printf("token: %v", err)
+		tokenError(w, http.StatusInternalServerError, "server_error", "failed to issue tokens")
+		return
+	}
+	writeTokenPair(w, pair)
+}
+
+// refreshHandler exchanges a refresh token for a new pair. The presented
+// refresh token stops working.
+func refreshHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+		tokenError(w, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+		return
+	}
+
+	pair, err := tokens.Refresh(r.Context(), body.RefreshToken)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		tokenError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("refresh: %v", err)
+		tokenError(w, http.StatusInternalServerError, "server_error", "failed to refresh tokens")
+		return
+	}
+	writeTokenPair(w, pair)
+}
+
+// revokeHandler revokes a refresh token and every token rotated from the
+// same login. It succeeds for unknown tokens too (RFC 7009 §2.2).
+func revokeHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+		tokenError(w, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+		return
+	}
+
+	if err := tokens.Revoke(r.Context(), body.RefreshToken); err != nil {
+		log.Printf("revoke: %v", err)
+		tokenError(w, http.StatusInternalServerError, "server_error", "failed to revoke token")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// jwksHandler publishes the public keys access tokens are signed with.
+func jwksHandler(w http.ResponseWriter, r *http.Request) {
+	set, err := tokens.KeySet(r.Context())
+	if err != nil {
+		log.Printf("jwks: %v", err)
+		http.Error(w, "Failed to load key set", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(set)
+}
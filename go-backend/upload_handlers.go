@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-backend/auth"
+	"go-backend/config"
+	"go-backend/events"
+	"go-backend/imaging"
+	"go-backend/models"
+	"go-backend/store"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/skip2/go-qrcode"
+)
+
+// qrSessionRetention is how long expired upload sessions are kept, so that
+// their status and files can still be looked up, before cleanup deletes
+// them. The images themselves are kept.
+const qrSessionRetention = 7 * 24 * time.Hour
+
+// uploadSessionCleanupInterval is how often expired upload sessions are
+// deleted.
+const uploadSessionCleanupInterval = time.Hour
+
+// generateQRHandler opens an upload session for the caller and returns a
+// QR code of its upload page as a PNG, naming the session in the
+// X-Upload-Session header. The query parameters ttl (a duration such as
+// "30m"), maxUploads and productId override the configured defaults;
+// uploads to a session with a productId, which only sellers may give, are
+// added to that product's images.
+func generateQRHandler(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	if principal == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	session := store.NewUploadSession{
+		ID:         uuid.New().String(),
+		UserID:     principal.UserID,
+		MaxUploads: int32(uploadConfig.QRMaxUploads),
+	}
+	ttl := uploadConfig.QRSessionTTL
+	query := r.URL.Query()
+	if v := query.Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > config.MaxQRSessionTTL {
+			http.Error(w, fmt.Sprintf("ttl must be a duration between 0 and %s", config.MaxQRSessionTTL), http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+	if v := query.Get("maxUploads"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > config.MaxQRUploads {
+			http.Error(w, fmt.Sprintf("maxUploads must be a number from 1 to %d", config.MaxQRUploads), http.StatusBadRequest)
+			return
+		}
+		session.MaxUploads = int32(n)
+	}
+	if v := query.Get("productId"); v != "" {
+		if !principal.HasRole(auth.RoleSeller) {
+			http.Error(w, "Only sellers can upload to a product", http.StatusForbidden)
+			return
+		}
+		id, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			http.Error(w, "productId must be a number", http.StatusBadRequest)
+			return
+		}
+		productID := int32(id)
+		session.ProductID = &productID
+	}
+	session.ExpiresAt = time.Now().Add(ttl)
+
+	created, err := uploadSessions.Create(r.Context(), session)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("generate-qr: %v", err)
+		http.Error(w, "Failed to open upload session", http.StatusInternalServerError)
+		return
+	}
+
+	uploadURL := fmt.Sprintf("https://%s/upload/%s", r.Host, created.ID) // Use the actual host
+	qr, err := qrcode.Encode(uploadURL, qrcode.Medium, 256)
+	if err != nil {
+		http.Error(w, "Failed to generate QR code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("X-Upload-Session", created.ID)
+	w.Header().Set("X-Upload-Session-Expires", created.ExpiresAt)
+	w.Write(qr)
+}
+
+// activeSession looks up the upload session named in the path, writing an
+// error response and returning nil unless it is still taking uploads.
+func activeSession(w http.ResponseWriter, r *http.Request) *models.UploadSession {
+	session, err := uploadSessions.Get(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Invalid or expired QR code", http.StatusNotFound)
+		return nil
+	}
+	if err != nil {
+		log.Printf("upload session: %v", err)
+		http.Error(w, "Failed to look up upload session", http.StatusInternalServerError)
+		return nil
+	}
+	if session.Status != "ACTIVE" {
+		http.Error(w, "This QR code is "+sessionClosedReason(session.Status), http.StatusGone)
+		return nil
+	}
+	return session
+}
+
+func sessionClosedReason(status string) string {
+	switch status {
+	case "COMPLETED":
+		return "used up"
+	case "REVOKED":
+		return "revoked"
+	}
+	return "expired"
+}
+
+var uploadPage = template.Must(template.New("upload").Parse(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Upload Image</title>
+	</head>
+	<body>
+		<h1>Upload Image</h1>
+		<p>{{.Remaining}} of {{.MaxUploads}} uploads left</p>
+		<form action="/upload/{{.ID}}" method="post" enctype="multipart/form-data">
+			<input type="file" name="file" accept="image/*" multiple>
+			<button type="submit">Upload</button>
+			<input type="file" name="file" accept="image/*" capture="camera">
+			<button type="submit">Take Photo</button>
+		</form>
+	</body>
+	</html>
+	`))
+
+func uploadHandlerGET(w http.ResponseWriter, r *http.Request) {
+	session := activeSession(w, r)
+	if session == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	uploadPage.Execute(w, struct {
+		ID                    string
+		Remaining, MaxUploads int32
+	}{session.ID, session.MaxUploads - session.UploadCount, session.MaxUploads})
+}
+
+// uploadHandlerPOST stores the files posted to an upload session, each
+// taking one of its uploads. Files that arrive once the session has closed
+// are turned away with 410 Gone; those before them are kept.
+func uploadHandlerPOST(w http.ResponseWriter, r *http.Request) {
+	session := activeSession(w, r)
+	if session == nil {
+		return
+	}
+
+	// Parse the multipart form data
+	r.Body = http.MaxBytesReader(w, r.Body, uploadConfig.MaxBytes)
+	err := r.ParseMultipartForm(uploadConfig.MaxBytes)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Uploads are limited to %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Unable to parse form", http.StatusBadRequest)
+		return
+	}
+
+	// The page has two file inputs; the one not used posts an empty part.
+	var files []*models.Image
+	for _, fileHeader := range r.MultipartForm.File["file"] {
+		if fileHeader.Filename == "" && fileHeader.Size == 0 {
+			continue
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			http.Error(w, "Error retrieving the file", http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			http.Error(w, "Error reading the file", http.StatusBadRequest)
+			return
+		}
+
+		img, err := saveImage(r.Context(), session.UserID, &session.ID, fileHeader.Filename, data)
+		var limitErr *imaging.LimitError
+		switch {
+		case errors.Is(err, imaging.ErrUnsupported):
+			http.Error(w, fileHeader.Filename+": "+err.Error(), http.StatusUnsupportedMediaType)
+			return
+		case errors.Is(err, imaging.ErrCorrupt), errors.As(err, &limitErr):
+			http.Error(w, fileHeader.Filename+": "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, store.ErrSessionClosed):
+			http.Error(w, "This QR code takes no more uploads", http.StatusGone)
+			return
+		case err != nil:
+			log.Printf("upload: %v", err)
+			http.Error(w, "Error saving the image", http.StatusInternalServerError)
+			return
+		}
+		files = append(files, img)
+		if err := events.PublishImageUploaded(r.Context(), broker, session.ID, img.ID); err != nil {
+			log.Printf("upload: announcing image %d: %v", img.ID, err)
+		}
+
+		if session.ProductID != nil {
+			url := imageStorage.URL(img.Key)
+			_, err := products.AddImage(r.Context(), *session.ProductID, models.ProductImageInput{ImageUrl: &url, ImageID: &img.ID})
+			if err != nil {
+				log.Printf("upload: adding image %d to product %d: %v", img.ID, *session.ProductID, err)
+			}
+		}
+	}
+	if len(files) == 0 {
+		http.Error(w, "Error retrieving the file", http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]int32, len(files))
+	for i, img := range files {
+		ids[i] = img.ID
+	}
+	renditions, err := images.RenditionsByImages(r.Context(), ids)
+	if err != nil {
+		log.Printf("upload: %v", err)
+	}
+	uploaded := make([]UploadedImage, len(files))
+	for i, img := range files {
+		uploaded[i] = uploadedImage(img, renditions[img.ID])
+	}
+
+	response := struct {
+		Message  string          `json:"message"`
+		ImageURL string          `json:"imageUrl"`
+		Images   []UploadedImage `json:"images"`
+	}{"File uploaded successfully", uploaded[0].URL, uploaded}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func getUserImagesHandler(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	if principal == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	owned, err := images.ListByOwner(r.Context(), principal.UserID)
+	if err != nil {
+		log.Printf("listing images: %v", err)
+		http.Error(w, "Failed to list images", http.StatusInternalServerError)
+		return
+	}
+	ids := make([]int32, len(owned))
+	for i, img := range owned {
+		ids[i] = img.ID
+	}
+	renditions, err := images.RenditionsByImages(r.Context(), ids)
+	if err != nil {
+		log.Printf("listing images: %v", err)
+		http.Error(w, "Failed to list images", http.StatusInternalServerError)
+		return
+	}
+	list := make([]UploadedImage, len(owned))
+	for i, img := range owned {
+		list[i] = uploadedImage(img, renditions[img.ID])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}